@@ -0,0 +1,384 @@
+// Package ovsdb implements the subset of the OVSDB management protocol
+// (RFC 7047) that the provider needs to talk to ovsdb-server directly.
+package ovsdb
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// ErrClosed is returned for calls made on, or interrupted by, a closed
+// connection.
+var ErrClosed = errors.New("ovsdb: connection closed")
+
+// DialFunc opens a connection to address on network ("unix" or "tcp").
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// DialOptions customizes how Dial reaches the server.
+type DialOptions struct {
+	// Dial opens the underlying connection. It defaults to a net.Dialer.
+	Dial DialFunc
+
+	// TLSConfig is required for ssl: endpoints.
+	TLSConfig *tls.Config
+}
+
+// Client is a JSON-RPC connection to an ovsdb-server. It is safe for
+// concurrent use.
+type Client struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan *response
+	err     error
+
+	// monitors holds the monitors set up on the connection by id.
+	nextMonitor uint64
+	monitors    map[string]*monitor
+
+	done chan struct{}
+}
+
+// message is the union of the JSON-RPC request, response and notification
+// objects.
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+type request struct {
+	ID     uint64        `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+type reply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  interface{}     `json:"error"`
+}
+
+type response struct {
+	result json.RawMessage
+	err    error
+}
+
+// RPCError is an error object returned by the server for a request.
+type RPCError struct {
+	Method string
+	Err    string
+	Detail string
+}
+
+func (e *RPCError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("ovsdb: %s: %s: %s", e.Method, e.Err, e.Detail)
+	}
+	return fmt.Sprintf("ovsdb: %s: %s", e.Method, e.Err)
+}
+
+// Dial connects to the ovsdb-server at endpoint, which uses the OVS remote
+// syntax: unix:<path>, tcp:<host>:<port> or ssl:<host>:<port>.
+func Dial(ctx context.Context, endpoint string, opts DialOptions) (*Client, error) {
+	network, address, err := parseEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	dial := opts.Dial
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+
+	conn, err := dial(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("ovsdb: dial %s: %w", endpoint, err)
+	}
+
+	if strings.HasPrefix(endpoint, "ssl:") {
+		if opts.TLSConfig == nil {
+			conn.Close()
+			return nil, fmt.Errorf("ovsdb: %s requires a TLS configuration", endpoint)
+		}
+		tlsConn := tls.Client(conn, opts.TLSConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ovsdb: TLS handshake with %s: %w", endpoint, err)
+		}
+		conn = tlsConn
+	}
+
+	return NewClient(conn), nil
+}
+
+// parseEndpoint splits an OVS remote into a net network and address.
+func parseEndpoint(endpoint string) (string, string, error) {
+	method, address, ok := strings.Cut(endpoint, ":")
+	if !ok || address == "" {
+		return "", "", fmt.Errorf("ovsdb: invalid endpoint %q", endpoint)
+	}
+	switch method {
+	case "unix":
+		return "unix", address, nil
+	case "tcp", "ssl":
+		return "tcp", address, nil
+	}
+	return "", "", fmt.Errorf("ovsdb: unsupported endpoint %q: must start with unix:, tcp: or ssl:", endpoint)
+}
+
+// NewClient starts a Client on an established connection.
+func NewClient(conn net.Conn) *Client {
+	c := &Client{
+		conn:     conn,
+		enc:      json.NewEncoder(conn),
+		pending:  make(map[uint64]chan *response),
+		monitors: make(map[string]*monitor),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Close closes the connection and fails any outstanding calls.
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
+
+// Done returns a channel that is closed once the connection has terminated.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Call sends method with params and decodes the result into result, which
+// may be nil.
+func (c *Client) Call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+
+	ch := make(chan *response, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	id := c.nextID
+	c.nextID++
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(request{ID: id, Method: method, Params: params}); err != nil {
+		return fmt.Errorf("ovsdb: %s: %w", method, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("ovsdb: %s: %w", method, ctx.Err())
+	case resp := <-ch:
+		if resp.err != nil {
+			if rpcErr := new(RPCError); errors.As(resp.err, &rpcErr) {
+				rpcErr.Method = method
+			}
+			return resp.err
+		}
+		if result == nil {
+			return nil
+		}
+		if err := decodeJSON(resp.result, result); err != nil {
+			return fmt.Errorf("ovsdb: decoding %s result: %w", method, err)
+		}
+		return nil
+	}
+}
+
+// Echo sends an echo request, which is useful as a liveness check.
+func (c *Client) Echo(ctx context.Context) error {
+	var result []interface{}
+	return c.Call(ctx, "echo", &result, "ping")
+}
+
+// ListDbs returns the names of the databases served by the server.
+func (c *Client) ListDbs(ctx context.Context) ([]string, error) {
+	var dbs []string
+	if err := c.Call(ctx, "list_dbs", &dbs); err != nil {
+		return nil, err
+	}
+	return dbs, nil
+}
+
+// GetSchema returns the raw schema of db.
+func (c *Client) GetSchema(ctx context.Context, db string) (json.RawMessage, error) {
+	var schema json.RawMessage
+	if err := c.Call(ctx, "get_schema", &schema, db); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Transact executes ops atomically against db. If any operation fails, the
+// transaction is rolled back by the server and an *OperationError describing
+// the first failure is returned along with the raw results.
+func (c *Client) Transact(ctx context.Context, db string, ops ...Operation) ([]OperationResult, error) {
+	params := make([]interface{}, 0, len(ops)+1)
+	params = append(params, db)
+	for _, op := range ops {
+		params = append(params, op)
+	}
+
+	var results []OperationResult
+	if err := c.Call(ctx, "transact", &results, params...); err != nil {
+		return nil, err
+	}
+
+	for i, r := range results {
+		if r.Error == "" {
+			continue
+		}
+		opErr := &OperationError{Index: i, Err: r.Error, Details: r.Details}
+		if i < len(ops) {
+			opErr.Op = ops[i].Op
+			opErr.Table = ops[i].Table
+		}
+		return results, opErr
+	}
+	if len(results) < len(ops) {
+		return results, fmt.Errorf("ovsdb: transact returned %d results for %d operations", len(results), len(ops))
+	}
+	return results, nil
+}
+
+// OperationError reports a failed operation within a transaction. An Index
+// equal to the number of operations denotes a commit failure.
+type OperationError struct {
+	Index   int
+	Op      string
+	Table   string
+	Err     string
+	Details string
+}
+
+func (e *OperationError) Error() string {
+	where := "commit"
+	if e.Op != "" {
+		where = fmt.Sprintf("operation %d (%s %s)", e.Index, e.Op, e.Table)
+	}
+	if e.Details != "" {
+		return fmt.Sprintf("ovsdb: %s failed: %s: %s", where, e.Err, e.Details)
+	}
+	return fmt.Sprintf("ovsdb: %s failed: %s", where, e.Err)
+}
+
+func (c *Client) write(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.enc.Encode(v)
+}
+
+// readLoop dispatches responses to pending calls and monitor updates to
+// their monitors, and answers echo requests sent by the server, until the
+// connection fails.
+func (c *Client) readLoop() {
+	dec := json.NewDecoder(c.conn)
+	dec.UseNumber()
+
+	var err error
+	for {
+		var msg message
+		if err = dec.Decode(&msg); err != nil {
+			break
+		}
+
+		switch {
+		case msg.Method == "echo":
+			// Inactivity probe from the server; reply with its params.
+			params := msg.Params
+			if params == nil {
+				params = json.RawMessage("[]")
+			}
+			err = c.write(reply{ID: msg.ID, Result: params})
+		case msg.Method == "update":
+			c.notify(msg.Params)
+		case msg.Method != "":
+			// Other notifications are not used.
+		default:
+			c.deliver(&msg)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	c.mu.Lock()
+	c.err = ErrClosed
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		c.err = fmt.Errorf("%w: %w", ErrClosed, err)
+	}
+	for id, ch := range c.pending {
+		ch <- &response{err: c.err}
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// deliver hands a response to the call waiting on its id.
+func (c *Client) deliver(msg *message) {
+	var id uint64
+	if err := json.Unmarshal(msg.ID, &id); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	ch, ok := c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	resp := &response{result: msg.Result}
+	if len(msg.Error) > 0 && string(msg.Error) != "null" {
+		resp.err = parseRPCError(msg.Error)
+	}
+	ch <- resp
+}
+
+// parseRPCError decodes a JSON-RPC error, which ovsdb-server sends either as
+// a string or as an object with "error" and "details" members.
+func parseRPCError(raw json.RawMessage) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return &RPCError{Err: s}
+	}
+	var obj struct {
+		Error   string `json:"error"`
+		Details string `json:"details"`
+	}
+	if err := json.Unmarshal(raw, &obj); err == nil && obj.Error != "" {
+		return &RPCError{Err: obj.Error, Detail: obj.Details}
+	}
+	return &RPCError{Err: string(raw)}
+}
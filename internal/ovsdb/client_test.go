@@ -0,0 +1,253 @@
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// serve answers requests on conn with handler until the connection closes.
+func serve(t *testing.T, conn net.Conn, handler func(method string, params json.RawMessage) (interface{}, interface{})) {
+	t.Helper()
+	go func() {
+		dec := json.NewDecoder(conn)
+		enc := json.NewEncoder(conn)
+		for {
+			var req struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+				Result json.RawMessage `json:"result"`
+			}
+			if err := dec.Decode(&req); err != nil {
+				return
+			}
+			if req.Method == "" {
+				// Reply to a server initiated request.
+				continue
+			}
+			result, rpcErr := handler(req.Method, req.Params)
+			if err := enc.Encode(map[string]interface{}{"id": req.ID, "result": result, "error": rpcErr}); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestClientTransact(t *testing.T) {
+	server, conn := net.Pipe()
+	serve(t, server, func(method string, params json.RawMessage) (interface{}, interface{}) {
+		if method != "transact" {
+			return nil, "unknown method"
+		}
+		var p []json.RawMessage
+		if err := json.Unmarshal(params, &p); err != nil || len(p) != 3 {
+			return nil, "bad params"
+		}
+		var db string
+		_ = json.Unmarshal(p[0], &db)
+		if db != "Open_vSwitch" {
+			return nil, "unknown database"
+		}
+		return []interface{}{
+			map[string]interface{}{"uuid": []string{"uuid", "d1194f67-4c14-4e29-979a-cd0d87ec1448"}},
+			map[string]interface{}{"rows": []interface{}{map[string]interface{}{"next_cfg": 2}}},
+		}, nil
+	})
+
+	c := NewClient(conn)
+	defer c.Close()
+
+	results, err := c.Transact(testContext(t), "Open_vSwitch",
+		Operation{Op: "insert", Table: "Interface", Row: Row{"name": "pepe0"}},
+		Operation{Op: "select", Table: "Open_vSwitch", Columns: []string{"next_cfg"}},
+	)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if results[0].UUID != "d1194f67-4c14-4e29-979a-cd0d87ec1448" {
+		t.Errorf("uuid = %q", results[0].UUID)
+	}
+	if got := results[1].Rows[0]["next_cfg"]; got != 2 {
+		t.Errorf("next_cfg = %#v, want 2", got)
+	}
+}
+
+func TestClientTransactOperationError(t *testing.T) {
+	server, conn := net.Pipe()
+	serve(t, server, func(string, json.RawMessage) (interface{}, interface{}) {
+		return []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{"error": "constraint violation", "details": "duplicate name pepe0"},
+			nil,
+		}, nil
+	})
+
+	c := NewClient(conn)
+	defer c.Close()
+
+	_, err := c.Transact(testContext(t), "Open_vSwitch",
+		Operation{Op: "comment", Comment: "first"},
+		Operation{Op: "insert", Table: "Bridge", Row: Row{"name": "pepe0"}},
+		Operation{Op: "comment", Comment: "last"},
+	)
+
+	var opErr *OperationError
+	if !errors.As(err, &opErr) {
+		t.Fatalf("expected *OperationError, got %v", err)
+	}
+	if opErr.Index != 1 || opErr.Table != "Bridge" || opErr.Err != "constraint violation" {
+		t.Errorf("unexpected error %+v", opErr)
+	}
+}
+
+func TestClientRPCError(t *testing.T) {
+	server, conn := net.Pipe()
+	serve(t, server, func(string, json.RawMessage) (interface{}, interface{}) {
+		return nil, map[string]string{"error": "unknown database", "details": "Nope"}
+	})
+
+	c := NewClient(conn)
+	defer c.Close()
+
+	_, err := c.GetSchema(testContext(t), "Nope")
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("expected *RPCError, got %v", err)
+	}
+	if rpcErr.Method != "get_schema" || rpcErr.Err != "unknown database" {
+		t.Errorf("unexpected error %+v", rpcErr)
+	}
+}
+
+func TestClientAnswersEcho(t *testing.T) {
+	server, conn := net.Pipe()
+	c := NewClient(conn)
+	defer c.Close()
+
+	go func() {
+		_ = json.NewEncoder(server).Encode(map[string]interface{}{"id": "echo", "method": "echo", "params": []string{"probe"}})
+	}()
+
+	var reply struct {
+		ID     string   `json:"id"`
+		Result []string `json:"result"`
+	}
+	if err := json.NewDecoder(server).Decode(&reply); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if reply.ID != "echo" || len(reply.Result) != 1 || reply.Result[0] != "probe" {
+		t.Errorf("unexpected echo reply %+v", reply)
+	}
+}
+
+func TestClientMonitor(t *testing.T) {
+	server, conn := net.Pipe()
+	c := NewClient(conn)
+	defer c.Close()
+
+	go func() {
+		dec := json.NewDecoder(server)
+		enc := json.NewEncoder(server)
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := dec.Decode(&req); err != nil || req.Method != "monitor" || len(req.Params) != 3 {
+			t.Errorf("unexpected request %+v: %v", req, err)
+			return
+		}
+		update := func(rows map[string]interface{}) map[string]interface{} {
+			return map[string]interface{}{"method": "update", "id": nil, "params": []interface{}{req.Params[1], map[string]interface{}{"Bridge": rows}}}
+		}
+
+		// An update racing the reply reaches the handler after the
+		// initial contents
+		for _, msg := range []interface{}{
+			update(map[string]interface{}{"b2": map[string]interface{}{"new": map[string]interface{}{"name": "br2"}}}),
+			map[string]interface{}{"id": req.ID, "error": nil, "result": map[string]interface{}{
+				"Bridge": map[string]interface{}{"b1": map[string]interface{}{"new": map[string]interface{}{"name": "br1"}}},
+			}},
+			update(map[string]interface{}{"b1": map[string]interface{}{"old": map[string]interface{}{"name": "br1"}}}),
+		} {
+			if err := enc.Encode(msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	got := make(chan string, 3)
+	err := c.Monitor(testContext(t), "Open_vSwitch", map[string]MonitorRequest{"Bridge": {Columns: []string{"name"}}}, func(updates TableUpdates) {
+		for uuid, u := range updates["Bridge"] {
+			if u.New == nil {
+				got <- "delete " + string(uuid)
+				continue
+			}
+			name, _ := u.New["name"].(string)
+			got <- "insert " + string(uuid) + " " + name
+		}
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	for _, want := range []string{"insert b1 br1", "insert b2 br2", "delete b1"} {
+		select {
+		case update := <-got:
+			if update != want {
+				t.Errorf("update = %q, want %q", update, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	server, conn := net.Pipe()
+	c := NewClient(conn)
+	server.Close()
+
+	<-c.Done()
+	if err := c.Echo(testContext(t)); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		network  string
+		address  string
+		wantErr  bool
+	}{
+		{endpoint: "unix:/run/openvswitch/db.sock", network: "unix", address: "/run/openvswitch/db.sock"},
+		{endpoint: "tcp:127.0.0.1:6640", network: "tcp", address: "127.0.0.1:6640"},
+		{endpoint: "ssl:[::1]:6640", network: "tcp", address: "[::1]:6640"},
+		{endpoint: "punix:/tmp/db.sock", wantErr: true},
+		{endpoint: "unix:", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			network, address, err := parseEndpoint(tt.endpoint)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if network != tt.network || address != tt.address {
+				t.Errorf("got %s %s, want %s %s", network, address, tt.network, tt.address)
+			}
+		})
+	}
+}
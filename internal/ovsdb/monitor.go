@@ -0,0 +1,106 @@
+package ovsdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// MonitorRequest selects the columns of a table reported by a monitor. No
+// columns means all of them.
+type MonitorRequest struct {
+	Columns []string `json:"columns,omitempty"`
+}
+
+// RowUpdate is the change of one row reported by a monitor. Old is nil for
+// an inserted row and New is nil for a deleted one. For a modified row, Old
+// holds only the columns that changed and New the whole row.
+type RowUpdate struct {
+	Old Row `json:"old"`
+	New Row `json:"new"`
+}
+
+// TableUpdates holds the row updates of each table, by row UUID.
+type TableUpdates map[string]map[UUID]RowUpdate
+
+// monitor is a monitor set up on the connection. Until the server has
+// replied to the monitor request, the updates sent for it are queued, so
+// that they reach the handler after the initial contents of the tables.
+type monitor struct {
+	update  func(TableUpdates)
+	started bool
+	queued  []json.RawMessage
+}
+
+// Monitor asks the server to report the contents of the tables of db in
+// requests, and every later change to them. update is called with the
+// current contents before Monitor returns, then with each change in the
+// order the server commits them, until the connection closes. It runs on
+// the goroutine reading the connection, so it must not call the Client.
+//
+// ovsdb-server sends the updates caused by a transaction before it answers
+// any request received after that transaction, so once a later call has
+// returned, update has seen the transaction.
+func (c *Client) Monitor(ctx context.Context, db string, requests map[string]MonitorRequest, update func(TableUpdates)) error {
+	m := &monitor{update: update}
+
+	c.mu.Lock()
+	id := fmt.Sprintf("monitor-%d", c.nextMonitor)
+	c.nextMonitor++
+	c.monitors[id] = m
+	c.mu.Unlock()
+
+	var initial TableUpdates
+	if err := c.Call(ctx, "monitor", &initial, db, id, requests); err != nil {
+		c.mu.Lock()
+		delete(c.monitors, id)
+		c.mu.Unlock()
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	update(initial)
+	for _, raw := range m.queued {
+		if updates, err := decodeUpdates(raw); err == nil {
+			update(updates)
+		}
+	}
+	m.started, m.queued = true, nil
+	return nil
+}
+
+// notify hands the params of an update notification to its monitor.
+func (c *Client) notify(params json.RawMessage) {
+	var p []json.RawMessage
+	if err := json.Unmarshal(params, &p); err != nil || len(p) != 2 {
+		return
+	}
+	var id string
+	if err := json.Unmarshal(p[0], &id); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	m, ok := c.monitors[id]
+	started := ok && m.started
+	if ok && !started {
+		m.queued = append(m.queued, p[1])
+	}
+	c.mu.Unlock()
+	if !started {
+		return
+	}
+
+	if updates, err := decodeUpdates(p[1]); err == nil {
+		m.update(updates)
+	}
+}
+
+func decodeUpdates(raw json.RawMessage) (TableUpdates, error) {
+	var updates TableUpdates
+	if err := decodeJSON(raw, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
@@ -0,0 +1,277 @@
+package ovsdbtest
+
+import (
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// condition is a where clause element decoded against its column.
+type condition struct {
+	col      *columnSchema
+	function string
+	value    interface{}
+}
+
+func parseConditions(table *tableSchema, conds []ovsdb.Condition, named resolveFunc) ([]*condition, error) {
+	parsed := make([]*condition, 0, len(conds))
+	for _, c := range conds {
+		col, err := table.column(c.Column)
+		if err != nil {
+			return nil, err
+		}
+		switch c.Function {
+		case "==", "!=", "includes", "excludes":
+		case "<", "<=", ">", ">=":
+			if !col.isScalar() || (col.key.typ != typeInteger && col.key.typ != typeReal) {
+				return nil, syntaxError("function %s is not supported on column %s of table %s", c.Function, c.Column, table.name)
+			}
+		default:
+			return nil, syntaxError("unknown function %q", c.Function)
+		}
+		value, err := decodeDatum(c.Value, col, named)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, &condition{col: col, function: c.Function, value: value})
+	}
+	return parsed, nil
+}
+
+// matches reports whether r satisfies c.
+func (c *condition) matches(r *row) bool {
+	datum := r.get(c.col.name)
+	switch c.function {
+	case "==":
+		return equalDatums(datum, c.value)
+	case "!=":
+		return !equalDatums(datum, c.value)
+	case "includes":
+		return includes(datum, c.value)
+	case "excludes":
+		return excludes(datum, c.value)
+	}
+
+	order := compareAtoms(datum, c.value)
+	switch c.function {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	}
+	return order >= 0
+}
+
+// matchesAll reports whether r satisfies every condition in conds.
+func matchesAll(conds []*condition, r *row) bool {
+	for _, c := range conds {
+		if !c.matches(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// includes reports whether every element of b is in a.
+func includes(a, b interface{}) bool {
+	if bm, ok := b.(ovsdb.Map); ok {
+		am, _ := a.(ovsdb.Map)
+		for k, v := range bm {
+			if av, ok := am[k]; !ok || av != v {
+				return false
+			}
+		}
+		return true
+	}
+	have := make(map[interface{}]bool)
+	for _, e := range elements(a) {
+		have[e] = true
+	}
+	for _, e := range elements(b) {
+		if !have[e] {
+			return false
+		}
+	}
+	return true
+}
+
+// excludes reports whether no element of b is in a.
+func excludes(a, b interface{}) bool {
+	if bm, ok := b.(ovsdb.Map); ok {
+		am, _ := a.(ovsdb.Map)
+		for k, v := range bm {
+			if av, ok := am[k]; ok && av == v {
+				return false
+			}
+		}
+		return true
+	}
+	have := make(map[interface{}]bool)
+	for _, e := range elements(a) {
+		have[e] = true
+	}
+	for _, e := range elements(b) {
+		if have[e] {
+			return false
+		}
+	}
+	return true
+}
+
+// mutation is a mutate operation element decoded against its column.
+type mutation struct {
+	col     *columnSchema
+	mutator string
+	value   interface{}
+}
+
+func parseMutation(table *tableSchema, m ovsdb.Mutation, named resolveFunc) (*mutation, error) {
+	col, ok := table.columns[m.Column]
+	if !ok {
+		return nil, syntaxError("no column %s in table %s", m.Column, table.name)
+	}
+	if !col.mutable {
+		return nil, constraintViolation("cannot mutate immutable column %s in table %s", m.Column, table.name)
+	}
+
+	// Operands may hold any number of elements
+	relaxed := *col
+	relaxed.min, relaxed.max = 0, unlimited
+
+	var value interface{}
+	var err error
+	switch m.Mutator {
+	case "+=", "-=", "*=", "/=", "%=":
+		if col.isMap() || (col.key.typ != typeInteger && col.key.typ != typeReal) ||
+			(m.Mutator == "%=" && col.key.typ != typeInteger) {
+			return nil, syntaxError("mutator %s is not supported on column %s of table %s", m.Mutator, m.Column, table.name)
+		}
+		operand, _ := newBaseType(col.key.typ)
+		value, err = decodeAtom(m.Value, &operand, nil)
+	case "insert":
+		if col.isScalar() {
+			return nil, syntaxError("mutator %s is not supported on column %s of table %s", m.Mutator, m.Column, table.name)
+		}
+		value, err = decodeDatum(m.Value, &relaxed, named)
+	case "delete":
+		if col.isScalar() {
+			return nil, syntaxError("mutator %s is not supported on column %s of table %s", m.Mutator, m.Column, table.name)
+		}
+		if _, isMap := m.Value.(ovsdb.Map); col.isMap() && !isMap {
+			// Deleting from a map by key
+			relaxed.value = nil
+		}
+		value, err = decodeDatum(m.Value, &relaxed, named)
+	default:
+		return nil, syntaxError("unknown mutator %q", m.Mutator)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mutation{col: col, mutator: m.Mutator, value: value}, nil
+}
+
+// apply returns the result of applying m to datum.
+func (m *mutation) apply(datum interface{}) (interface{}, error) {
+	switch m.mutator {
+	case "insert":
+		if add, ok := m.value.(ovsdb.Map); ok {
+			current, _ := datum.(ovsdb.Map)
+			result := make(ovsdb.Map, len(current)+len(add))
+			for k, v := range add {
+				result[k] = v
+			}
+			// Keys already present keep their value
+			for k, v := range current {
+				result[k] = v
+			}
+			return result, nil
+		}
+		return newSet(append(append([]interface{}{}, elements(datum)...), elements(m.value)...)...), nil
+
+	case "delete":
+		if current, ok := datum.(ovsdb.Map); ok {
+			result := make(ovsdb.Map, len(current))
+			for k, v := range current {
+				result[k] = v
+			}
+			if pairs, ok := m.value.(ovsdb.Map); ok {
+				for k, v := range pairs {
+					if result[k] == v {
+						delete(result, k)
+					}
+				}
+			} else {
+				for _, k := range elements(m.value) {
+					delete(result, k)
+				}
+			}
+			return result, nil
+		}
+		remove := make(map[interface{}]bool)
+		for _, e := range elements(m.value) {
+			remove[e] = true
+		}
+		var kept []interface{}
+		for _, e := range elements(datum) {
+			if !remove[e] {
+				kept = append(kept, e)
+			}
+		}
+		return newSet(kept...), nil
+	}
+
+	results := make([]interface{}, 0, size(datum))
+	for _, e := range elements(datum) {
+		result, err := arithmetic(m.mutator, e, m.value)
+		if err != nil {
+			return nil, err
+		}
+		// The result must still satisfy the constraints of the column
+		if _, err := decodeAtom(result, &m.col.key, nil); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if m.col.isScalar() {
+		return results[0], nil
+	}
+	return newSet(results...), nil
+}
+
+// arithmetic applies an arithmetic mutator to a number.
+func arithmetic(mutator string, a, b interface{}) (interface{}, error) {
+	if x, ok := a.(float64); ok {
+		y, _ := b.(float64)
+		switch mutator {
+		case "+=":
+			return x + y, nil
+		case "-=":
+			return x - y, nil
+		case "*=":
+			return x * y, nil
+		}
+		if y == 0 {
+			return nil, &opError{err: "domain error", details: "division by zero"}
+		}
+		return x / y, nil
+	}
+
+	x, _ := a.(int)
+	y, _ := b.(int)
+	switch mutator {
+	case "+=":
+		return x + y, nil
+	case "-=":
+		return x - y, nil
+	case "*=":
+		return x * y, nil
+	}
+	if y == 0 {
+		return nil, &opError{err: "domain error", details: "division by zero"}
+	}
+	if mutator == "/=" {
+		return x / y, nil
+	}
+	return x % y, nil
+}
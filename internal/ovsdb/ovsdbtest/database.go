@@ -0,0 +1,726 @@
+package ovsdbtest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// opError is an error reported in the results of a transaction, using the
+// error strings of RFC 7047.
+type opError struct {
+	err     string
+	details string
+}
+
+func (e *opError) Error() string {
+	return e.err + ": " + e.details
+}
+
+func syntaxError(format string, args ...interface{}) *opError {
+	return &opError{err: "syntax error", details: fmt.Sprintf(format, args...)}
+}
+
+func constraintViolation(format string, args ...interface{}) *opError {
+	return &opError{err: "constraint violation", details: fmt.Sprintf(format, args...)}
+}
+
+// errorResult returns err as a transaction result.
+func errorResult(err error) map[string]interface{} {
+	if e, ok := err.(*opError); ok {
+		return map[string]interface{}{"error": e.err, "details": e.details}
+	}
+	return map[string]interface{}{"error": err.Error()}
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() ovsdb.UUID {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return ovsdb.UUID(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]))
+}
+
+// Pseudo columns present in every table.
+var (
+	uuidColumn    = &columnSchema{name: "_uuid", key: baseType{typ: typeUUID}, min: 1, max: 1}
+	versionColumn = &columnSchema{name: "_version", key: baseType{typ: typeUUID}, min: 1, max: 1}
+)
+
+type row struct {
+	uuid    ovsdb.UUID
+	version ovsdb.UUID
+
+	// seq orders rows by insertion, so that results are stable.
+	seq uint64
+
+	// columns holds the datum of every column of the table.
+	columns map[string]interface{}
+}
+
+// get returns the datum of column, which may be a pseudo column.
+func (r *row) get(column string) interface{} {
+	switch column {
+	case uuidColumn.name:
+		return r.uuid
+	case versionColumn.name:
+		return r.version
+	}
+	return r.columns[column]
+}
+
+func (r *row) clone() *row {
+	c := *r
+	c.columns = make(map[string]interface{}, len(r.columns))
+	for k, v := range r.columns {
+		c.columns[k] = v
+	}
+	return &c
+}
+
+// encode returns the wire form of the given columns of r.
+func (r *row) encode(columns []string) map[string]interface{} {
+	out := make(map[string]interface{}, len(columns))
+	for _, c := range columns {
+		out[c] = encodeDatum(r.get(c))
+	}
+	return out
+}
+
+// database holds the committed contents of one database.
+type database struct {
+	schema *dbSchema
+	tables map[string]map[ovsdb.UUID]*row
+	seq    uint64
+}
+
+func newDatabase(schema *dbSchema) *database {
+	db := &database{
+		schema: schema,
+		tables: make(map[string]map[ovsdb.UUID]*row, len(schema.tables)),
+	}
+	for name := range schema.tables {
+		db.tables[name] = make(map[ovsdb.UUID]*row)
+	}
+	return db
+}
+
+// rowChange records a row inserted (old is nil), modified or deleted (new
+// is nil) by a transaction.
+type rowChange struct {
+	table    string
+	uuid     ovsdb.UUID
+	old, new *row
+}
+
+// transact executes ops as one transaction. It returns the results in wire
+// form, and the changes made if the transaction committed.
+func (db *database) transact(ops []ovsdb.Operation, readOnly bool) ([]interface{}, []rowChange) {
+	t := db.begin()
+	results := make([]interface{}, len(ops))
+	for i := range ops {
+		op := &ops[i]
+		if readOnly && !readOnlyOps[op.Op] {
+			results[i] = errorResult(&opError{err: "not allowed", details: fmt.Sprintf("%s database is read-only", db.schema.name)})
+			return results, nil
+		}
+		result, err := t.execute(op)
+		if err != nil {
+			results[i] = errorResult(err)
+			return results, nil
+		}
+		results[i] = result
+	}
+	if err := t.commit(); err != nil {
+		return append(results, errorResult(err)), nil
+	}
+	return results, db.apply(t)
+}
+
+// readOnlyOps lists the operations allowed on a read-only database.
+var readOnlyOps = map[string]bool{
+	"select":  true,
+	"wait":    true,
+	"commit":  true,
+	"abort":   true,
+	"comment": true,
+	"assert":  true,
+}
+
+// txn is a transaction in progress. It works on copies of the tables, and
+// clones rows before modifying them, so the database is untouched until
+// the transaction is applied.
+type txn struct {
+	db       *database
+	tables   map[string]map[ovsdb.UUID]*row
+	symbols  map[string]*symbol
+	seq      uint64
+	modified bool
+}
+
+// symbol is the UUID bound to a uuid-name, which may be referenced before
+// the row is inserted.
+type symbol struct {
+	uuid    ovsdb.UUID
+	created bool
+}
+
+func (db *database) begin() *txn {
+	t := &txn{
+		db:      db,
+		tables:  make(map[string]map[ovsdb.UUID]*row, len(db.tables)),
+		symbols: make(map[string]*symbol),
+		seq:     db.seq,
+	}
+	for name, rows := range db.tables {
+		copied := make(map[ovsdb.UUID]*row, len(rows))
+		for uuid, r := range rows {
+			copied[uuid] = r
+		}
+		t.tables[name] = copied
+	}
+	return t
+}
+
+// symbol returns the symbol for uuid-name name, creating it on first use.
+func (t *txn) symbol(name string) *symbol {
+	s, ok := t.symbols[name]
+	if !ok {
+		s = &symbol{uuid: newUUID()}
+		t.symbols[name] = s
+	}
+	return s
+}
+
+func (t *txn) resolve(name ovsdb.NamedUUID) ovsdb.UUID {
+	return t.symbol(string(name)).uuid
+}
+
+// insert adds r to table.
+func (t *txn) insert(table string, r *row) {
+	t.seq++
+	r.seq = t.seq
+	t.tables[table][r.uuid] = r
+	t.modified = true
+}
+
+// writable returns a copy of the row uuid of table owned by the
+// transaction, ready to be modified.
+func (t *txn) writable(table string, uuid ovsdb.UUID) *row {
+	r := t.tables[table][uuid]
+	if r == t.db.tables[table][uuid] {
+		r = r.clone()
+		t.tables[table][uuid] = r
+	}
+	t.modified = true
+	return r
+}
+
+// remove deletes the row uuid from table.
+func (t *txn) remove(table string, uuid ovsdb.UUID) {
+	delete(t.tables[table], uuid)
+	t.modified = true
+}
+
+// rows returns the rows of table in insertion order.
+func (t *txn) rows(table string) []*row {
+	return sortedRows(t.tables[table])
+}
+
+// sortedRows returns rows in insertion order.
+func sortedRows(rows map[ovsdb.UUID]*row) []*row {
+	sorted := make([]*row, 0, len(rows))
+	for _, r := range rows {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].seq < sorted[j].seq })
+	return sorted
+}
+
+func (t *txn) execute(op *ovsdb.Operation) (map[string]interface{}, error) {
+	switch op.Op {
+	case "commit", "comment":
+		return map[string]interface{}{}, nil
+	case "abort":
+		return nil, &opError{err: "aborted", details: "aborted by request"}
+	case "assert":
+		// Locks are not implemented, so no client can hold one
+		return nil, &opError{err: "not owner", details: "lock is not held"}
+	}
+
+	table, ok := t.db.schema.tables[op.Table]
+	if !ok {
+		return nil, syntaxError("no table named %q", op.Table)
+	}
+	switch op.Op {
+	case "insert":
+		return t.executeInsert(table, op)
+	case "select":
+		return t.executeSelect(table, op)
+	case "update":
+		return t.executeUpdate(table, op)
+	case "mutate":
+		return t.executeMutate(table, op)
+	case "delete":
+		return t.executeDelete(table, op)
+	case "wait":
+		return t.executeWait(table, op)
+	}
+	return nil, syntaxError("unknown operation %q", op.Op)
+}
+
+func (t *txn) executeInsert(table *tableSchema, op *ovsdb.Operation) (map[string]interface{}, error) {
+	uuid := newUUID()
+	if op.UUIDName != "" {
+		s := t.symbol(op.UUIDName)
+		if s.created {
+			return nil, syntaxError("duplicate uuid-name %q", op.UUIDName)
+		}
+		s.created = true
+		uuid = s.uuid
+	}
+
+	r := &row{uuid: uuid, version: newUUID(), columns: make(map[string]interface{}, len(table.columns))}
+	for name, col := range table.columns {
+		r.columns[name] = defaultDatum(col)
+	}
+	if err := t.setColumns(table, r, op.Row, true); err != nil {
+		return nil, err
+	}
+	t.insert(table.name, r)
+	return map[string]interface{}{"uuid": uuid}, nil
+}
+
+func (t *txn) executeSelect(table *tableSchema, op *ovsdb.Operation) (map[string]interface{}, error) {
+	rows, err := t.where(table, op.Where)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := selectColumns(table, op.Columns)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, r.encode(columns))
+	}
+	return map[string]interface{}{"rows": out}, nil
+}
+
+func (t *txn) executeUpdate(table *tableSchema, op *ovsdb.Operation) (map[string]interface{}, error) {
+	rows, err := t.where(table, op.Where)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		if err := t.setColumns(table, t.writable(table.name, r.uuid), op.Row, false); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]interface{}{"count": len(rows)}, nil
+}
+
+func (t *txn) executeMutate(table *tableSchema, op *ovsdb.Operation) (map[string]interface{}, error) {
+	mutations := make([]*mutation, 0, len(op.Mutations))
+	for _, m := range op.Mutations {
+		parsed, err := parseMutation(table, m, t.resolve)
+		if err != nil {
+			return nil, err
+		}
+		mutations = append(mutations, parsed)
+	}
+	rows, err := t.where(table, op.Where)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		w := t.writable(table.name, r.uuid)
+		for _, m := range mutations {
+			datum, err := m.apply(w.columns[m.col.name])
+			if err != nil {
+				return nil, err
+			}
+			w.columns[m.col.name] = datum
+		}
+	}
+	return map[string]interface{}{"count": len(rows)}, nil
+}
+
+func (t *txn) executeDelete(table *tableSchema, op *ovsdb.Operation) (map[string]interface{}, error) {
+	rows, err := t.where(table, op.Where)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		t.remove(table.name, r.uuid)
+	}
+	return map[string]interface{}{"count": len(rows)}, nil
+}
+
+// executeWait checks the condition of a wait operation. Nothing else can
+// commit while a transaction runs, so a condition that does not hold fails
+// at once, whatever the timeout.
+func (t *txn) executeWait(table *tableSchema, op *ovsdb.Operation) (map[string]interface{}, error) {
+	if op.Until != "==" && op.Until != "!=" {
+		return nil, syntaxError("invalid until %q", op.Until)
+	}
+	rows, err := t.where(table, op.Where)
+	if err != nil {
+		return nil, err
+	}
+	columns := op.Columns
+	if columns == nil {
+		columns = sortedColumns(table)
+	}
+	if _, err := selectColumns(table, columns); err != nil {
+		return nil, err
+	}
+
+	// Compare the selected rows with the expected ones as multisets
+	counts := make(map[string]int)
+	for _, r := range rows {
+		counts[projectionKey(r, columns)]++
+	}
+	for _, want := range op.Rows {
+		r := &row{columns: make(map[string]interface{}, len(columns))}
+		for _, c := range columns {
+			v, ok := want[c]
+			if !ok {
+				return nil, syntaxError("wait row is missing column %s", c)
+			}
+			col, err := table.column(c)
+			if err != nil {
+				return nil, err
+			}
+			datum, err := decodeDatum(v, col, t.resolve)
+			if err != nil {
+				return nil, err
+			}
+			r.columns[c] = datum
+		}
+		counts[projectionKey(r, columns)]--
+	}
+	equal := true
+	for _, n := range counts {
+		equal = equal && n == 0
+	}
+
+	if equal == (op.Until == "==") {
+		return map[string]interface{}{}, nil
+	}
+	return nil, &opError{err: "timed out", details: fmt.Sprintf("\"wait\" timed out after %d ms", timeout(op))}
+}
+
+func timeout(op *ovsdb.Operation) int {
+	if op.Timeout == nil {
+		return 0
+	}
+	return *op.Timeout
+}
+
+// projectionKey identifies the values of columns in r.
+func projectionKey(r *row, columns []string) string {
+	b, _ := json.Marshal(r.encode(columns))
+	return string(b)
+}
+
+// setColumns decodes values into r.
+func (t *txn) setColumns(table *tableSchema, r *row, values ovsdb.Row, inserting bool) error {
+	for name, v := range values {
+		col, ok := table.columns[name]
+		if !ok {
+			return syntaxError("no column %s in table %s", name, table.name)
+		}
+		if !inserting && !col.mutable {
+			return constraintViolation("cannot update immutable column %s in table %s", name, table.name)
+		}
+		datum, err := decodeDatum(v, col, t.resolve)
+		if err != nil {
+			return err
+		}
+		r.columns[name] = datum
+	}
+	return nil
+}
+
+// where returns the rows of table matching all conds, in insertion order.
+func (t *txn) where(table *tableSchema, conds []ovsdb.Condition) ([]*row, error) {
+	parsed, err := parseConditions(table, conds, t.resolve)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*row
+	for _, r := range t.rows(table.name) {
+		if matchesAll(parsed, r) {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+// column returns the schema of column, which may be a pseudo column.
+func (table *tableSchema) column(name string) (*columnSchema, error) {
+	switch name {
+	case uuidColumn.name:
+		return uuidColumn, nil
+	case versionColumn.name:
+		return versionColumn, nil
+	}
+	col, ok := table.columns[name]
+	if !ok {
+		return nil, syntaxError("no column %s in table %s", name, table.name)
+	}
+	return col, nil
+}
+
+// sortedColumns returns the names of the columns of table, sorted.
+func sortedColumns(table *tableSchema) []string {
+	names := make([]string, 0, len(table.columns))
+	for name := range table.columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// selectColumns checks the columns selected by an operation, defaulting to
+// all of them including the pseudo columns.
+func selectColumns(table *tableSchema, columns []string) ([]string, error) {
+	if columns == nil {
+		return append(sortedColumns(table), uuidColumn.name, versionColumn.name), nil
+	}
+	for _, c := range columns {
+		if _, err := table.column(c); err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
+
+// commit enforces the integrity rules of the schema on the transaction, as
+// ovsdb-server does before committing: references to deleted rows are
+// dropped from weak reference columns, unreferenced rows of non-root
+// tables are garbage collected, and then strong references, the number of
+// elements of each column, indexes and row limits are checked.
+func (t *txn) commit() error {
+	if !t.modified {
+		return nil
+	}
+
+	for {
+		dropped := t.dropWeakReferences()
+		if collected := t.collectGarbage(); !dropped && !collected {
+			break
+		}
+	}
+
+	for _, table := range t.db.schema.tables {
+		for _, r := range t.rows(table.name) {
+			if err := t.checkRow(table, r); err != nil {
+				return err
+			}
+		}
+		if err := t.checkIndexes(table); err != nil {
+			return err
+		}
+		if table.maxRows > 0 && len(t.tables[table.name]) > table.maxRows {
+			return constraintViolation("transaction causes %q table to contain %d rows, greater than the schema-defined limit of %d row(s)",
+				table.name, len(t.tables[table.name]), table.maxRows)
+		}
+	}
+	return nil
+}
+
+// references calls fn with every reference held by the datum of col.
+func references(col *columnSchema, datum interface{}, fn func(uuid ovsdb.UUID, base *baseType)) {
+	if m, ok := datum.(ovsdb.Map); ok {
+		for k, v := range m {
+			if col.key.refTable != "" {
+				fn(k.(ovsdb.UUID), &col.key)
+			}
+			if col.value.refTable != "" {
+				fn(v.(ovsdb.UUID), col.value)
+			}
+		}
+		return
+	}
+	if col.key.refTable != "" {
+		for _, e := range elements(datum) {
+			fn(e.(ovsdb.UUID), &col.key)
+		}
+	}
+}
+
+// dropWeakReferences removes references to missing rows from weak
+// reference columns, and reports whether it changed anything.
+func (t *txn) dropWeakReferences() bool {
+	changed := false
+	for _, table := range t.db.schema.tables {
+		for _, col := range table.columns {
+			if !col.key.weak && (col.value == nil || !col.value.weak) {
+				continue
+			}
+			for _, r := range t.rows(table.name) {
+				datum := r.columns[col.name]
+				kept := t.withoutDangling(col, datum)
+				if size(kept) != size(datum) {
+					t.writable(table.name, r.uuid).columns[col.name] = kept
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// withoutDangling returns datum without the elements holding weak
+// references to missing rows.
+func (t *txn) withoutDangling(col *columnSchema, datum interface{}) interface{} {
+	exists := func(u interface{}, base *baseType) bool {
+		if !base.weak {
+			return true
+		}
+		_, ok := t.tables[base.refTable][u.(ovsdb.UUID)]
+		return ok
+	}
+	switch d := datum.(type) {
+	case ovsdb.Map:
+		kept := make(ovsdb.Map, len(d))
+		for k, v := range d {
+			if exists(k, &col.key) && exists(v, col.value) {
+				kept[k] = v
+			}
+		}
+		return kept
+	case ovsdb.Set:
+		var kept []interface{}
+		for _, e := range d {
+			if exists(e, &col.key) {
+				kept = append(kept, e)
+			}
+		}
+		return newSet(kept...)
+	}
+	return datum
+}
+
+// collectGarbage deletes the rows of non-root tables that no row strongly
+// references, and reports whether it deleted any.
+func (t *txn) collectGarbage() bool {
+	referenced := make(map[ovsdb.UUID]bool)
+	for _, table := range t.db.schema.tables {
+		for _, col := range table.columns {
+			for _, r := range t.tables[table.name] {
+				references(col, r.columns[col.name], func(uuid ovsdb.UUID, base *baseType) {
+					if !base.weak {
+						referenced[uuid] = true
+					}
+				})
+			}
+		}
+	}
+
+	collected := false
+	for _, table := range t.db.schema.tables {
+		if table.isRoot {
+			continue
+		}
+		for uuid := range t.tables[table.name] {
+			if !referenced[uuid] {
+				t.remove(table.name, uuid)
+				collected = true
+			}
+		}
+	}
+	return collected
+}
+
+// checkRow checks the strong references and the number of elements of
+// every column of r.
+func (t *txn) checkRow(table *tableSchema, r *row) error {
+	for _, name := range sortedColumns(table) {
+		col := table.columns[name]
+		datum := r.columns[name]
+
+		var err error
+		references(col, datum, func(uuid ovsdb.UUID, base *baseType) {
+			if _, ok := t.tables[base.refTable][uuid]; !ok && !base.weak && err == nil {
+				err = &opError{
+					err: "referential integrity violation",
+					details: fmt.Sprintf("Table %s column %s row %s references nonexistent row %s in table %s.",
+						table.name, name, r.uuid, uuid, base.refTable),
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if n := size(datum); n < col.min || n > col.max {
+			return constraintViolation("%d values in column %s of table %s row %s is not in the valid range %d to %d (inclusive)",
+				n, name, table.name, r.uuid, col.min, col.max)
+		}
+	}
+	return nil
+}
+
+// checkIndexes checks that no two rows of table share the values of an
+// index.
+func (t *txn) checkIndexes(table *tableSchema) error {
+	for _, index := range table.indexes {
+		seen := make(map[string]ovsdb.UUID)
+		for _, r := range t.rows(table.name) {
+			key := projectionKey(r, index)
+			if other, ok := seen[key]; ok {
+				return constraintViolation("Transaction causes multiple rows in %q table to have identical values (%s) for index on columns %v. First row has UUID %s, second row has UUID %s.",
+					table.name, key, index, other, r.uuid)
+			}
+			seen[key] = r.uuid
+		}
+	}
+	return nil
+}
+
+// apply commits the changes of t to the database and returns them. Rows
+// written without actually changing keep their version and are not
+// reported.
+func (db *database) apply(t *txn) []rowChange {
+	var changes []rowChange
+	for name, rows := range t.tables {
+		old := db.tables[name]
+		for uuid, r := range rows {
+			o, ok := old[uuid]
+			switch {
+			case o == r:
+				continue
+			case ok && equalDatums(o.columns, r.columns):
+				rows[uuid] = o
+				continue
+			case ok:
+				r.version = newUUID()
+			}
+			changes = append(changes, rowChange{table: name, uuid: uuid, old: o, new: r})
+		}
+		for uuid, o := range old {
+			if _, ok := rows[uuid]; !ok {
+				changes = append(changes, rowChange{table: name, uuid: uuid, old: o})
+			}
+		}
+	}
+	db.tables = t.tables
+	db.seq = t.seq
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].table != changes[j].table {
+			return changes[i].table < changes[j].table
+		}
+		return changes[i].uuid < changes[j].uuid
+	})
+	return changes
+}
@@ -0,0 +1,270 @@
+package ovsdbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// Datums are held in their canonical form: a scalar column holds its atom,
+// a set column an ovsdb.Set sorted in atom order and a map column an
+// ovsdb.Map. Atoms are int, float64, bool, string or ovsdb.UUID. Datums are
+// never modified in place, so rows can share them.
+
+// zeroUUID is the default value of a uuid atom.
+const zeroUUID = ovsdb.UUID("00000000-0000-0000-0000-000000000000")
+
+// resolveFunc maps a named-uuid of the current transaction to its UUID.
+type resolveFunc func(name ovsdb.NamedUUID) ovsdb.UUID
+
+// decodeValue decodes raw JSON into the generic OVSDB representation used
+// by the ovsdb package.
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	var row ovsdb.Row
+	if err := json.Unmarshal([]byte(`{"v":`+string(raw)+`}`), &row); err != nil {
+		return nil, err
+	}
+	return row["v"], nil
+}
+
+// decodeDatum converts v, as decoded from the wire, into the canonical
+// datum of col. named resolves named-uuids, which are rejected when nil.
+// The number of elements is not checked, since conditions and mutations
+// may use sets of any size.
+func decodeDatum(v interface{}, col *columnSchema, named resolveFunc) (interface{}, error) {
+	if col.isMap() {
+		m, ok := v.(ovsdb.Map)
+		if !ok {
+			return nil, syntaxError("expected a map for column %s, got %s", col.name, describe(v))
+		}
+		datum := make(ovsdb.Map, len(m))
+		for k, val := range m {
+			key, err := decodeAtom(k, &col.key, named)
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeAtom(val, col.value, named)
+			if err != nil {
+				return nil, err
+			}
+			datum[key] = value
+		}
+		return datum, nil
+	}
+
+	elems := []interface{}{v}
+	if set, ok := v.(ovsdb.Set); ok {
+		elems = set
+	}
+	atoms := make([]interface{}, 0, len(elems))
+	for _, e := range elems {
+		atom, err := decodeAtom(e, &col.key, named)
+		if err != nil {
+			return nil, err
+		}
+		atoms = append(atoms, atom)
+	}
+	if col.isScalar() {
+		if len(atoms) != 1 {
+			return nil, syntaxError("expected a single value for column %s, got %d", col.name, len(atoms))
+		}
+		return atoms[0], nil
+	}
+	return newSet(atoms...), nil
+}
+
+// decodeAtom converts v into an atom of base.
+func decodeAtom(v interface{}, base *baseType, named resolveFunc) (interface{}, error) {
+	var atom interface{}
+	switch base.typ {
+	case typeInteger:
+		i, ok := v.(int)
+		if !ok {
+			return nil, syntaxError("expected an integer, got %s", describe(v))
+		}
+		if int64(i) < base.minInteger || int64(i) > base.maxInteger {
+			return nil, constraintViolation("%d is not in the valid range %d to %d (inclusive)", i, base.minInteger, base.maxInteger)
+		}
+		atom = i
+	case typeReal:
+		var f float64
+		switch n := v.(type) {
+		case int:
+			f = float64(n)
+		case float64:
+			f = n
+		default:
+			return nil, syntaxError("expected a real, got %s", describe(v))
+		}
+		if f < base.minReal || f > base.maxReal {
+			return nil, constraintViolation("%g is not in the valid range %g to %g (inclusive)", f, base.minReal, base.maxReal)
+		}
+		atom = f
+	case typeBoolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, syntaxError("expected a boolean, got %s", describe(v))
+		}
+		atom = b
+	case typeString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, syntaxError("expected a string, got %s", describe(v))
+		}
+		if n := utf8.RuneCountInString(s); n < base.minLength || n > base.maxLength {
+			return nil, constraintViolation("%q length %d is not in the valid range %d to %d (inclusive)", s, n, base.minLength, base.maxLength)
+		}
+		atom = s
+	case typeUUID:
+		switch u := v.(type) {
+		case ovsdb.UUID:
+			atom = u
+		case ovsdb.NamedUUID:
+			if named == nil {
+				return nil, syntaxError("named-uuid %s is not allowed here", string(u))
+			}
+			atom = named(u)
+		default:
+			return nil, syntaxError("expected a uuid, got %s", describe(v))
+		}
+	}
+
+	if base.enum != nil {
+		for _, e := range base.enum {
+			if e == atom {
+				return atom, nil
+			}
+		}
+		return nil, constraintViolation("%v is not one of the allowed values (%s)", atom, joinAtoms(base.enum))
+	}
+	return atom, nil
+}
+
+// defaultDatum returns the default value of col.
+func defaultDatum(col *columnSchema) interface{} {
+	switch {
+	case col.isMap():
+		return ovsdb.Map{}
+	case !col.isScalar():
+		return ovsdb.Set{}
+	}
+	switch col.key.typ {
+	case typeInteger:
+		return 0
+	case typeReal:
+		return 0.0
+	case typeBoolean:
+		return false
+	case typeUUID:
+		return zeroUUID
+	}
+	return ""
+}
+
+// encodeDatum returns datum in its wire form. Like ovsdb-server, a set with
+// exactly one element is sent as a bare atom.
+func encodeDatum(datum interface{}) interface{} {
+	if set, ok := datum.(ovsdb.Set); ok && len(set) == 1 {
+		return set[0]
+	}
+	return datum
+}
+
+// equalDatums reports whether two canonical datums are equal.
+func equalDatums(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// size returns the number of elements in datum.
+func size(datum interface{}) int {
+	switch d := datum.(type) {
+	case ovsdb.Set:
+		return len(d)
+	case ovsdb.Map:
+		return len(d)
+	}
+	return 1
+}
+
+// elements returns the atoms of a set or scalar datum.
+func elements(datum interface{}) []interface{} {
+	if set, ok := datum.(ovsdb.Set); ok {
+		return set
+	}
+	return []interface{}{datum}
+}
+
+// newSet returns the canonical set of atoms, sorted and without duplicates.
+func newSet(atoms ...interface{}) ovsdb.Set {
+	set := make(ovsdb.Set, 0, len(atoms))
+	seen := make(map[interface{}]bool, len(atoms))
+	for _, a := range atoms {
+		if !seen[a] {
+			seen[a] = true
+			set = append(set, a)
+		}
+	}
+	sort.Slice(set, func(i, j int) bool { return compareAtoms(set[i], set[j]) < 0 })
+	return set
+}
+
+// compareAtoms orders two atoms of the same type.
+func compareAtoms(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		b, _ := b.(int)
+		return compare(a < b, a > b)
+	case float64:
+		b, _ := b.(float64)
+		return compare(a < b, a > b)
+	case bool:
+		b, _ := b.(bool)
+		return compare(!a && b, a && !b)
+	case string:
+		return strings.Compare(a, fmt.Sprint(b))
+	case ovsdb.UUID:
+		b, _ := b.(ovsdb.UUID)
+		return strings.Compare(string(a), string(b))
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compare(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// describe names the OVSDB type of a decoded value for error messages.
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case ovsdb.Set:
+		return "a set"
+	case ovsdb.Map:
+		return "a map"
+	case ovsdb.UUID, ovsdb.NamedUUID:
+		return "a uuid"
+	case string:
+		return fmt.Sprintf("string %q", v)
+	}
+	return fmt.Sprintf("%T %v", v, v)
+}
+
+func joinAtoms(atoms []interface{}) string {
+	s := make([]string, 0, len(atoms))
+	for _, a := range atoms {
+		s = append(s, fmt.Sprint(a))
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,296 @@
+package ovsdbtest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// monitor is a monitor set up by a monitor or monitor_cond request. The
+// former sends "update" notifications with old and new rows, the latter
+// "update2" notifications with only what changed.
+type monitor struct {
+	id     json.RawMessage
+	db     *database
+	cond   bool
+	tables map[string]*monitoredTable
+}
+
+type monitoredTable struct {
+	schema  *tableSchema
+	columns []string
+
+	// where lists the alternative conditions of the requests for the table.
+	// A row is monitored if it matches any of them.
+	where [][]*condition
+
+	initial, insert, delete, modify bool
+}
+
+// monitorRequest is a <monitor-request> or <monitor-cond-request>.
+type monitorRequest struct {
+	Columns *[]string         `json:"columns"`
+	Where   []ovsdb.Condition `json:"where"`
+	Select  *struct {
+		Initial *bool `json:"initial"`
+		Insert  *bool `json:"insert"`
+		Delete  *bool `json:"delete"`
+		Modify  *bool `json:"modify"`
+	} `json:"select"`
+}
+
+// newMonitor parses the <monitor-requests> object of a monitor request.
+func newMonitor(id json.RawMessage, db *database, cond bool, raw json.RawMessage) (*monitor, error) {
+	var requests map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &requests); err != nil {
+		return nil, syntaxError("invalid monitor requests: %s", err)
+	}
+
+	m := &monitor{id: id, db: db, cond: cond, tables: make(map[string]*monitoredTable, len(requests))}
+	for name, r := range requests {
+		schema, ok := db.schema.tables[name]
+		if !ok {
+			return nil, syntaxError("no table named %q", name)
+		}
+
+		// A table takes one request or an array of them
+		var reqs []monitorRequest
+		if err := json.Unmarshal(r, &reqs); err != nil {
+			var req monitorRequest
+			if err := json.Unmarshal(r, &req); err != nil {
+				return nil, syntaxError("invalid monitor request for table %s: %s", name, err)
+			}
+			reqs = []monitorRequest{req}
+		}
+
+		mt := &monitoredTable{schema: schema}
+		seen := make(map[string]bool)
+		for _, req := range reqs {
+			columns := sortedColumns(schema)
+			if req.Columns != nil {
+				columns = *req.Columns
+			}
+			for _, c := range columns {
+				if _, ok := schema.columns[c]; !ok {
+					return nil, syntaxError("no column %s in table %s", c, name)
+				}
+				if !seen[c] {
+					seen[c] = true
+					mt.columns = append(mt.columns, c)
+				}
+			}
+
+			if req.Where != nil && !cond {
+				return nil, syntaxError("conditions require monitor_cond")
+			}
+			where, err := parseConditions(schema, req.Where, nil)
+			if err != nil {
+				return nil, err
+			}
+			mt.where = append(mt.where, where)
+
+			sel := req.Select
+			mt.initial = mt.initial || sel == nil || sel.Initial == nil || *sel.Initial
+			mt.insert = mt.insert || sel == nil || sel.Insert == nil || *sel.Insert
+			mt.delete = mt.delete || sel == nil || sel.Delete == nil || *sel.Delete
+			mt.modify = mt.modify || sel == nil || sel.Modify == nil || *sel.Modify
+		}
+		m.tables[name] = mt
+	}
+	return m, nil
+}
+
+// matches reports whether r is a row of the table the monitor covers.
+func (mt *monitoredTable) matches(r *row) bool {
+	if r == nil {
+		return false
+	}
+	for _, where := range mt.where {
+		if matchesAll(where, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// initial returns the <table-updates> or <table-updates2> object sent in
+// reply to the monitor request, holding the rows present when it started.
+func (m *monitor) initial() map[string]interface{} {
+	updates := make(map[string]interface{})
+	for name, mt := range m.tables {
+		if !mt.initial {
+			continue
+		}
+		rows := make(map[string]interface{})
+		for _, r := range sortedRows(m.db.tables[name]) {
+			if !mt.matches(r) {
+				continue
+			}
+			if m.cond {
+				rows[string(r.uuid)] = map[string]interface{}{"initial": nonDefault(mt, r)}
+			} else {
+				rows[string(r.uuid)] = map[string]interface{}{"new": r.encode(mt.columns)}
+			}
+		}
+		if len(rows) > 0 {
+			updates[name] = rows
+		}
+	}
+	return updates
+}
+
+// updates returns the notification parameters describing changes, or nil
+// if none of them is monitored.
+func (m *monitor) updates(changes []rowChange) []interface{} {
+	updates := make(map[string]interface{})
+	for _, c := range changes {
+		mt, ok := m.tables[c.table]
+		if !ok {
+			continue
+		}
+		var update map[string]interface{}
+		if m.cond {
+			update = mt.update2(c)
+		} else {
+			update = mt.update(c)
+		}
+		if update == nil {
+			continue
+		}
+		rows, ok := updates[c.table].(map[string]interface{})
+		if !ok {
+			rows = make(map[string]interface{})
+			updates[c.table] = rows
+		}
+		rows[string(c.uuid)] = update
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return []interface{}{m.id, updates}
+}
+
+// update returns the <row-update> of the monitor method for c.
+func (mt *monitoredTable) update(c rowChange) map[string]interface{} {
+	switch {
+	case c.old == nil:
+		if mt.insert {
+			return map[string]interface{}{"new": c.new.encode(mt.columns)}
+		}
+	case c.new == nil:
+		if mt.delete {
+			return map[string]interface{}{"old": c.old.encode(mt.columns)}
+		}
+	default:
+		changed := mt.changedColumns(c)
+		if mt.modify && len(changed) > 0 {
+			return map[string]interface{}{"old": c.old.encode(changed), "new": c.new.encode(mt.columns)}
+		}
+	}
+	return nil
+}
+
+// update2 returns the <row-update2> of the monitor_cond method for c. Rows
+// entering or leaving the conditions of the monitor are reported as
+// inserted or deleted.
+func (mt *monitoredTable) update2(c rowChange) map[string]interface{} {
+	wasIn, isIn := mt.matches(c.old), mt.matches(c.new)
+	switch {
+	case !wasIn && isIn:
+		if mt.insert {
+			return map[string]interface{}{"insert": nonDefault(mt, c.new)}
+		}
+	case wasIn && !isIn:
+		if mt.delete {
+			return map[string]interface{}{"delete": nil}
+		}
+	case wasIn && isIn:
+		changed := mt.changedColumns(c)
+		if !mt.modify || len(changed) == 0 {
+			return nil
+		}
+		diff := make(map[string]interface{}, len(changed))
+		for _, name := range changed {
+			diff[name] = encodeDatum(difference(c.old.columns[name], c.new.columns[name]))
+		}
+		return map[string]interface{}{"modify": diff}
+	}
+	return nil
+}
+
+// changedColumns returns the monitored columns whose value differs between
+// the old and new row of c.
+func (mt *monitoredTable) changedColumns(c rowChange) []string {
+	var changed []string
+	for _, name := range mt.columns {
+		if !equalDatums(c.old.columns[name], c.new.columns[name]) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// nonDefault returns the monitored columns of r that do not hold their
+// default value, as sent in update2 initial and insert row updates.
+func nonDefault(mt *monitoredTable, r *row) map[string]interface{} {
+	out := make(map[string]interface{})
+	for _, name := range mt.columns {
+		datum := r.columns[name]
+		if !equalDatums(datum, defaultDatum(mt.schema.columns[name])) {
+			out[name] = encodeDatum(datum)
+		}
+	}
+	return out
+}
+
+// difference returns the change from before to after as sent in update2 modify
+// row updates: the new value of a scalar, the elements added or removed
+// from a set, and the pairs added, removed or changed in a map, with their
+// new value unless removed.
+func difference(before, after interface{}) interface{} {
+	switch o := before.(type) {
+	case ovsdb.Set:
+		n, _ := after.(ovsdb.Set)
+		inOld := make(map[interface{}]bool, len(o))
+		for _, e := range o {
+			inOld[e] = true
+		}
+		var diff []interface{}
+		for _, e := range n {
+			if !inOld[e] {
+				diff = append(diff, e)
+			}
+			delete(inOld, e)
+		}
+		for e := range inOld {
+			diff = append(diff, e)
+		}
+		return newSet(diff...)
+	case ovsdb.Map:
+		n, _ := after.(ovsdb.Map)
+		diff := make(ovsdb.Map)
+		for k, v := range o {
+			if _, ok := n[k]; !ok {
+				diff[k] = v
+			}
+		}
+		for k, v := range n {
+			if ov, ok := o[k]; !ok || ov != v {
+				diff[k] = v
+			}
+		}
+		return diff
+	}
+	return after
+}
+
+// monitorKey identifies a monitor by the JSON encoding of its id.
+func monitorKey(id json.RawMessage) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(id, &v); err != nil {
+		return "", fmt.Errorf("invalid monitor id: %w", err)
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
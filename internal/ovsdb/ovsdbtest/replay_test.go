@@ -0,0 +1,316 @@
+package ovsdbtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// wireDumps documents the messages ovs-vsctl and ovsdb-server exchange.
+const wireDumps = "../../../docs/ovs-vsctl.md"
+
+// dumpMessage is a JSON-RPC message of the wire dumps.
+type dumpMessage struct {
+	line int
+	raw  string
+	msg  map[string]interface{}
+}
+
+// request reports whether m is sent by the client. Everything else is
+// expected from the server.
+func (m dumpMessage) request() bool {
+	_, isMethod := m.msg["method"]
+	return isMethod && m.msg["id"] != nil
+}
+
+// readWireDumps returns the JSON messages of the fenced code blocks of the
+// document at path. Command lines and messages abridged with "# .." are
+// skipped.
+func readWireDumps(t *testing.T, path string) []dumpMessage {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var msgs []dumpMessage
+	var block []string
+	inBlock, start := false, 0
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "```") && !inBlock:
+			inBlock, start, block = true, line+1, nil
+		case strings.HasPrefix(text, "```"):
+			inBlock = false
+			raw := strings.Join(block, "\n")
+			var msg map[string]interface{}
+			if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+				if !strings.Contains(raw, "#") {
+					t.Fatalf("%s:%d: invalid message: %v", path, start, err)
+				}
+				continue
+			}
+			msgs = append(msgs, dumpMessage{line: start, raw: raw, msg: msg})
+		case inBlock:
+			block = append(block, text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+// TestReplayWireDumps replays the requests of docs/ovs-vsctl.md against the
+// server and checks that it answers with the documented messages, in the
+// documented order. The UUIDs of the document are mapped to the ones the
+// server generates as they appear.
+func TestReplayWireDumps(t *testing.T) {
+	msgs := readWireDumps(t, wireDumps)
+	if len(msgs) == 0 {
+		t.Fatal("no messages found")
+	}
+
+	// Replies the document leaves out, such as the one to
+	// set_db_change_aware, are discarded when they arrive.
+	undocumented := make(map[string]int)
+	for i, m := range msgs {
+		if !m.request() {
+			continue
+		}
+		id := encodeID(m.msg["id"])
+		documented := false
+		for _, next := range msgs[i+1:] {
+			if encodeID(next.msg["id"]) != id {
+				continue
+			}
+			documented = !next.request()
+			break
+		}
+		if !documented {
+			undocumented[id]++
+		}
+	}
+
+	raw := dialRaw(t, testServer(t))
+	uuids := newUUIDBinding()
+	for _, m := range msgs {
+		if m.request() {
+			raw.send(uuids.toActual(m.msg))
+			continue
+		}
+
+		var got map[string]interface{}
+		for {
+			got = raw.receive()
+			if _, isMethod := got["method"]; isMethod {
+				break
+			}
+			if id := encodeID(got["id"]); undocumented[id] > 0 {
+				undocumented[id]--
+				continue
+			}
+			break
+		}
+		if err := uuids.match(m.msg, got, ""); err != nil {
+			actual, _ := json.MarshalIndent(got, "", "  ")
+			t.Fatalf("%s:%d: %v\nexpected:\n%s\ngot:\n%s", wireDumps, m.line, err, m.raw, actual)
+		}
+	}
+}
+
+func encodeID(id interface{}) string {
+	b, _ := json.Marshal(id)
+	return string(b)
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// uuidBinding maps the UUIDs of the document to those of the server, one to
+// one.
+type uuidBinding struct {
+	actual map[string]string
+	doc    map[string]string
+}
+
+func newUUIDBinding() *uuidBinding {
+	return &uuidBinding{actual: make(map[string]string), doc: make(map[string]string)}
+}
+
+func (b *uuidBinding) clone() *uuidBinding {
+	c := newUUIDBinding()
+	for k, v := range b.actual {
+		c.actual[k] = v
+		c.doc[v] = k
+	}
+	return c
+}
+
+// bind maps the document UUID doc to actual, failing if either is already
+// mapped to another UUID.
+func (b *uuidBinding) bind(doc, actual string) error {
+	if bound, ok := b.actual[doc]; ok {
+		if bound != actual {
+			return fmt.Errorf("expected %s, which stands for %s, got %s", doc, bound, actual)
+		}
+		return nil
+	}
+	if bound, ok := b.doc[actual]; ok {
+		return fmt.Errorf("expected %s, got %s, which stands for %s", doc, actual, bound)
+	}
+	b.actual[doc], b.doc[actual] = actual, doc
+	return nil
+}
+
+// toActual replaces the UUIDs of the document in v by those of the server.
+func (b *uuidBinding) toActual(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			if actual, ok := b.actual[k]; ok {
+				k = actual
+			}
+			out[k] = b.toActual(e)
+		}
+		return out
+	case []interface{}:
+		if uuid, ok := taggedUUID(v); ok {
+			if actual, ok := b.actual[uuid]; ok {
+				return []interface{}{"uuid", actual}
+			}
+		}
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = b.toActual(e)
+		}
+		return out
+	}
+	return v
+}
+
+// match compares the expected message with the one received, binding the
+// UUIDs of the document to the ones received in ["uuid", ...] values and in
+// object keys.
+func (b *uuidBinding) match(expected, got interface{}, path string) error {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %v", path, got)
+		}
+		return b.matchObject(e, g, path)
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(e) {
+			return fmt.Errorf("%s: expected %v, got %v", path, expected, got)
+		}
+		if uuid, ok := taggedUUID(e); ok {
+			actual, ok := taggedUUID(g)
+			if !ok {
+				return fmt.Errorf("%s: expected a uuid, got %v", path, got)
+			}
+			if err := b.bind(uuid, actual); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			return nil
+		}
+		for i := range e {
+			if err := b.match(e[i], g[i], fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !reflect.DeepEqual(expected, got) {
+		return fmt.Errorf("%s: expected %v, got %v", path, expected, got)
+	}
+	return nil
+}
+
+func (b *uuidBinding) matchObject(expected, got map[string]interface{}, path string) error {
+	if len(expected) != len(got) {
+		return fmt.Errorf("%s: expected members %v, got %v", path, keys(expected), keys(got))
+	}
+
+	// Members named by a UUID are matched once the others are, trying each
+	// candidate of an unbound UUID in turn.
+	var pending []string
+	unmatched := make(map[string]bool)
+	for k := range got {
+		unmatched[k] = true
+	}
+	for _, k := range keys(expected) {
+		if uuidPattern.MatchString(k) {
+			pending = append(pending, k)
+			continue
+		}
+		if _, ok := got[k]; !ok {
+			return fmt.Errorf("%s: missing member %q", path, k)
+		}
+		if err := b.match(expected[k], got[k], path+"."+k); err != nil {
+			return err
+		}
+		delete(unmatched, k)
+	}
+	return b.matchUUIDMembers(expected, got, pending, unmatched, path)
+}
+
+func (b *uuidBinding) matchUUIDMembers(expected, got map[string]interface{}, pending []string, unmatched map[string]bool, path string) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	k := pending[0]
+	candidates := keys(got)
+	if actual, ok := b.actual[k]; ok {
+		candidates = []string{actual}
+	}
+
+	err := fmt.Errorf("%s: no member matches %q", path, k)
+	for _, candidate := range candidates {
+		if !unmatched[candidate] {
+			continue
+		}
+		trial := b.clone()
+		if err = trial.bind(k, candidate); err != nil {
+			continue
+		}
+		if err = trial.match(expected[k], got[candidate], path+"."+k); err != nil {
+			continue
+		}
+		delete(unmatched, candidate)
+		if err = trial.matchUUIDMembers(expected, got, pending[1:], unmatched, path); err == nil {
+			*b = *trial
+			return nil
+		}
+		unmatched[candidate] = true
+	}
+	return err
+}
+
+// taggedUUID returns the UUID of a ["uuid", ...] value.
+func taggedUUID(v []interface{}) (string, bool) {
+	if len(v) != 2 || v[0] != "uuid" {
+		return "", false
+	}
+	uuid, ok := v[1].(string)
+	return uuid, ok
+}
+
+func keys(m map[string]interface{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,267 @@
+package ovsdbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Atomic types of the OVSDB type system.
+const (
+	typeInteger = "integer"
+	typeReal    = "real"
+	typeBoolean = "boolean"
+	typeString  = "string"
+	typeUUID    = "uuid"
+)
+
+// unlimited is the maximum number of elements of a set or map column whose
+// schema says "max": "unlimited".
+const unlimited = math.MaxInt
+
+// dbSchema is a parsed database schema.
+type dbSchema struct {
+	name    string
+	version string
+	tables  map[string]*tableSchema
+
+	// raw is the schema as served by get_schema.
+	raw json.RawMessage
+}
+
+type tableSchema struct {
+	name    string
+	columns map[string]*columnSchema
+	isRoot  bool
+	maxRows int
+	indexes [][]string
+}
+
+type columnSchema struct {
+	name    string
+	key     baseType
+	value   *baseType
+	min     int
+	max     int
+	mutable bool
+}
+
+// baseType is the type of the keys or values of a column, with its
+// constraints.
+type baseType struct {
+	typ        string
+	enum       []interface{}
+	minInteger int64
+	maxInteger int64
+	minReal    float64
+	maxReal    float64
+	minLength  int
+	maxLength  int
+	refTable   string
+	weak       bool
+}
+
+// isMap reports whether the column holds a map.
+func (c *columnSchema) isMap() bool {
+	return c.value != nil
+}
+
+// isScalar reports whether the column holds exactly one atom.
+func (c *columnSchema) isScalar() bool {
+	return c.value == nil && c.min == 1 && c.max == 1
+}
+
+// parseSchema parses the schema of a database in the format of
+// ovsdb-server's .ovsschema files.
+func parseSchema(raw []byte) (*dbSchema, error) {
+	var s struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Tables  map[string]struct {
+			Columns map[string]struct {
+				Type    json.RawMessage `json:"type"`
+				Mutable *bool           `json:"mutable"`
+			} `json:"columns"`
+			IsRoot  bool       `json:"isRoot"`
+			MaxRows int        `json:"maxRows"`
+			Indexes [][]string `json:"indexes"`
+		} `json:"tables"`
+	}
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("parsing schema: %w", err)
+	}
+
+	schema := &dbSchema{
+		name:    s.Name,
+		version: s.Version,
+		tables:  make(map[string]*tableSchema, len(s.Tables)),
+		raw:     json.RawMessage(raw),
+	}
+	for name, t := range s.Tables {
+		table := &tableSchema{
+			name:    name,
+			columns: make(map[string]*columnSchema, len(t.Columns)),
+			isRoot:  t.IsRoot,
+			maxRows: t.MaxRows,
+			indexes: t.Indexes,
+		}
+		for colName, c := range t.Columns {
+			col, err := parseColumnType(c.Type)
+			if err != nil {
+				return nil, fmt.Errorf("table %s column %s: %w", name, colName, err)
+			}
+			col.name = colName
+			col.mutable = c.Mutable == nil || *c.Mutable
+			table.columns[colName] = col
+		}
+		for _, index := range t.Indexes {
+			for _, colName := range index {
+				if _, ok := table.columns[colName]; !ok {
+					return nil, fmt.Errorf("table %s index on unknown column %s", name, colName)
+				}
+			}
+		}
+		schema.tables[name] = table
+	}
+
+	// Schemas predating isRoot had no garbage collection, so ovsdb-server
+	// treats every table as a root when none is marked as one
+	for _, table := range schema.tables {
+		if table.isRoot {
+			return schema, nil
+		}
+	}
+	for _, table := range schema.tables {
+		table.isRoot = true
+	}
+	return schema, nil
+}
+
+// parseColumnType parses a column type, either the name of an atomic type
+// or an object with key, value, min and max members.
+func parseColumnType(raw json.RawMessage) (*columnSchema, error) {
+	var atomic string
+	if err := json.Unmarshal(raw, &atomic); err == nil {
+		key, err := parseBaseType(raw)
+		if err != nil {
+			return nil, err
+		}
+		return &columnSchema{key: key, min: 1, max: 1}, nil
+	}
+
+	var t struct {
+		Key   json.RawMessage `json:"key"`
+		Value json.RawMessage `json:"value"`
+		Min   *int            `json:"min"`
+		Max   json.RawMessage `json:"max"`
+	}
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, fmt.Errorf("invalid type %s", raw)
+	}
+	key, err := parseBaseType(t.Key)
+	if err != nil {
+		return nil, err
+	}
+	col := &columnSchema{key: key, min: 1, max: 1}
+	if t.Value != nil {
+		value, err := parseBaseType(t.Value)
+		if err != nil {
+			return nil, err
+		}
+		col.value = &value
+	}
+	if t.Min != nil {
+		col.min = *t.Min
+	}
+	if t.Max != nil {
+		var limit string
+		if err := json.Unmarshal(t.Max, &limit); err == nil && limit == "unlimited" {
+			col.max = unlimited
+		} else if err := json.Unmarshal(t.Max, &col.max); err != nil {
+			return nil, fmt.Errorf("invalid max %s", t.Max)
+		}
+	}
+	if col.min < 0 || col.min > 1 || col.max < 1 || col.max < col.min {
+		return nil, fmt.Errorf("invalid bounds min %d max %d", col.min, col.max)
+	}
+	return col, nil
+}
+
+// parseBaseType parses a base type, either the name of an atomic type or
+// an object with its constraints.
+func parseBaseType(raw json.RawMessage) (baseType, error) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return newBaseType(name)
+	}
+
+	var t struct {
+		Type       string          `json:"type"`
+		Enum       json.RawMessage `json:"enum"`
+		MinInteger *int64          `json:"minInteger"`
+		MaxInteger *int64          `json:"maxInteger"`
+		MinReal    *float64        `json:"minReal"`
+		MaxReal    *float64        `json:"maxReal"`
+		MinLength  *int            `json:"minLength"`
+		MaxLength  *int            `json:"maxLength"`
+		RefTable   string          `json:"refTable"`
+		RefType    string          `json:"refType"`
+	}
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return baseType{}, fmt.Errorf("invalid base type %s", raw)
+	}
+	base, err := newBaseType(t.Type)
+	if err != nil {
+		return baseType{}, err
+	}
+	if t.MinInteger != nil {
+		base.minInteger = *t.MinInteger
+	}
+	if t.MaxInteger != nil {
+		base.maxInteger = *t.MaxInteger
+	}
+	if t.MinReal != nil {
+		base.minReal = *t.MinReal
+	}
+	if t.MaxReal != nil {
+		base.maxReal = *t.MaxReal
+	}
+	if t.MinLength != nil {
+		base.minLength = *t.MinLength
+	}
+	if t.MaxLength != nil {
+		base.maxLength = *t.MaxLength
+	}
+	base.refTable = t.RefTable
+	base.weak = t.RefType == "weak"
+	if t.Enum != nil {
+		// The enum is a set of atoms of the base type itself
+		unconstrained, _ := newBaseType(base.typ)
+		v, err := decodeValue(t.Enum)
+		if err != nil {
+			return baseType{}, fmt.Errorf("invalid enum: %w", err)
+		}
+		enum, err := decodeDatum(v, &columnSchema{key: unconstrained, max: unlimited}, nil)
+		if err != nil {
+			return baseType{}, fmt.Errorf("invalid enum: %w", err)
+		}
+		base.enum = elements(enum)
+	}
+	return base, nil
+}
+
+func newBaseType(name string) (baseType, error) {
+	switch name {
+	case typeInteger, typeReal, typeBoolean, typeString, typeUUID:
+	default:
+		return baseType{}, fmt.Errorf("unknown atomic type %q", name)
+	}
+	return baseType{
+		typ:        name,
+		minInteger: math.MinInt64,
+		maxInteger: math.MaxInt64,
+		minReal:    math.Inf(-1),
+		maxReal:    math.Inf(1),
+		maxLength:  math.MaxInt,
+	}, nil
+}
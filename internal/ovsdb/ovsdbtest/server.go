@@ -0,0 +1,413 @@
+// Package ovsdbtest provides an in-process ovsdb-server for tests.
+//
+// The Server serves the Open_vSwitch database, with the vswitch schema of
+// Open vSwitch 2.17, and the _Server database over JSON-RPC on a unix
+// socket. It implements the methods of RFC 7047 that the provider and
+// ovs-vsctl use: echo, list_dbs, get_schema, transact, monitor,
+// monitor_cond, monitor_cancel and set_db_change_aware. Transactions are
+// checked against the schema the way ovsdb-server checks them, including
+// garbage collection of unreferenced rows and index uniqueness.
+//
+// The Server also plays the part of ovs-vswitchd: after every transaction
+// that increments next_cfg it assigns OpenFlow port numbers to new
+// interfaces and sets cur_cfg, so clients waiting for the switch to
+// reconfigure do not block.
+package ovsdbtest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// Names of the databases served.
+const (
+	VswitchDatabase = "Open_vSwitch"
+	ServerDatabase  = "_Server"
+)
+
+var (
+	//go:embed vswitch.ovsschema
+	vswitchSchema []byte
+
+	//go:embed _server.ovsschema
+	serverSchema []byte
+)
+
+// Server is a fake ovsdb-server listening on a unix socket.
+type Server struct {
+	path     string
+	listener net.Listener
+
+	// mu serializes transactions and guards the databases, connections
+	// and monitors.
+	mu    sync.Mutex
+	dbs   map[string]*database
+	conns map[*conn]bool
+
+	wg sync.WaitGroup
+}
+
+// NewServer starts a Server listening on the unix socket at path, with an
+// Open_vSwitch database holding only its root row.
+func NewServer(path string) (*Server, error) {
+	vswitch, err := parseSchema(vswitchSchema)
+	if err != nil {
+		return nil, err
+	}
+	server, err := parseSchema(serverSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		path: path,
+		dbs: map[string]*database{
+			VswitchDatabase: newDatabase(vswitch),
+			ServerDatabase:  newDatabase(server),
+		},
+		conns: make(map[*conn]bool),
+	}
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+
+	s.listener, err = net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("ovsdbtest: %w", err)
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// init creates the root row of the Open_vSwitch database, and describes
+// the databases in the _Server database.
+func (s *Server) init() error {
+	db := s.dbs[VswitchDatabase]
+	if _, err := s.transactInternal(db, ovsdb.Operation{
+		Op:    "insert",
+		Table: "Open_vSwitch",
+		Row:   ovsdb.Row{"db_version": db.schema.version},
+	}); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(s.dbs))
+	for name := range s.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ops := make([]ovsdb.Operation, 0, len(names))
+	for _, name := range names {
+		ops = append(ops, ovsdb.Operation{
+			Op:    "insert",
+			Table: "Database",
+			Row: ovsdb.Row{
+				"name":      name,
+				"model":     "standalone",
+				"connected": true,
+				"leader":    true,
+				"schema":    string(s.dbs[name].schema.raw),
+			},
+		})
+	}
+	_, err := s.transactInternal(s.dbs[ServerDatabase], ops...)
+	return err
+}
+
+// transactInternal runs ops on behalf of the server itself.
+func (s *Server) transactInternal(db *database, ops ...ovsdb.Operation) ([]rowChange, error) {
+	results, changes := db.transact(ops, false)
+	for _, r := range results {
+		if result, ok := r.(map[string]interface{}); ok && result["error"] != nil {
+			return nil, fmt.Errorf("ovsdbtest: %v: %v", result["error"], result["details"])
+		}
+	}
+	return changes, nil
+}
+
+// Endpoint returns the OVSDB remote of the server, for example
+// unix:/tmp/db.sock.
+func (s *Server) Endpoint() string {
+	return "unix:" + s.path
+}
+
+// Close stops listening and closes every client connection.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.nc.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		c := &conn{
+			server:   s,
+			nc:       nc,
+			enc:      json.NewEncoder(nc),
+			monitors: make(map[string]*monitor),
+		}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			c.serve()
+
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+			c.nc.Close()
+		}()
+	}
+}
+
+// conn is a client connection.
+type conn struct {
+	server *Server
+	nc     net.Conn
+
+	writeMu sync.Mutex
+	enc     *json.Encoder
+
+	// monitors holds the monitors of the connection by id. It is guarded
+	// by the server's mu.
+	monitors map[string]*monitor
+}
+
+// message is a JSON-RPC request, response or notification.
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// rpcError is an error returned for a whole request.
+type rpcError struct {
+	Error   string `json:"error"`
+	Details string `json:"details,omitempty"`
+}
+
+func (c *conn) serve() {
+	dec := json.NewDecoder(c.nc)
+	for {
+		var msg message
+		if err := dec.Decode(&msg); err != nil {
+			return
+		}
+		if msg.Method == "" || msg.ID == nil || string(msg.ID) == "null" {
+			// Replies to the server and notifications need no answer
+			continue
+		}
+
+		var params []json.RawMessage
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			c.reply(msg.ID, nil, &rpcError{Error: "syntax error", Details: "params must be an array"})
+			continue
+		}
+		switch msg.Method {
+		case "echo":
+			c.reply(msg.ID, msg.Params, nil)
+		case "list_dbs":
+			c.reply(msg.ID, c.server.listDbs(), nil)
+		case "get_schema":
+			c.getSchema(msg.ID, params)
+		case "transact":
+			c.transact(msg.ID, params)
+		case "monitor", "monitor_cond":
+			c.monitor(msg.ID, msg.Method == "monitor_cond", params)
+		case "monitor_cancel":
+			c.monitorCancel(msg.ID, params)
+		case "set_db_change_aware":
+			c.reply(msg.ID, map[string]interface{}{}, nil)
+		default:
+			c.reply(msg.ID, nil, &rpcError{Error: "unknown method", Details: msg.Method})
+		}
+	}
+}
+
+// write sends a message, giving up on a connection that fails.
+func (c *conn) write(v interface{}) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := c.enc.Encode(v); err != nil {
+		c.nc.Close()
+	}
+}
+
+func (c *conn) reply(id json.RawMessage, result interface{}, err *rpcError) {
+	if err != nil {
+		c.write(map[string]interface{}{"id": id, "result": nil, "error": err})
+		return
+	}
+	c.write(map[string]interface{}{"id": id, "result": result, "error": nil})
+}
+
+func (c *conn) notify(method string, params interface{}) {
+	c.write(map[string]interface{}{"id": nil, "method": method, "params": params})
+}
+
+func (s *Server) listDbs() []string {
+	names := make([]string, 0, len(s.dbs))
+	for name := range s.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// database returns the database named by the first of params.
+func (s *Server) database(params []json.RawMessage) (*database, *rpcError) {
+	if len(params) == 0 {
+		return nil, &rpcError{Error: "syntax error", Details: "missing database name"}
+	}
+	var name string
+	if err := json.Unmarshal(params[0], &name); err != nil {
+		return nil, &rpcError{Error: "syntax error", Details: "database name must be a string"}
+	}
+	db, ok := s.dbs[name]
+	if !ok {
+		return nil, &rpcError{Error: "unknown database", Details: name}
+	}
+	return db, nil
+}
+
+func (c *conn) getSchema(id json.RawMessage, params []json.RawMessage) {
+	db, err := c.server.database(params)
+	if err != nil {
+		c.reply(id, nil, err)
+		return
+	}
+	c.reply(id, db.schema.raw, nil)
+}
+
+func (c *conn) transact(id json.RawMessage, params []json.RawMessage) {
+	s := c.server
+	db, rpcErr := s.database(params)
+	if rpcErr != nil {
+		c.reply(id, nil, rpcErr)
+		return
+	}
+	ops := make([]ovsdb.Operation, 0, len(params)-1)
+	for _, raw := range params[1:] {
+		var op ovsdb.Operation
+		if err := json.Unmarshal(raw, &op); err != nil {
+			c.reply(id, nil, &rpcError{Error: "syntax error", Details: err.Error()})
+			return
+		}
+		ops = append(ops, op)
+	}
+
+	// Like ovsdb-server, send the updates of the monitors before the reply
+	s.mu.Lock()
+	results, changes := db.transact(ops, db.schema.name == ServerDatabase)
+	s.notify(db, changes)
+	s.mu.Unlock()
+	c.reply(id, results, nil)
+
+	if db.schema.name == VswitchDatabase && len(changes) > 0 {
+		s.mu.Lock()
+		s.reconfigure()
+		s.mu.Unlock()
+	}
+}
+
+// notify sends changes to the monitors of db. Callers must hold s.mu.
+func (s *Server) notify(db *database, changes []rowChange) {
+	if len(changes) == 0 {
+		return
+	}
+	for c := range s.conns {
+		for _, m := range c.monitors {
+			if m.db != db {
+				continue
+			}
+			params := m.updates(changes)
+			if params == nil {
+				continue
+			}
+			if m.cond {
+				c.notify("update2", params)
+			} else {
+				c.notify("update", params)
+			}
+		}
+	}
+}
+
+func (c *conn) monitor(id json.RawMessage, cond bool, params []json.RawMessage) {
+	s := c.server
+	db, rpcErr := s.database(params)
+	if rpcErr != nil {
+		c.reply(id, nil, rpcErr)
+		return
+	}
+	if len(params) < 3 {
+		c.reply(id, nil, &rpcError{Error: "syntax error", Details: "monitor requires a database, an id and requests"})
+		return
+	}
+	key, err := monitorKey(params[1])
+	if err != nil {
+		c.reply(id, nil, &rpcError{Error: "syntax error", Details: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := c.monitors[key]; ok {
+		c.reply(id, nil, &rpcError{Error: "duplicate monitor ID", Details: key})
+		return
+	}
+	m, err := newMonitor(params[1], db, cond, params[2])
+	if err != nil {
+		var opErr *opError
+		if errors.As(err, &opErr) {
+			c.reply(id, nil, &rpcError{Error: opErr.err, Details: opErr.details})
+		} else {
+			c.reply(id, nil, &rpcError{Error: err.Error()})
+		}
+		return
+	}
+	c.monitors[key] = m
+	c.reply(id, m.initial(), nil)
+}
+
+func (c *conn) monitorCancel(id json.RawMessage, params []json.RawMessage) {
+	if len(params) != 1 {
+		c.reply(id, nil, &rpcError{Error: "syntax error", Details: "monitor_cancel requires a monitor id"})
+		return
+	}
+	key, err := monitorKey(params[0])
+	if err != nil {
+		c.reply(id, nil, &rpcError{Error: "syntax error", Details: err.Error()})
+		return
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if _, ok := c.monitors[key]; !ok {
+		c.reply(id, nil, &rpcError{Error: "unknown monitor", Details: key})
+		return
+	}
+	delete(c.monitors, key)
+	c.reply(id, map[string]interface{}{}, nil)
+}
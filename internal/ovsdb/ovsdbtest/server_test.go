@@ -0,0 +1,469 @@
+package ovsdbtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// testServer starts a Server for the duration of the test.
+func testServer(t *testing.T) *Server {
+	t.Helper()
+	s, err := NewServer(filepath.Join(t.TempDir(), "db.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return s
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// testClient connects an ovsdb.Client to s.
+func testClient(t *testing.T, s *Server) *ovsdb.Client {
+	t.Helper()
+	c, err := ovsdb.Dial(testContext(t), s.Endpoint(), ovsdb.DialOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// addBridge inserts a bridge with a local port the way ovs-vsctl add-br does.
+func addBridge(t *testing.T, c *ovsdb.Client, name string) ovsdb.UUID {
+	t.Helper()
+	results, err := c.Transact(testContext(t), VswitchDatabase,
+		ovsdb.Operation{Op: "insert", Table: "Interface", UUIDName: "iface", Row: ovsdb.Row{"name": name, "type": "internal"}},
+		ovsdb.Operation{Op: "insert", Table: "Port", UUIDName: "port", Row: ovsdb.Row{"name": name, "interfaces": ovsdb.NamedUUID("iface")}},
+		ovsdb.Operation{Op: "insert", Table: "Bridge", UUIDName: "bridge", Row: ovsdb.Row{"name": name, "ports": ovsdb.NamedUUID("port")}},
+		ovsdb.Operation{Op: "mutate", Table: "Open_vSwitch", Mutations: []ovsdb.Mutation{
+			{Column: "bridges", Mutator: "insert", Value: ovsdb.NamedUUID("bridge")},
+			{Column: "next_cfg", Mutator: "+=", Value: 1},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results[2].UUID
+}
+
+// selectRows returns the rows of table matching where.
+func selectRows(t *testing.T, c *ovsdb.Client, table string, where ...ovsdb.Condition) []ovsdb.Row {
+	t.Helper()
+	results, err := c.Transact(testContext(t), VswitchDatabase,
+		ovsdb.Operation{Op: "select", Table: table, Where: where})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results[0].Rows
+}
+
+func TestServerMethods(t *testing.T) {
+	c := testClient(t, testServer(t))
+	ctx := testContext(t)
+
+	if err := c.Echo(ctx); err != nil {
+		t.Errorf("echo: %v", err)
+	}
+
+	dbs, err := c.ListDbs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dbs) != 2 || dbs[0] != VswitchDatabase || dbs[1] != ServerDatabase {
+		t.Errorf("unexpected databases %v", dbs)
+	}
+
+	raw, err := c.GetSchema(ctx, VswitchDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Name != VswitchDatabase || schema.Version == "" {
+		t.Errorf("unexpected schema %s %s", schema.Name, schema.Version)
+	}
+
+	_, err = c.GetSchema(ctx, "Nope")
+	var rpcErr *ovsdb.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Err != "unknown database" {
+		t.Errorf("expected unknown database, got %v", err)
+	}
+
+	err = c.Call(ctx, "frobnicate", nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Err != "unknown method" {
+		t.Errorf("expected unknown method, got %v", err)
+	}
+}
+
+func TestServerRootRow(t *testing.T) {
+	c := testClient(t, testServer(t))
+
+	rows := selectRows(t, c, "Open_vSwitch")
+	if len(rows) != 1 {
+		t.Fatalf("expected one Open_vSwitch row, got %d", len(rows))
+	}
+	if rows[0]["db_version"] != "8.3.0" || rows[0]["next_cfg"] != 0 || rows[0]["cur_cfg"] != 0 {
+		t.Errorf("unexpected root row %v", rows[0])
+	}
+}
+
+func TestServerAddBridge(t *testing.T) {
+	c := testClient(t, testServer(t))
+
+	uuid := addBridge(t, c, "br0")
+
+	rows := selectRows(t, c, "Bridge", ovsdb.Condition{Column: "name", Function: "==", Value: "br0"})
+	if len(rows) != 1 || rows[0]["_uuid"] != uuid {
+		t.Fatalf("unexpected bridges %v", rows)
+	}
+	if ports := ovsdb.UUIDs(rows[0]["ports"]); len(ports) != 1 {
+		t.Errorf("unexpected ports %v", rows[0]["ports"])
+	}
+
+	// The emulated ovs-vswitchd acknowledges the configuration and numbers
+	// the local interface.
+	root := selectRows(t, c, "Open_vSwitch")[0]
+	if root["next_cfg"] != 1 || root["cur_cfg"] != 1 {
+		t.Errorf("unexpected configuration sequence %v", root)
+	}
+	iface := selectRows(t, c, "Interface")[0]
+	if iface["ofport"] != 65534 {
+		t.Errorf("unexpected ofport %v", iface["ofport"])
+	}
+	if iface["link_state"] != "up" {
+		t.Errorf("unexpected link_state %v", iface["link_state"])
+	}
+
+	// It also reports the MAC and datapath ID the bridge uses
+	mac, _ := iface["mac_in_use"].(string)
+	dpid := selectRows(t, c, "Bridge")[0]["datapath_id"]
+	if len(mac) != 17 || dpid != "0000"+strings.ReplaceAll(mac, ":", "") {
+		t.Errorf("unexpected mac_in_use %v and datapath_id %v", iface["mac_in_use"], dpid)
+	}
+}
+
+func TestServerOFPortAssignment(t *testing.T) {
+	c := testClient(t, testServer(t))
+	bridge := addBridge(t, c, "br0")
+
+	addPort := func(name string, request int) {
+		t.Helper()
+		row := ovsdb.Row{"name": name}
+		if request != 0 {
+			row["ofport_request"] = request
+		}
+		_, err := c.Transact(testContext(t), VswitchDatabase,
+			ovsdb.Operation{Op: "insert", Table: "Interface", UUIDName: "iface", Row: row},
+			ovsdb.Operation{Op: "insert", Table: "Port", UUIDName: "port", Row: ovsdb.Row{"name": name, "interfaces": ovsdb.NamedUUID("iface")}},
+			ovsdb.Operation{Op: "mutate", Table: "Bridge",
+				Where:     []ovsdb.Condition{{Column: "_uuid", Function: "==", Value: bridge}},
+				Mutations: []ovsdb.Mutation{{Column: "ports", Mutator: "insert", Value: ovsdb.NamedUUID("port")}}},
+			ovsdb.Operation{Op: "mutate", Table: "Open_vSwitch",
+				Mutations: []ovsdb.Mutation{{Column: "next_cfg", Mutator: "+=", Value: 1}}},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addPort("p1", 0)
+	addPort("p5", 5)
+	addPort("p2", 0)
+
+	want := map[string]int{"br0": 65534, "p1": 1, "p5": 5, "p2": 2}
+	for _, iface := range selectRows(t, c, "Interface") {
+		name, _ := iface["name"].(string)
+		if iface["ofport"] != want[name] {
+			t.Errorf("interface %s: expected ofport %d, got %v", name, want[name], iface["ofport"])
+		}
+	}
+}
+
+func TestServerGarbageCollection(t *testing.T) {
+	c := testClient(t, testServer(t))
+	bridge := addBridge(t, c, "br0")
+
+	// Removing the bridge from the root row orphans the bridge, its port
+	// and its interface.
+	_, err := c.Transact(testContext(t), VswitchDatabase,
+		ovsdb.Operation{Op: "mutate", Table: "Open_vSwitch",
+			Mutations: []ovsdb.Mutation{{Column: "bridges", Mutator: "delete", Value: bridge}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"Bridge", "Port", "Interface"} {
+		if rows := selectRows(t, c, table); len(rows) != 0 {
+			t.Errorf("expected table %s to be empty, got %v", table, rows)
+		}
+	}
+}
+
+func TestServerConstraints(t *testing.T) {
+	c := testClient(t, testServer(t))
+	addBridge(t, c, "br0")
+
+	tests := map[string]struct {
+		ops []ovsdb.Operation
+		err string
+	}{
+		"duplicate index": {
+			ops: []ovsdb.Operation{
+				{Op: "insert", Table: "Interface", UUIDName: "iface", Row: ovsdb.Row{"name": "br0"}},
+				{Op: "insert", Table: "Port", UUIDName: "port", Row: ovsdb.Row{"name": "p0", "interfaces": ovsdb.NamedUUID("iface")}},
+				{Op: "mutate", Table: "Bridge", Mutations: []ovsdb.Mutation{{Column: "ports", Mutator: "insert", Value: ovsdb.NamedUUID("port")}}},
+			},
+			err: "constraint violation",
+		},
+		"dangling strong reference": {
+			ops: []ovsdb.Operation{
+				{Op: "update", Table: "Bridge", Row: ovsdb.Row{"ports": ovsdb.UUID("8b6a5d2f-6f3c-4f1e-9a4d-2d1f0c3b7e6a")}},
+			},
+			err: "referential integrity violation",
+		},
+		"port without interfaces": {
+			ops: []ovsdb.Operation{
+				{Op: "insert", Table: "Port", UUIDName: "port", Row: ovsdb.Row{"name": "p0"}},
+				{Op: "mutate", Table: "Bridge", Mutations: []ovsdb.Mutation{{Column: "ports", Mutator: "insert", Value: ovsdb.NamedUUID("port")}}},
+			},
+			err: "constraint violation",
+		},
+		"enum": {
+			ops: []ovsdb.Operation{
+				{Op: "update", Table: "Bridge", Row: ovsdb.Row{"fail_mode": "open"}},
+			},
+			err: "constraint violation",
+		},
+		"immutable column": {
+			ops: []ovsdb.Operation{
+				{Op: "update", Table: "Bridge", Row: ovsdb.Row{"name": "br1"}},
+			},
+			err: "constraint violation",
+		},
+		"unknown column": {
+			ops: []ovsdb.Operation{
+				{Op: "select", Table: "Bridge", Columns: []string{"nope"}},
+			},
+			err: "syntax error",
+		},
+		"wait timeout": {
+			ops: []ovsdb.Operation{
+				{Op: "wait", Table: "Bridge", Timeout: new(int), Until: "==", Columns: []string{"name"}, Rows: []ovsdb.Row{{"name": "br1"}}},
+			},
+			err: "timed out",
+		},
+		"abort": {
+			ops: []ovsdb.Operation{
+				{Op: "delete", Table: "Bridge"},
+				{Op: "abort"},
+			},
+			err: "aborted",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := c.Transact(testContext(t), VswitchDatabase, tt.ops...)
+			var opErr *ovsdb.OperationError
+			if !errors.As(err, &opErr) || opErr.Err != tt.err {
+				t.Fatalf("expected %s, got %v", tt.err, err)
+			}
+
+			// Failed transactions leave the database untouched
+			rows := selectRows(t, c, "Bridge")
+			if len(rows) != 1 || rows[0]["name"] != "br0" || rows[0]["fail_mode"] != nil && len(ovsdb.Elements(rows[0]["fail_mode"])) != 0 {
+				t.Errorf("unexpected bridges %v", rows)
+			}
+		})
+	}
+}
+
+func TestServerServerDatabaseIsReadOnly(t *testing.T) {
+	c := testClient(t, testServer(t))
+
+	results, err := c.Transact(testContext(t), ServerDatabase,
+		ovsdb.Operation{Op: "select", Table: "Database", Columns: []string{"name", "model"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results[0].Rows) != 2 {
+		t.Errorf("unexpected databases %v", results[0].Rows)
+	}
+
+	_, err = c.Transact(testContext(t), ServerDatabase,
+		ovsdb.Operation{Op: "delete", Table: "Database"})
+	var opErr *ovsdb.OperationError
+	if !errors.As(err, &opErr) || opErr.Err != "not allowed" {
+		t.Errorf("expected not allowed, got %v", err)
+	}
+}
+
+// rawConn is a connection to the server exchanging JSON-RPC messages
+// without the ovsdb.Client in between.
+type rawConn struct {
+	t   *testing.T
+	nc  net.Conn
+	enc *json.Encoder
+	dec *json.Decoder
+}
+
+func dialRaw(t *testing.T, s *Server) *rawConn {
+	t.Helper()
+	nc, err := net.Dial("unix", s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nc.Close() })
+	return &rawConn{t: t, nc: nc, enc: json.NewEncoder(nc), dec: json.NewDecoder(nc)}
+}
+
+func (c *rawConn) send(msg interface{}) {
+	c.t.Helper()
+	if err := c.enc.Encode(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive reads the next message, failing the test if none arrives
+// promptly.
+func (c *rawConn) receive() map[string]interface{} {
+	c.t.Helper()
+	if err := c.nc.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		c.t.Fatal(err)
+	}
+	var msg map[string]interface{}
+	if err := c.dec.Decode(&msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+func TestServerMonitor(t *testing.T) {
+	s := testServer(t)
+	c := testClient(t, s)
+	raw := dialRaw(t, s)
+
+	raw.send(map[string]interface{}{
+		"id":     1,
+		"method": "monitor",
+		"params": []interface{}{VswitchDatabase, "mon", map[string]interface{}{
+			"Bridge": map[string]interface{}{"columns": []string{"name", "fail_mode"}},
+		}},
+	})
+	if reply := raw.receive(); reply["id"] != 1.0 || len(reply["result"].(map[string]interface{})) != 0 {
+		t.Fatalf("unexpected reply %v", reply)
+	}
+
+	uuid := addBridge(t, c, "br0")
+	update := raw.receive()
+	want := map[string]interface{}{
+		"Bridge": map[string]interface{}{
+			string(uuid): map[string]interface{}{
+				"new": map[string]interface{}{"name": "br0", "fail_mode": []interface{}{"set", []interface{}{}}},
+			},
+		},
+	}
+	if update["method"] != "update" || !equalJSON(update["params"], []interface{}{"mon", want}) {
+		t.Errorf("unexpected update %v", update)
+	}
+
+	_, err := c.Transact(testContext(t), VswitchDatabase,
+		ovsdb.Operation{Op: "update", Table: "Bridge", Row: ovsdb.Row{"fail_mode": "secure"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update = raw.receive()
+	want = map[string]interface{}{
+		"Bridge": map[string]interface{}{
+			string(uuid): map[string]interface{}{
+				"old": map[string]interface{}{"fail_mode": []interface{}{"set", []interface{}{}}},
+				"new": map[string]interface{}{"name": "br0", "fail_mode": "secure"},
+			},
+		},
+	}
+	if !equalJSON(update["params"], []interface{}{"mon", want}) {
+		t.Errorf("unexpected update %v", update)
+	}
+
+	raw.send(map[string]interface{}{"id": 2, "method": "monitor_cancel", "params": []interface{}{"mon"}})
+	if reply := raw.receive(); reply["id"] != 2.0 || reply["error"] != nil {
+		t.Errorf("unexpected reply %v", reply)
+	}
+}
+
+func TestServerMonitorCond(t *testing.T) {
+	s := testServer(t)
+	c := testClient(t, s)
+	addBridge(t, c, "br0")
+	raw := dialRaw(t, s)
+
+	raw.send(map[string]interface{}{
+		"id":     1,
+		"method": "monitor_cond",
+		"params": []interface{}{VswitchDatabase, "mon", map[string]interface{}{
+			"Bridge": []interface{}{map[string]interface{}{
+				"columns": []string{"name", "stp_enable"},
+				"where":   []interface{}{[]interface{}{"name", "==", "br1"}},
+			}},
+		}},
+	})
+	if reply := raw.receive(); len(reply["result"].(map[string]interface{})) != 0 {
+		t.Fatalf("expected br0 to be filtered out, got %v", reply)
+	}
+
+	uuid := addBridge(t, c, "br1")
+	update := raw.receive()
+	want := map[string]interface{}{
+		"Bridge": map[string]interface{}{
+			string(uuid): map[string]interface{}{"insert": map[string]interface{}{"name": "br1"}},
+		},
+	}
+	if update["method"] != "update2" || !equalJSON(update["params"], []interface{}{"mon", want}) {
+		t.Errorf("unexpected update %v", update)
+	}
+
+	_, err := c.Transact(testContext(t), VswitchDatabase,
+		ovsdb.Operation{Op: "update", Table: "Bridge", Row: ovsdb.Row{"stp_enable": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	update = raw.receive()
+	want = map[string]interface{}{
+		"Bridge": map[string]interface{}{
+			string(uuid): map[string]interface{}{"modify": map[string]interface{}{"stp_enable": true}},
+		},
+	}
+	if !equalJSON(update["params"], []interface{}{"mon", want}) {
+		t.Errorf("unexpected update %v", update)
+	}
+}
+
+// equalJSON reports whether a and b encode to the same JSON.
+func equalJSON(a, b interface{}) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	return err == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,209 @@
+package ovsdbtest
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// ofppLocal is the OpenFlow port number of a bridge's local interface.
+const ofppLocal = 65534
+
+// reconfigure does what ovs-vswitchd does once it notices that next_cfg
+// has changed: it gives every interface on a bridge an OpenFlow port
+// number, honoring ofport_request, and a link state, reports the MAC and
+// datapath ID each bridge uses and its spanning tree status, and
+// acknowledges the configuration by
+// copying next_cfg to cur_cfg. Callers must hold s.mu.
+func (s *Server) reconfigure() {
+	db := s.dbs[VswitchDatabase]
+	t := db.begin()
+
+	for _, ovs := range t.rows("Open_vSwitch") {
+		if equalDatums(ovs.columns["cur_cfg"], ovs.columns["next_cfg"]) {
+			return
+		}
+		t.writable("Open_vSwitch", ovs.uuid).columns["cur_cfg"] = ovs.columns["next_cfg"]
+	}
+
+	for _, bridge := range t.rows("Bridge") {
+		name, _ := bridge.columns["name"].(string)
+
+		// Collect the ports and interfaces of the bridge and the port
+		// numbers in use
+		var ports, ifaces []*row
+		used := make(map[int]bool)
+		for _, p := range elements(bridge.columns["ports"]) {
+			port, ok := t.tables["Port"][uuidOf(p)]
+			if !ok {
+				continue
+			}
+			ports = append(ports, port)
+			for _, i := range elements(port.columns["interfaces"]) {
+				iface, ok := t.tables["Interface"][uuidOf(i)]
+				if !ok {
+					continue
+				}
+				ifaces = append(ifaces, iface)
+				for _, ofport := range elements(iface.columns["ofport"]) {
+					if n, ok := ofport.(int); ok {
+						used[n] = true
+					}
+				}
+			}
+		}
+
+		next := 1
+		for _, iface := range ifaces {
+			if size(iface.columns["ofport"]) > 0 {
+				continue
+			}
+			var ofport int
+			linkState := "down"
+			requested, isRequested := firstInt(iface.columns["ofport_request"])
+			switch {
+			case iface.columns["name"] == name:
+				ofport = ofppLocal
+				linkState = "up"
+			case isRequested && !used[requested]:
+				ofport = requested
+			default:
+				for used[next] {
+					next++
+				}
+				ofport = next
+			}
+			used[ofport] = true
+			writable := t.writable("Interface", iface.uuid)
+			writable.columns["ofport"] = newSet(ofport)
+			writable.columns["link_state"] = newSet(linkState)
+		}
+
+		mac, dpid := bridgeAddresses(bridge)
+		if !equalDatums(bridge.columns["datapath_id"], newSet(dpid)) {
+			t.writable("Bridge", bridge.uuid).columns["datapath_id"] = newSet(dpid)
+		}
+		for _, iface := range ifaces {
+			if iface.columns["name"] == name && !equalDatums(iface.columns["mac_in_use"], newSet(mac)) {
+				t.writable("Interface", iface.uuid).columns["mac_in_use"] = newSet(mac)
+			}
+		}
+		t.spanningTree(bridge, ports, mac)
+	}
+
+	if err := t.commit(); err != nil {
+		return
+	}
+	s.notify(db, db.apply(t))
+}
+
+// bridgeAddresses returns the MAC and datapath ID of bridge: those set in
+// other_config, or else a locally administered MAC derived from its name
+// and the datapath ID derived from the MAC, as ovs-vswitchd derives it.
+func bridgeAddresses(bridge *row) (mac, dpid string) {
+	config, _ := bridge.columns["other_config"].(ovsdb.Map)
+	mac, _ = config["hwaddr"].(string)
+	if mac == "" {
+		h := fnv.New32a()
+		name, _ := bridge.columns["name"].(string)
+		h.Write([]byte(name))
+		sum := h.Sum32()
+		mac = fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", byte(sum>>24), byte(sum>>16), byte(sum>>8), byte(sum))
+	}
+	dpid, _ = config["datapath-id"].(string)
+	if dpid == "" {
+		dpid = "0000" + strings.ReplaceAll(mac, ":", "")
+	}
+	return mac, dpid
+}
+
+// spanningTree reports the STP and RSTP status of bridge and its ports,
+// whose local port uses mac. With no links between bridges, every bridge
+// is the root of its own tree and its ports are designated ports.
+func (t *txn) spanningTree(bridge *row, ports []*row, mac string) {
+	name, _ := bridge.columns["name"].(string)
+	stp, _ := bridge.columns["stp_enable"].(bool)
+	rstp, _ := bridge.columns["rstp_enable"].(bool)
+	config, _ := bridge.columns["other_config"].(ovsdb.Map)
+	addr := strings.ReplaceAll(mac, ":", "")
+
+	status, rstpStatus := ovsdb.Map{}, ovsdb.Map{}
+	switch {
+	case stp:
+		id := fmt.Sprintf("%04x.%s", configInt(config, "stp-priority", 32768), addr)
+		status = ovsdb.Map{"stp_bridge_id": id, "stp_designated_root": id, "stp_root_path_cost": "0"}
+	case rstp:
+		id := fmt.Sprintf("%04x.%s", configInt(config, "rstp-priority", 32768), addr)
+		rstpStatus = ovsdb.Map{"rstp_bridge_id": id, "rstp_root_id": id, "rstp_root_path_cost": "0"}
+	}
+	t.setStatus("Bridge", bridge, status, rstpStatus)
+
+	for _, port := range ports {
+		if port.columns["name"] == name {
+			continue
+		}
+		// Port IDs hold the OpenFlow port number of the interface
+		var ofport int
+		for _, i := range elements(port.columns["interfaces"]) {
+			if iface, ok := t.tables["Interface"][uuidOf(i)]; ok {
+				ofport, _ = firstInt(iface.columns["ofport"])
+			}
+		}
+		config, _ := port.columns["other_config"].(ovsdb.Map)
+		status, rstpStatus := ovsdb.Map{}, ovsdb.Map{}
+		switch {
+		case stp:
+			status = ovsdb.Map{
+				"stp_port_id": fmt.Sprintf("%04x", configInt(config, "stp-port-priority", 128)<<8|ofport),
+				"stp_state":   "forwarding",
+				"stp_role":    "designated",
+			}
+		case rstp:
+			rstpStatus = ovsdb.Map{
+				"rstp_port_id":    fmt.Sprintf("%04x", configInt(config, "rstp-port-priority", 128)<<8|ofport),
+				"rstp_port_role":  "Designated",
+				"rstp_port_state": "Forwarding",
+			}
+		}
+		t.setStatus("Port", port, status, rstpStatus)
+	}
+}
+
+// setStatus sets the status and rstp_status columns of r, a row of table,
+// unless they already hold those values.
+func (t *txn) setStatus(table string, r *row, status, rstpStatus ovsdb.Map) {
+	if equalDatums(r.columns["status"], status) && equalDatums(r.columns["rstp_status"], rstpStatus) {
+		return
+	}
+	writable := t.writable(table, r.uuid)
+	writable.columns["status"] = status
+	writable.columns["rstp_status"] = rstpStatus
+}
+
+// configInt returns the integer held by key in an other_config column, or
+// def if there is none.
+func configInt(config ovsdb.Map, key string, def int) int {
+	value, _ := config[key].(string)
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	return def
+}
+
+// uuidOf returns the UUID held by atom.
+func uuidOf(atom interface{}) ovsdb.UUID {
+	uuid, _ := atom.(ovsdb.UUID)
+	return uuid
+}
+
+// firstInt returns the integer held by an optional integer column.
+func firstInt(datum interface{}) (int, bool) {
+	for _, e := range elements(datum) {
+		n, ok := e.(int)
+		return n, ok
+	}
+	return 0, false
+}
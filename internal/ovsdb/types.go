@@ -0,0 +1,433 @@
+package ovsdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// UUID is a reference to an existing row, encoded as ["uuid", "<uuid>"].
+type UUID string
+
+// MarshalJSON implements json.Marshaler.
+func (u UUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{"uuid", string(u)})
+}
+
+// NamedUUID refers to a row inserted earlier in the same transaction by its
+// uuid-name, encoded as ["named-uuid", "<name>"].
+type NamedUUID string
+
+// MarshalJSON implements json.Marshaler.
+func (u NamedUUID) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]string{"named-uuid", string(u)})
+}
+
+// Set is an OVSDB set, encoded as ["set", [<atom>...]].
+type Set []interface{}
+
+// MarshalJSON implements json.Marshaler.
+func (s Set) MarshalJSON() ([]byte, error) {
+	elems := []interface{}(s)
+	if elems == nil {
+		elems = []interface{}{}
+	}
+	return json.Marshal([]interface{}{"set", elems})
+}
+
+// Map is an OVSDB map, encoded as ["map", [[<key>, <value>]...]]. Pairs are
+// written in key order so that encoded transactions are deterministic.
+type Map map[interface{}]interface{}
+
+// MarshalJSON implements json.Marshaler.
+func (m Map) MarshalJSON() ([]byte, error) {
+	keys := make([]interface{}, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	pairs := make([][]interface{}, 0, len(m))
+	for _, k := range keys {
+		pairs = append(pairs, []interface{}{k, m[k]})
+	}
+	return json.Marshal([]interface{}{"map", pairs})
+}
+
+// Row is a set of column values. Values decoded from the wire are one of
+// string, int, float64, bool, UUID, NamedUUID, Set or Map.
+type Row map[string]interface{}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *Row) UnmarshalJSON(b []byte) error {
+	var raw map[string]interface{}
+	if err := decodeJSON(b, &raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*r = nil
+		return nil
+	}
+
+	row := make(Row, len(raw))
+	for column, value := range raw {
+		v, err := decodeValue(value)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", column, err)
+		}
+		row[column] = v
+	}
+	*r = row
+	return nil
+}
+
+// Condition is a where clause element, encoded as [column, function, value].
+type Condition struct {
+	Column   string
+	Function string
+	Value    interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (c Condition) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{c.Column, c.Function, c.Value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Condition) UnmarshalJSON(b []byte) error {
+	column, function, value, err := decodeTriple(b)
+	if err != nil {
+		return fmt.Errorf("condition: %w", err)
+	}
+	*c = Condition{Column: column, Function: function, Value: value}
+	return nil
+}
+
+// Mutation is a mutate operation element, encoded as [column, mutator, value].
+type Mutation struct {
+	Column  string
+	Mutator string
+	Value   interface{}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m Mutation) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{m.Column, m.Mutator, m.Value})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *Mutation) UnmarshalJSON(b []byte) error {
+	column, mutator, value, err := decodeTriple(b)
+	if err != nil {
+		return fmt.Errorf("mutation: %w", err)
+	}
+	*m = Mutation{Column: column, Mutator: mutator, Value: value}
+	return nil
+}
+
+// Operation is a single operation within a transact request.
+type Operation struct {
+	Op        string
+	Table     string
+	Row       Row
+	Rows      []Row
+	Columns   []string
+	Mutations []Mutation
+	Where     []Condition
+	UUIDName  string
+	Until     string
+	Timeout   *int
+	Comment   string
+}
+
+// opsWithWhere lists the operations for which "where" is a required member.
+var opsWithWhere = map[string]bool{
+	"select": true,
+	"update": true,
+	"mutate": true,
+	"delete": true,
+	"wait":   true,
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	obj := map[string]interface{}{"op": o.Op}
+	if o.Table != "" {
+		obj["table"] = o.Table
+	}
+	if o.Row != nil {
+		obj["row"] = o.Row
+	}
+	if o.Rows != nil {
+		obj["rows"] = o.Rows
+	}
+	if o.Columns != nil {
+		obj["columns"] = o.Columns
+	}
+	if o.Mutations != nil {
+		obj["mutations"] = o.Mutations
+	}
+	if o.Where != nil || opsWithWhere[o.Op] {
+		where := o.Where
+		if where == nil {
+			where = []Condition{}
+		}
+		obj["where"] = where
+	}
+	if o.UUIDName != "" {
+		obj["uuid-name"] = o.UUIDName
+	}
+	if o.Until != "" {
+		obj["until"] = o.Until
+	}
+	if o.Timeout != nil {
+		obj["timeout"] = *o.Timeout
+	}
+	if o.Op == "comment" {
+		obj["comment"] = o.Comment
+	}
+	return json.Marshal(obj)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *Operation) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Op        string      `json:"op"`
+		Table     string      `json:"table"`
+		Row       Row         `json:"row"`
+		Rows      []Row       `json:"rows"`
+		Columns   []string    `json:"columns"`
+		Mutations []Mutation  `json:"mutations"`
+		Where     []Condition `json:"where"`
+		UUIDName  string      `json:"uuid-name"`
+		Until     string      `json:"until"`
+		Timeout   *int        `json:"timeout"`
+		Comment   string      `json:"comment"`
+	}
+	if err := decodeJSON(b, &raw); err != nil {
+		return err
+	}
+	*o = Operation(raw)
+	return nil
+}
+
+// OperationResult is the result of a single operation in a transaction.
+type OperationResult struct {
+	Count   int    `json:"count,omitempty"`
+	UUID    UUID   `json:"-"`
+	Rows    []Row  `json:"rows,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r OperationResult) MarshalJSON() ([]byte, error) {
+	type plain OperationResult
+	if r.UUID == "" {
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(struct {
+		plain
+		UUID UUID `json:"uuid"`
+	}{plain(r), r.UUID})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (r *OperationResult) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Count   int             `json:"count"`
+		UUID    json.RawMessage `json:"uuid"`
+		Rows    []Row           `json:"rows"`
+		Error   string          `json:"error"`
+		Details string          `json:"details"`
+	}
+	if err := decodeJSON(b, &raw); err != nil {
+		return err
+	}
+
+	*r = OperationResult{
+		Count:   raw.Count,
+		Rows:    raw.Rows,
+		Error:   raw.Error,
+		Details: raw.Details,
+	}
+	if len(raw.UUID) > 0 {
+		var v interface{}
+		if err := decodeJSON(raw.UUID, &v); err != nil {
+			return err
+		}
+		dv, err := decodeValue(v)
+		if err != nil {
+			return err
+		}
+		uuid, ok := dv.(UUID)
+		if !ok {
+			return fmt.Errorf("unexpected uuid value %v", v)
+		}
+		r.UUID = uuid
+	}
+	return nil
+}
+
+// decodeJSON unmarshals b into v, keeping numbers as json.Number so that
+// integers survive the round trip.
+func decodeJSON(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// decodeTriple decodes the [string, string, value] form shared by
+// conditions and mutations.
+func decodeTriple(b []byte) (string, string, interface{}, error) {
+	var raw []interface{}
+	if err := decodeJSON(b, &raw); err != nil {
+		return "", "", nil, err
+	}
+	if len(raw) != 3 {
+		return "", "", nil, fmt.Errorf("expected 3 elements, got %d", len(raw))
+	}
+	first, ok1 := raw[0].(string)
+	second, ok2 := raw[1].(string)
+	if !ok1 || !ok2 {
+		return "", "", nil, fmt.Errorf("expected string elements, got %v", raw[:2])
+	}
+	value, err := decodeValue(raw[2])
+	if err != nil {
+		return "", "", nil, err
+	}
+	return first, second, value, nil
+}
+
+// decodeValue converts a generic JSON value into its OVSDB representation.
+func decodeValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i), nil
+		}
+		return v.Float64()
+	case []interface{}:
+		return decodeTagged(v)
+	default:
+		return v, nil
+	}
+}
+
+// decodeTagged decodes the ["uuid", ...], ["named-uuid", ...], ["set", ...]
+// and ["map", ...] forms.
+func decodeTagged(v []interface{}) (interface{}, error) {
+	if len(v) != 2 {
+		return nil, fmt.Errorf("unexpected array value %v", v)
+	}
+	tag, ok := v[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected array value %v", v)
+	}
+
+	switch tag {
+	case "uuid", "named-uuid":
+		s, ok := v[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", tag, v[1])
+		}
+		if tag == "uuid" {
+			return UUID(s), nil
+		}
+		return NamedUUID(s), nil
+	case "set":
+		elems, ok := v[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid set value %v", v[1])
+		}
+		set := make(Set, 0, len(elems))
+		for _, e := range elems {
+			de, err := decodeValue(e)
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, de)
+		}
+		return set, nil
+	case "map":
+		pairs, ok := v[1].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid map value %v", v[1])
+		}
+		m := make(Map, len(pairs))
+		for _, p := range pairs {
+			pair, ok := p.([]interface{})
+			if !ok || len(pair) != 2 {
+				return nil, fmt.Errorf("invalid map pair %v", p)
+			}
+			k, err := decodeValue(pair[0])
+			if err != nil {
+				return nil, err
+			}
+			val, err := decodeValue(pair[1])
+			if err != nil {
+				return nil, err
+			}
+			m[k] = val
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown value tag %q", tag)
+}
+
+// Strings returns the string atoms held by v, which may be a single atom or
+// a Set.
+func Strings(v interface{}) []string {
+	var out []string
+	for _, e := range Elements(v) {
+		if s, ok := e.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// StringMap returns the string pairs of v, a Map such as an external_ids
+// column. Pairs of other types are left out.
+func StringMap(v interface{}) map[string]string {
+	m, ok := v.(Map)
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		ks, ok1 := k.(string)
+		vs, ok2 := v.(string)
+		if ok1 && ok2 {
+			out[ks] = vs
+		}
+	}
+	return out
+}
+
+// UUIDs returns the UUID atoms held by v, which may be a single atom or a Set.
+func UUIDs(v interface{}) []UUID {
+	var out []UUID
+	for _, e := range Elements(v) {
+		if u, ok := e.(UUID); ok {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+// Elements returns the atoms of v. OVSDB encodes a set with exactly one
+// element as a bare atom, so a non-Set value is treated as a one element set.
+func Elements(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case Set:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
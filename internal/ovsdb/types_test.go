@@ -0,0 +1,140 @@
+package ovsdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestOperationMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		op   Operation
+		want string
+	}{
+		{
+			name: "insert with named uuid reference",
+			op: Operation{
+				Op:       "insert",
+				Table:    "Port",
+				UUIDName: "port",
+				Row: Row{
+					"name":       "pepe0",
+					"interfaces": NamedUUID("iface"),
+				},
+			},
+			want: `{"op":"insert","row":{"interfaces":["named-uuid","iface"],"name":"pepe0"},"table":"Port","uuid-name":"port"}`,
+		},
+		{
+			name: "select always carries where",
+			op:   Operation{Op: "select", Table: "Open_vSwitch", Columns: []string{"next_cfg"}},
+			want: `{"columns":["next_cfg"],"op":"select","table":"Open_vSwitch","where":[]}`,
+		},
+		{
+			name: "mutate",
+			op: Operation{
+				Op:        "mutate",
+				Table:     "Open_vSwitch",
+				Where:     []Condition{{Column: "_uuid", Function: "==", Value: UUID("731977d5-f606-4bb7-8778-ff2fa2aeb3a9")}},
+				Mutations: []Mutation{{Column: "next_cfg", Mutator: "+=", Value: 1}},
+			},
+			want: `{"mutations":[["next_cfg","+=",1]],"op":"mutate","table":"Open_vSwitch","where":[["_uuid","==",["uuid","731977d5-f606-4bb7-8778-ff2fa2aeb3a9"]]]}`,
+		},
+		{
+			name: "set and map values",
+			op: Operation{
+				Op:    "update",
+				Table: "Bridge",
+				Row: Row{
+					"bridges":      Set{},
+					"external_ids": Map{"b": "2", "a": "1"},
+				},
+			},
+			want: `{"op":"update","row":{"bridges":["set",[]],"external_ids":["map",[["a","1"],["b","2"]]]},"table":"Bridge","where":[]}`,
+		},
+		{
+			name: "comment",
+			op:   Operation{Op: "comment", Comment: "terraform"},
+			want: `{"comment":"terraform","op":"comment"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.op)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOperationResultUnmarshalJSON(t *testing.T) {
+	// Result of the add-br transaction recorded in docs/ovs-vsctl.md.
+	raw := `[
+		{},
+		{"uuid": ["uuid", "63665e39-8601-4248-8258-9ac33ef822c4"]},
+		{"count": 1},
+		{"rows": [{"next_cfg": 1, "bridges": ["set", [["uuid", "7523cffb-1dcf-4b7c-9746-354c49dc9aa5"]]], "external_ids": ["map", [["k", "v"]]]}]},
+		{"error": "constraint violation", "details": "duplicate name"}
+	]`
+
+	var results []OperationResult
+	if err := json.Unmarshal([]byte(raw), &results); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	want := []OperationResult{
+		{},
+		{UUID: "63665e39-8601-4248-8258-9ac33ef822c4"},
+		{Count: 1},
+		{Rows: []Row{{
+			"next_cfg":     1,
+			"bridges":      Set{UUID("7523cffb-1dcf-4b7c-9746-354c49dc9aa5")},
+			"external_ids": Map{"k": "v"},
+		}}},
+		{Error: "constraint violation", Details: "duplicate name"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got  %#v\nwant %#v", results, want)
+	}
+}
+
+func TestElements(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  []string
+	}{
+		{name: "nil", value: nil, want: nil},
+		{name: "bare atom", value: "OpenFlow13", want: []string{"OpenFlow13"}},
+		{name: "set", value: Set{"OpenFlow10", "OpenFlow13"}, want: []string{"OpenFlow10", "OpenFlow13"}},
+		{name: "empty set", value: Set{}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Strings(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Strings(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	uuids := UUIDs(Set{UUID("a"), UUID("b")})
+	if !reflect.DeepEqual(uuids, []UUID{"a", "b"}) {
+		t.Errorf("UUIDs = %v", uuids)
+	}
+}
+
+func TestStringMap(t *testing.T) {
+	got := StringMap(Map{"managed-by": "terraform-provider-openvswitch", "n": 1})
+	if !reflect.DeepEqual(got, map[string]string{"managed-by": "terraform-provider-openvswitch"}) {
+		t.Errorf("StringMap = %v", got)
+	}
+	if got := StringMap(Set{}); got != nil {
+		t.Errorf("StringMap of a non-map = %v, want nil", got)
+	}
+}
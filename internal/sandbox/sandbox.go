@@ -0,0 +1,263 @@
+// Package sandbox runs a private Open vSwitch in a directory of its own, the
+// way the sandbox target of the Open vSwitch build does.
+//
+// A Sandbox starts ovsdb-server on a fresh database and ovs-vswitchd with
+// --disable-system, so bridges must use the userspace netdev datapath and no
+// kernel module is involved. Both daemons keep their sockets, logs and pid
+// files in the sandbox directory, which ovs-vsctl, ovs-ofctl and ovs-appctl
+// find through OVS_RUNDIR. Several sandboxes can run side by side.
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// DatapathType is the datapath bridges in a sandbox must use.
+const DatapathType = "netdev"
+
+// schemaPaths lists where Open vSwitch installs the vswitch schema.
+var schemaPaths = []string{
+	"/usr/share/openvswitch/vswitch.ovsschema",
+	"/usr/local/share/openvswitch/vswitch.ovsschema",
+}
+
+// Options customizes a Sandbox.
+type Options struct {
+	// Dir is the directory the sandbox runs in. It defaults to a new
+	// temporary directory, removed when the sandbox is closed.
+	Dir string
+
+	// SchemaPath is the vswitch schema the database is created from. It
+	// defaults to the schema installed with Open vSwitch.
+	SchemaPath string
+
+	// OVSDBToolPath, OVSDBServerPath and OVSVswitchdPath locate the
+	// binaries. Bare names are resolved through PATH.
+	OVSDBToolPath   string
+	OVSDBServerPath string
+	OVSVswitchdPath string
+
+	// StartTimeout bounds how long the daemons may take to come up. It
+	// defaults to 10 seconds.
+	StartTimeout time.Duration
+}
+
+// Sandbox is a running ovsdb-server and ovs-vswitchd pair.
+type Sandbox struct {
+	dir       string
+	removeDir bool
+
+	// daemons holds the running processes in start order.
+	daemons []*exec.Cmd
+}
+
+// Start creates the database and starts the daemons. The sandbox must be
+// closed to stop them.
+func Start(ctx context.Context, opts Options) (*Sandbox, error) {
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.StartTimeout)
+	defer cancel()
+
+	schema, err := schemaPath(opts.SchemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sandbox{dir: opts.Dir}
+	if s.dir == "" {
+		if s.dir, err = os.MkdirTemp("", "ovs-sandbox"); err != nil {
+			return nil, fmt.Errorf("error creating sandbox directory: %w", err)
+		}
+		s.removeDir = true
+	}
+
+	if err := s.start(ctx, schema, opts); err != nil {
+		return nil, errors.Join(err, s.Close())
+	}
+	return s, nil
+}
+
+func (s *Sandbox) start(ctx context.Context, schema string, opts Options) error {
+	db := s.path("conf.db")
+	out, err := s.command(binary(opts.OVSDBToolPath, "ovsdb-tool"), "create", db, schema).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error creating sandbox database: %w: %s", err, out)
+	}
+
+	if err := s.startDaemon(binary(opts.OVSDBServerPath, "ovsdb-server"), db,
+		"--remote=punix:"+s.path("db.sock"),
+	); err != nil {
+		return err
+	}
+	if err := s.waitForSocket(ctx, s.path("db.sock")); err != nil {
+		return err
+	}
+	if err := s.initDatabase(ctx); err != nil {
+		return err
+	}
+
+	if err := s.startDaemon(binary(opts.OVSVswitchdPath, "ovs-vswitchd"), s.Endpoint(),
+		"--disable-system",
+	); err != nil {
+		return err
+	}
+	return s.waitForSocket(ctx, s.path("ovs-vswitchd.ctl"))
+}
+
+// initDatabase creates the root row of the Open_vSwitch database, like
+// ovs-vsctl init.
+func (s *Sandbox) initDatabase(ctx context.Context) error {
+	client, err := ovsdb.Dial(ctx, s.Endpoint(), ovsdb.DialOptions{})
+	if err != nil {
+		return fmt.Errorf("error connecting to sandbox ovsdb-server: %w", err)
+	}
+	defer client.Close()
+
+	if _, err := client.Transact(ctx, "Open_vSwitch", ovsdb.Operation{
+		Op:    "insert",
+		Table: "Open_vSwitch",
+		Row:   ovsdb.Row{},
+	}); err != nil {
+		return fmt.Errorf("error initializing sandbox database: %w", err)
+	}
+	return nil
+}
+
+// startDaemon starts the daemon at path with the arguments that keep its
+// control socket, log and pid file in the sandbox directory.
+func (s *Sandbox) startDaemon(path string, args ...string) error {
+	name := filepath.Base(path)
+	cmd := s.command(path, append(args,
+		"--no-chdir",
+		"--unixctl="+s.path(name+".ctl"),
+		"--pidfile="+s.path(name+".pid"),
+		"--log-file="+s.path(name+".log"),
+	)...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting %s: %w", name, err)
+	}
+	s.daemons = append(s.daemons, cmd)
+	return nil
+}
+
+// waitForSocket waits for a daemon to create the unix socket at path.
+func (s *Sandbox) waitForSocket(ctx context.Context, path string) error {
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error waiting for %s: %w (see the logs in %s)", filepath.Base(path), ctx.Err(), s.dir)
+		case <-ticker.C:
+		}
+	}
+}
+
+// command returns cmd running with the OVS directories pointing into the
+// sandbox.
+func (s *Sandbox) command(name string, args ...string) *exec.Cmd {
+	cmd := exec.Command(name, args...)
+	cmd.Env = append(os.Environ(), s.Env()...)
+	return cmd
+}
+
+func (s *Sandbox) path(name string) string {
+	return filepath.Join(s.dir, name)
+}
+
+// Dir returns the sandbox directory, which is also the OVS run directory.
+func (s *Sandbox) Dir() string {
+	return s.dir
+}
+
+// Endpoint returns the OVSDB remote of the sandbox.
+func (s *Sandbox) Endpoint() string {
+	return "unix:" + s.path("db.sock")
+}
+
+// Env returns the environment variables that point the OVS utilities at the
+// sandbox.
+func (s *Sandbox) Env() []string {
+	return []string{
+		"OVS_RUNDIR=" + s.dir,
+		"OVS_LOGDIR=" + s.dir,
+		"OVS_DBDIR=" + s.dir,
+		"OVS_SYSCONFDIR=" + s.dir,
+	}
+}
+
+// Close stops the daemons, ovs-vswitchd first, and removes the sandbox
+// directory if Start created it.
+func (s *Sandbox) Close() error {
+	var errs []error
+	for i := len(s.daemons) - 1; i >= 0; i-- {
+		if err := stop(s.daemons[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.daemons = nil
+
+	if s.removeDir {
+		if err := os.RemoveAll(s.dir); err != nil {
+			errs = append(errs, fmt.Errorf("error removing sandbox directory: %w", err))
+		}
+		s.removeDir = false
+	}
+	return errors.Join(errs...)
+}
+
+// stop terminates cmd, killing it if it does not exit promptly.
+func stop(cmd *exec.Cmd) error {
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("error stopping %s: %w", filepath.Base(cmd.Path), err)
+	}
+	select {
+	case <-done:
+		// The daemons exit with a signal status, which is expected here
+		return nil
+	case <-time.After(5 * time.Second):
+		if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			return fmt.Errorf("error killing %s: %w", filepath.Base(cmd.Path), err)
+		}
+		<-done
+		return nil
+	}
+}
+
+// schemaPath returns path, or the installed vswitch schema if path is empty.
+func schemaPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	for _, p := range schemaPaths {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("vswitch.ovsschema not found in %v", schemaPaths)
+}
+
+// binary returns path, or name if path is empty.
+func binary(path, name string) string {
+	if path != "" {
+		return path
+	}
+	return name
+}
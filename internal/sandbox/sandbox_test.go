@@ -0,0 +1,223 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb/ovsdbtest"
+)
+
+// TestMain lets the test binary stand in for the OVS daemons: started
+// through a link named after one of them, it plays that program.
+func TestMain(m *testing.M) {
+	fakes := map[string]func([]string) error{
+		"ovsdb-tool":   fakeOVSDBTool,
+		"ovsdb-server": fakeOVSDBServer,
+		"ovs-vswitchd": fakeVswitchd,
+	}
+	if fake, ok := fakes[filepath.Base(os.Args[0])]; ok {
+		if err := fake(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// flag returns the value of the --name= argument in args.
+func flag(args []string, name string) string {
+	for _, arg := range args {
+		if v, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+// fakeOVSDBTool implements ovsdb-tool create.
+func fakeOVSDBTool(args []string) error {
+	if len(args) != 3 || args[0] != "create" {
+		return fmt.Errorf("unexpected arguments %v", args)
+	}
+	if _, err := os.Stat(args[2]); err != nil {
+		return err
+	}
+	return os.WriteFile(args[1], nil, 0o600)
+}
+
+// fakeOVSDBServer serves an empty database on the --remote socket until it
+// is terminated.
+func fakeOVSDBServer(args []string) error {
+	socket, ok := strings.CutPrefix(flag(args, "remote"), "punix:")
+	if !ok {
+		return fmt.Errorf("unexpected arguments %v", args)
+	}
+	if _, err := os.Stat(args[0]); err != nil {
+		return err
+	}
+
+	// ovsdb-tool creates the database without the root row, so remove it
+	// before the socket shows up under its name
+	server, err := ovsdbtest.NewServer(socket + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer server.Close()
+	ctx := context.Background()
+	client, err := ovsdb.Dial(ctx, server.Endpoint(), ovsdb.DialOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = client.Transact(ctx, ovsdbtest.VswitchDatabase, ovsdb.Operation{Op: "delete", Table: "Open_vSwitch"})
+	client.Close()
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(socket+".tmp", socket); err != nil {
+		return err
+	}
+	return waitForTerm(args)
+}
+
+// fakeVswitchd checks how it was started, then opens its control socket
+// and waits to be terminated.
+func fakeVswitchd(args []string) error {
+	if args[0] != "unix:"+filepath.Join(os.Getenv("OVS_RUNDIR"), "db.sock") {
+		return fmt.Errorf("unexpected database %s", args[0])
+	}
+	disabled := false
+	for _, arg := range args {
+		disabled = disabled || arg == "--disable-system"
+	}
+	if !disabled {
+		return errors.New("started without --disable-system")
+	}
+
+	ctx := context.Background()
+	client, err := ovsdb.Dial(ctx, args[0], ovsdb.DialOptions{})
+	if err != nil {
+		return err
+	}
+	results, err := client.Transact(ctx, ovsdbtest.VswitchDatabase, ovsdb.Operation{Op: "select", Table: "Open_vSwitch"})
+	client.Close()
+	if err != nil {
+		return err
+	}
+	if len(results[0].Rows) != 1 {
+		return fmt.Errorf("expected the database to be initialized, got %d root rows", len(results[0].Rows))
+	}
+	return waitForTerm(args)
+}
+
+// waitForTerm listens on the --unixctl socket until SIGTERM.
+func waitForTerm(args []string) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+	l, err := net.Listen("unix", flag(args, "unixctl"))
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	<-signals
+	return nil
+}
+
+// fakeOptions returns Options running the fakes of TestMain.
+func fakeOptions(t *testing.T) Options {
+	t.Helper()
+	bin := t.TempDir()
+	for _, name := range []string{"ovsdb-tool", "ovsdb-server", "ovs-vswitchd"} {
+		if err := os.Symlink(os.Args[0], filepath.Join(bin, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schema := filepath.Join(bin, "vswitch.ovsschema")
+	if err := os.WriteFile(schema, []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Options{
+		SchemaPath:      schema,
+		OVSDBToolPath:   filepath.Join(bin, "ovsdb-tool"),
+		OVSDBServerPath: filepath.Join(bin, "ovsdb-server"),
+		OVSVswitchdPath: filepath.Join(bin, "ovs-vswitchd"),
+		StartTimeout:    5 * time.Second,
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	s, err := Start(context.Background(), fakeOptions(t))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	daemons := s.daemons
+
+	if s.Endpoint() != "unix:"+filepath.Join(s.Dir(), "db.sock") {
+		t.Errorf("unexpected endpoint %s", s.Endpoint())
+	}
+	if env := s.Env(); env[0] != "OVS_RUNDIR="+s.Dir() {
+		t.Errorf("unexpected environment %v", env)
+	}
+	for _, name := range []string{"conf.db", "db.sock", "ovsdb-server.ctl", "ovs-vswitchd.ctl"} {
+		if _, err := os.Stat(filepath.Join(s.Dir(), name)); err != nil {
+			t.Errorf("err: %s", err)
+		}
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, d := range daemons {
+		if d.ProcessState == nil || !d.ProcessState.Exited() {
+			t.Errorf("%s still running", d.Path)
+		}
+	}
+	if _, err := os.Stat(s.Dir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the sandbox directory to be removed, got %v", err)
+	}
+}
+
+func TestSandboxKeepsGivenDir(t *testing.T) {
+	opts := fakeOptions(t)
+	opts.Dir = t.TempDir()
+	s, err := Start(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.Dir, "conf.db")); err != nil {
+		t.Errorf("expected the sandbox directory to be kept: %s", err)
+	}
+}
+
+func TestSandboxStartFailure(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	opts := fakeOptions(t)
+	opts.OVSVswitchdPath = filepath.Join(tmp, "missing")
+	if _, err := Start(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "error starting missing") {
+		t.Fatalf("expected ovs-vswitchd to fail to start, got %v", err)
+	}
+
+	// The daemons already started are stopped and the directory removed
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected the sandbox directory to be removed, found %v", entries)
+	}
+}
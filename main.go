@@ -1,17 +1,167 @@
 package main
 
 import (
-	"github.com/hashicorp/terraform/plugin"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/trvon/terraform-provider-openvswitch/openvswitch"
 )
 
+// version is set by goreleaser at build time.
+var version = "dev"
+
 func main() {
-	// The binary works for both Terraform and OpenTofu - they use the same plugin interface
-	// To detect if it's being called from OpenTofu, you could look at environment variables
-	// or command line args, but we don't need to do anything differently in this case
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "run the provider with support for debuggers like delve")
+	flag.Parse()
 
-	// Both Terraform and OpenTofu will properly load and use the same provider schema
-	plugin.Serve(&plugin.ServeOpts{
-		ProviderFunc: openvswitch.Provider,
+	// The binary works for both Terraform and OpenTofu: both speak plugin
+	// protocol v6 and resolve the provider from the same registry address
+	err := providerserver.Serve(context.Background(), openvswitch.New(version), providerserver.ServeOpts{
+		Address: "registry.terraform.io/trevon/openvswitch",
+		Debug:   debug,
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// commands are the subcommands of the binary, which otherwise serves the
+// provider to Terraform.
+var commands = map[string]func(args []string) error{
+	"export": export,
+	"doctor": doctor,
+}
+
+// errChecksFailed is returned by doctor when a check fails, after the
+// failure has been printed.
+var errChecksFailed = errors.New("some checks failed")
+
+// connectionUsage lists the flags parseFlags adds to every subcommand.
+const connectionUsage = "[--ovsdb-endpoint remote] [--ovs-rundir dir] [--ssl-private-key file --ssl-certificate file --ssl-ca-cert file] [--privilege-escalation method] [--ovs-ofctl-path path] [--ovs-appctl-path path] [--ip-path path] [--ssh-host host --ssh-user user --ssh-private-key file [--ssh-known-hosts file]]"
+
+// parseFlags parses the arguments of the subcommand name, which takes no
+// positional arguments, into a Config selecting the switch to work with.
+// Every subcommand takes the provider arguments that say how to reach the
+// switch: the endpoint, the SSL files, privilege escalation, the binary
+// paths and the ssh block. register adds the flags specific to the
+// subcommand, which usage lists. It returns nil and no error when help was
+// requested.
+func parseFlags(name, usage string, args []string, register func(*flag.FlagSet)) (*openvswitch.Config, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s %s %s\n\n", os.Args[0], name, strings.TrimSpace(usage+" "+connectionUsage))
+		flags.PrintDefaults()
+	}
+	config := &openvswitch.Config{}
+	var ssh openvswitch.SSHConfig
+	var keyFile, knownHostsFile string
+	flags.StringVar(&config.OVSDBEndpoint, "ovsdb-endpoint", os.Getenv("OVSDB_ENDPOINT"), "ovsdb-server remote, such as unix:/run/openvswitch/db.sock or tcp:10.0.0.1:6640")
+	flags.StringVar(&config.OVSRunDir, "ovs-rundir", os.Getenv("OVS_RUNDIR"), "OVS run directory holding db.sock and the control sockets")
+	flags.StringVar(&config.SSLPrivateKey, "ssl-private-key", os.Getenv("OVS_SSL_PRIVATE_KEY"), "PEM private key file for ssl: endpoints")
+	flags.StringVar(&config.SSLCertificate, "ssl-certificate", os.Getenv("OVS_SSL_CERTIFICATE"), "PEM certificate file for ssl: endpoints")
+	flags.StringVar(&config.SSLCACert, "ssl-ca-cert", os.Getenv("OVS_SSL_CA_CERT"), "PEM CA certificate file verifying ssl: endpoints")
+	flags.StringVar(&config.PrivilegeEscalation, "privilege-escalation", "sudo", "how commands gain root: none, sudo or doas")
+	flags.StringVar(&config.OVSOfctlPath, "ovs-ofctl-path", "", "path of ovs-ofctl, looked up in PATH by default")
+	flags.StringVar(&config.OVSAppctlPath, "ovs-appctl-path", "", "path of ovs-appctl, looked up in PATH by default")
+	flags.StringVar(&config.IPPath, "ip-path", "", "path of ip, looked up in PATH by default")
+	flags.StringVar(&ssh.Host, "ssh-host", "", "reach the switch on this host over SSH, with an optional :port suffix")
+	flags.StringVar(&ssh.User, "ssh-user", "", "login user on the SSH host")
+	flags.StringVar(&keyFile, "ssh-private-key", "", "file holding the PEM encoded private key to log in with")
+	flags.StringVar(&knownHostsFile, "ssh-known-hosts", "", "known_hosts file verifying the host key, ~/.ssh/known_hosts by default")
+	register(flags)
+
+	if err := flags.Parse(args); errors.Is(err, flag.ErrHelp) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return nil, fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+
+	if ssh.Host != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading SSH private key: %w", err)
+		}
+		ssh.PrivateKey = string(key)
+		if knownHostsFile != "" {
+			knownHosts, err := os.ReadFile(knownHostsFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading SSH known hosts: %w", err)
+			}
+			ssh.KnownHosts = string(knownHosts)
+		}
+		config.SSH = &ssh
+	}
+	return config, nil
+}
+
+// export runs the export subcommand, which prints the configuration of a
+// running switch as Terraform resources and import blocks.
+func export(args []string) error {
+	var bridge string
+	config, err := parseFlags("export", "[--bridge name]", args, func(flags *flag.FlagSet) {
+		flags.StringVar(&bridge, "bridge", "", "export only this bridge and its ports")
+	})
+	if config == nil {
+		return err
+	}
+	return openvswitch.Export(context.Background(), os.Stdout, config, bridge)
+}
+
+// doctor runs the doctor subcommand, which runs the preflight checks of the
+// provider and prints their outcome.
+func doctor(args []string) error {
+	config, err := parseFlags("doctor", "", args, func(*flag.FlagSet) {})
+	if config == nil {
+		return err
+	}
+
+	checks, err := openvswitch.Preflight(context.Background(), config)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	failed := false
+	for _, check := range checks {
+		if check.Err != nil && check.Warning {
+			fmt.Fprintf(w, "WARN\t%s\t%s\n", check.Name, check.Err)
+			fmt.Fprintf(w, "\t\t%s\n", check.Hint)
+			continue
+		}
+		if check.Err != nil {
+			failed = true
+			fmt.Fprintf(w, "FAIL\t%s\t%s\n", check.Name, check.Err)
+			fmt.Fprintf(w, "\t\t%s\n", check.Hint)
+			continue
+		}
+		fmt.Fprintf(w, "ok\t%s\t%s\n", check.Name, check.Result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed {
+		return errChecksFailed
+	}
+	return nil
 }
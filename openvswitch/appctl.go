@@ -0,0 +1,73 @@
+package openvswitch
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// appctlRow is a row of a table printed by ovs-appctl fdb/show or
+// mdb/show: the OpenFlow port number, or LOCAL, the VLAN, the MAC or group
+// address and the age.
+type appctlRow struct {
+	Port string
+	VLAN int
+	Addr string
+	Age  string
+}
+
+// appctlTable returns the rows of a table printed by the ovs-appctl
+// command, such as fdb/show or mdb/show:
+//
+//	port  VLAN  MAC                Age
+//	   1     0  52:54:00:12:34:56    3
+//
+// The header and blank lines are skipped.
+func appctlTable(out, command string) ([]appctlRow, error) {
+	var rows []appctlRow
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "port" {
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected ovs-appctl %s line %q", command, scanner.Text())
+		}
+		vlan, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected VLAN in ovs-appctl %s line %q", command, scanner.Text())
+		}
+		rows = append(rows, appctlRow{Port: fields[0], VLAN: vlan, Addr: fields[2], Age: fields[3]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// ofportNames maps the OpenFlow port numbers of the ports on bridge, as
+// ovs-appctl prints them, to the port names. LOCAL maps to the bridge.
+// Ports ovs-vswitchd has not added are left out.
+func ofportNames(ctx context.Context, client ovsClient, bridge string) (map[string]string, error) {
+	ports, err := client.listPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]string{"LOCAL": bridge}
+	for _, port := range ports {
+		row, err := client.getInterface(ctx, port)
+		switch {
+		case isNotFound(err):
+			continue
+		case err != nil:
+			return nil, err
+		}
+		if ofport, ok := interfaceOFPort(row); ok && ofport > 0 {
+			names[strconv.Itoa(ofport)] = port
+		}
+	}
+	return names, nil
+}
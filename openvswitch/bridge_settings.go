@@ -0,0 +1,251 @@
+package openvswitch
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// failModes lists the values of the Bridge fail_mode column. standalone is
+// what OVS does when the column is empty.
+var failModes = []string{"standalone", "secure"}
+
+// datapathTypes lists the datapaths a bridge can run on: the kernel module
+// or the userspace datapath.
+var datapathTypes = []string{"system", "netdev"}
+
+// bridgeSettings holds the Bridge configuration openvswitch_bridge manages
+// besides the OpenFlow versions, which have their own operations.
+type bridgeSettings struct {
+	// FailMode is what the bridge does while no controller is connected:
+	// standalone or secure. Empty leaves the OVS default.
+	FailMode string
+
+	// Controllers lists the targets of the OpenFlow controllers, such as
+	// tcp:127.0.0.1:6653.
+	Controllers []string
+
+	// DatapathType is the datapath the bridge runs on, such as system or
+	// netdev. Empty leaves it unchanged, and new bridges then use the
+	// datapath_type of the Config.
+	DatapathType string
+
+	// STPEnable and RSTPEnable turn on the Spanning Tree and Rapid
+	// Spanning Tree protocols.
+	STPEnable  bool
+	RSTPEnable bool
+
+	// MulticastSnooping turns on IGMP and MLD snooping.
+	MulticastSnooping bool
+
+	// FloodVLANs lists the VLANs on which the bridge floods instead of
+	// learning MAC addresses.
+	FloodVLANs []int
+
+	// OtherConfig holds the pairs of bridgeOtherConfigKeys to set in the
+	// other_config column, such as datapath-id or stp-priority. Keys left
+	// out are removed.
+	OtherConfig map[string]string
+}
+
+// bridgeOtherConfigKeys lists the Bridge other_config keys held by
+// bridgeSettings: those of the openvswitch_bridge attributes. Other keys
+// are left alone.
+var bridgeOtherConfigKeys = (&bridgeModel{}).otherConfig().keys()
+
+// columns returns the Bridge columns holding s, other than other_config,
+// and the operations that insert the Controller rows they refer to.
+func (s bridgeSettings) columns() (ovsdb.Row, []ovsdb.Operation) {
+	row := ovsdb.Row{"fail_mode": ovsdb.Set{}}
+	if s.FailMode != "" {
+		row["fail_mode"] = s.FailMode
+	}
+	if s.DatapathType != "" {
+		row["datapath_type"] = s.DatapathType
+	}
+	row["stp_enable"] = s.STPEnable
+	row["rstp_enable"] = s.RSTPEnable
+	row["mcast_snooping_enable"] = s.MulticastSnooping
+	vlans := make(ovsdb.Set, 0, len(s.FloodVLANs))
+	for _, vlan := range s.FloodVLANs {
+		vlans = append(vlans, vlan)
+	}
+	row["flood_vlans"] = vlans
+
+	controllers := make(ovsdb.Set, 0, len(s.Controllers))
+	ops := make([]ovsdb.Operation, 0, len(s.Controllers))
+	for i, target := range s.Controllers {
+		name := fmt.Sprintf("controller%d", i)
+		ops = append(ops, ovsdb.Operation{
+			Op:       "insert",
+			Table:    "Controller",
+			UUIDName: name,
+			Row:      ovsdb.Row{"target": target},
+		})
+		controllers = append(controllers, ovsdb.NamedUUID(name))
+	}
+	row["controller"] = controllers
+	return row, ops
+}
+
+// otherConfig returns the other_config pairs holding s.
+func (s bridgeSettings) otherConfig() ovsdb.Map {
+	config := make(ovsdb.Map, len(s.OtherConfig))
+	for key, value := range s.OtherConfig {
+		config[key] = value
+	}
+	return config
+}
+
+// bridgeFailMode returns the fail mode of a Bridge row, standalone if the
+// column is empty.
+func bridgeFailMode(row ovsdb.Row) string {
+	if mode := ovsdb.Strings(row["fail_mode"]); len(mode) == 1 {
+		return mode[0]
+	}
+	return failModes[0]
+}
+
+// bridgeDatapathType returns the datapath of a Bridge row, system if the
+// column is empty.
+func bridgeDatapathType(row ovsdb.Row) string {
+	if dt, _ := row["datapath_type"].(string); dt != "" {
+		return dt
+	}
+	return datapathTypes[0]
+}
+
+// bridgeFloodVLANs returns the flood_vlans of a Bridge row, sorted.
+func bridgeFloodVLANs(row ovsdb.Row) []int {
+	vlans := []int{}
+	for _, e := range ovsdb.Elements(row["flood_vlans"]) {
+		if vlan, ok := e.(int); ok {
+			vlans = append(vlans, vlan)
+		}
+	}
+	sort.Ints(vlans)
+	return vlans
+}
+
+// optionalString returns the value of an optional string column, or an
+// empty string if it has none.
+func optionalString(datum interface{}) string {
+	if values := ovsdb.Strings(datum); len(values) == 1 {
+		return values[0]
+	}
+	return ""
+}
+
+// controllerTargets returns the targets of Controller rows, sorted.
+func controllerTargets(rows []ovsdb.Row) []string {
+	targets := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if target, ok := row["target"].(string); ok {
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// sameTargets reports whether a and b hold the same controller targets.
+func sameTargets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// setBridgeSettings replaces the settings of bridge. The Controller rows
+// are only replaced when the targets change, so that ovs-vswitchd keeps
+// its connections otherwise; ovsdb-server garbage collects the old rows.
+// That decision rests on the controllers read from the snapshot, so the
+// transaction verifies them and is built again if another client changed
+// them first.
+func (c *Client) setBridgeSettings(ctx context.Context, bridge string, settings bridgeSettings) error {
+	unlock, err := c.bridges.lock(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return c.retry(ctx, "set bridge "+bridge, func() error {
+		return c.trySetBridgeSettings(ctx, bridge, settings)
+	})
+}
+
+// trySetBridgeSettings makes one attempt at setBridgeSettings. It fails
+// with an error wrapping errConflict if the controllers it read changed
+// before the transaction committed.
+func (c *Client) trySetBridgeSettings(ctx context.Context, bridge string, settings bridgeSettings) error {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("error configuring bridge %s: %w", bridge, err)
+	}
+	current, ok := snap.get("Bridge", bridge)
+	if !ok {
+		return fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	controllers := snap.lookup("Controller", ovsdb.UUIDs(current["controller"]))
+
+	row, inserts := settings.columns()
+	if sameTargets(controllerTargets(controllers), settings.Controllers) {
+		delete(row, "controller")
+		inserts = nil
+	}
+
+	ops := []ovsdb.Operation{verify("Bridge", bridge, ovsdb.Row{"controller": current["controller"]})}
+	ops = append(ops, inserts...)
+	ops = append(ops,
+		ovsdb.Operation{
+			Op:    "update",
+			Table: "Bridge",
+			Where: whereName(bridge),
+			Row:   row,
+		},
+		ovsdb.Operation{
+			Op:        "mutate",
+			Table:     "Bridge",
+			Where:     whereName(bridge),
+			Mutations: otherConfigMutations(bridgeOtherConfigKeys, settings.OtherConfig),
+		},
+	)
+	results, err := c.commit(ctx, "set bridge "+bridge, ops...)
+	if err != nil {
+		return fmt.Errorf("error configuring bridge %s: %w", bridge, conflict(err, 0))
+	}
+	if results[1+len(inserts)].Count == 0 {
+		return fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	return nil
+}
+
+// getControllers returns the Controller rows of bridge, sorted by target.
+func (c *Client) getControllers(ctx context.Context, bridge string) ([]ovsdb.Row, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading controllers of bridge %s: %w", bridge, err)
+	}
+	row, ok := snap.get("Bridge", bridge)
+	if !ok {
+		return nil, fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	rows := snap.lookup("Controller", ovsdb.UUIDs(row["controller"]))
+	sort.Slice(rows, func(i, j int) bool {
+		a, _ := rows[i]["target"].(string)
+		b, _ := rows[j]["target"].(string)
+		return a < b
+	})
+	return rows, nil
+}
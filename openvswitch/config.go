@@ -0,0 +1,450 @@
+package openvswitch
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-openvswitch/ovs"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// defaultOVSRunDir is the run directory used by a stock Open vSwitch install.
+const defaultOVSRunDir = "/var/run/openvswitch"
+
+// Privilege escalation methods accepted by privilege_escalation.
+const (
+	privilegeEscalationNone = "none"
+	privilegeEscalationSudo = "sudo"
+	privilegeEscalationDoas = "doas"
+)
+
+// privilegeEscalationMethods lists the valid privilege_escalation values.
+var privilegeEscalationMethods = []string{
+	privilegeEscalationNone,
+	privilegeEscalationSudo,
+	privilegeEscalationDoas,
+}
+
+// Config holds the provider-level settings used to reach an Open vSwitch
+// instance.
+type Config struct {
+	// OVSDBEndpoint is the ovsdb-server remote, for example
+	// unix:/run/openvswitch/db.sock or tcp:10.0.0.1:6640. It defaults to
+	// db.sock in the run directory.
+	OVSDBEndpoint string
+
+	// OVSRunDir overrides the directory in which ovs-ofctl and ovs-appctl
+	// look for the bridge management and control sockets.
+	OVSRunDir string
+
+	// CommandTimeout is the timeout in seconds applied to every OVS command
+	// and OVSDB transaction. Zero leaves the OVS default in place.
+	CommandTimeout int
+
+	// SSLPrivateKey, SSLCertificate and SSLCACert are the PEM files used to
+	// authenticate ssl: endpoints.
+	SSLPrivateKey  string
+	SSLCertificate string
+	SSLCACert      string
+
+	// PrivilegeEscalation is the command prefix used to gain root for every
+	// command the provider runs: none, sudo or doas.
+	PrivilegeEscalation string
+
+	// OVSOfctlPath, OVSAppctlPath and IPPath locate the binaries the
+	// provider runs. Bare names are resolved through PATH.
+	OVSOfctlPath  string
+	OVSAppctlPath string
+	IPPath        string
+
+	// SSH, when set, runs every command and OVSDB connection on a remote
+	// host instead of locally.
+	SSH *SSHConfig
+
+	// DatapathType is the datapath_type given to new bridges, for example
+	// netdev for a userspace switch. Empty leaves the OVS default.
+	DatapathType string
+
+	// Owner is recorded in the external_ids of the bridges and ports the
+	// provider creates. Only objects created under the same owner are
+	// deleted without force_destroy.
+	Owner string
+
+	// MaxRetries is how many times a transaction ovsdb-server asks to try
+	// again, or an OVS command that runs out of time, is retried with
+	// exponential backoff. Zero disables retries.
+	MaxRetries int
+}
+
+// Client bundles the OVS clients built from a Config. It is handed to every
+// CRUD function through the meta argument.
+type Client struct {
+	config *Config
+
+	// ssh is the connection to the remote host, or nil to run locally.
+	ssh *sshConn
+
+	// dbMu guards db, the lazily established ovsdb-server connection, and
+	// snap, the snapshot monitored on it.
+	dbMu sync.Mutex
+	db   *ovsdb.Client
+	snap *snapshot
+
+	// bridges serializes the changes made to each bridge.
+	bridges bridgeLocks
+
+	// portStatuses holds the port statuses of bridges read with ovs-ofctl
+	// show.
+	portStatuses portStatusCache
+
+	// ovsVersion and schemaVersion are the Open vSwitch release and
+	// Open_vSwitch schema version found by the preflight checks.
+	ovsVersion    string
+	schemaVersion string
+}
+
+// Client returns a Client configured from c.
+func (c *Config) Client() (*Client, error) {
+	if c.OVSDBEndpoint != "" && !validOVSDBEndpoint(c.OVSDBEndpoint) {
+		return nil, fmt.Errorf("invalid ovsdb_endpoint %q: must start with unix:, tcp: or ssl:", c.OVSDBEndpoint)
+	}
+	if c.CommandTimeout < 0 {
+		return nil, fmt.Errorf("command_timeout must not be negative, got %d", c.CommandTimeout)
+	}
+	if c.MaxRetries < 0 {
+		return nil, fmt.Errorf("max_retries must not be negative, got %d", c.MaxRetries)
+	}
+	if !validPrivilegeEscalation(c.PrivilegeEscalation) {
+		return nil, fmt.Errorf("invalid privilege_escalation %q: must be one of %v", c.PrivilegeEscalation, privilegeEscalationMethods)
+	}
+	if strings.HasPrefix(c.OVSDBEndpoint, "ssl:") &&
+		(c.SSLPrivateKey == "" || c.SSLCertificate == "" || c.SSLCACert == "") {
+		return nil, fmt.Errorf("ovsdb_endpoint %q requires ssl_private_key, ssl_certificate and ssl_ca_cert", c.OVSDBEndpoint)
+	}
+
+	client := &Client{config: c}
+	if c.SSH != nil {
+		client.ssh = &sshConn{config: c.SSH}
+	}
+
+	return client, nil
+}
+
+// newOVS returns an ovs.Client that runs its commands through c and offers
+// protocols to the switch.
+func (c *Client) newOVS(protocols []string) *ovs.Client {
+	options := []ovs.OptionFunc{
+		ovs.FlowFormat("OXM-OpenFlow14"),
+		ovs.Protocols(protocols),
+		ovs.Exec(c.run),
+		ovs.Pipe(c.pipe),
+	}
+	if c.config.CommandTimeout > 0 {
+		options = append(options, ovs.Timeout(c.config.CommandTimeout))
+	}
+	return ovs.New(options...)
+}
+
+// openFlow returns the ovs-ofctl service speaking only OpenFlow version
+// ofversion, as selected by a port's ofversion.
+func (c *Client) openFlow(ofversion string) *ovs.OpenFlowService {
+	return c.newOVS([]string{ofversion}).OpenFlow
+}
+
+// endpoint returns the ovsdb-server remote to connect to.
+func (c *Config) endpoint() string {
+	if c.OVSDBEndpoint != "" {
+		return c.OVSDBEndpoint
+	}
+	rundir := c.OVSRunDir
+	if rundir == "" {
+		rundir = defaultOVSRunDir
+	}
+	return "unix:" + filepath.Join(rundir, "db.sock")
+}
+
+// tlsConfig loads the client certificate and CA used for ssl: endpoints.
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.SSLCertificate, c.SSLPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error loading SSL certificate: %w", err)
+	}
+
+	ca, err := os.ReadFile(c.SSLCACert)
+	if err != nil {
+		return nil, fmt.Errorf("error reading SSL CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", c.SSLCACert)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+		// OVS PKI certificates identify switches and controllers rather
+		// than host names, so only the chain is verified.
+		InsecureSkipVerify: true, //nolint:gosec // chain is verified in VerifyPeerCertificate
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, pool)
+		},
+	}, nil
+}
+
+// verifyChain checks that the peer certificate chains up to roots.
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// context returns a context bounded by the configured command timeout.
+func (c *Client) context() (context.Context, context.CancelFunc) {
+	if c.config.CommandTimeout > 0 {
+		return context.WithTimeout(context.Background(), time.Duration(c.config.CommandTimeout)*time.Second)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// conn returns the ovsdb-server connection, dialing it on first use or after
+// the previous connection was lost. Each new connection starts monitoring a
+// fresh snapshot.
+func (c *Client) conn(ctx context.Context) (*ovsdb.Client, error) {
+	c.dbMu.Lock()
+	defer c.dbMu.Unlock()
+
+	if c.db != nil {
+		select {
+		case <-c.db.Done():
+			c.db = nil
+		default:
+			return c.db, nil
+		}
+	}
+
+	opts := ovsdb.DialOptions{Dial: c.dial}
+	endpoint := c.config.endpoint()
+	if strings.HasPrefix(endpoint, "ssl:") {
+		tlsConfig, err := c.config.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+
+	db, err := ovsdb.Dial(ctx, endpoint, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to ovsdb-server: %w", err)
+	}
+	snap := newSnapshot()
+	if err := db.Monitor(ctx, vswitchDatabase, snap.requests(), snap.update); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error monitoring ovsdb-server: %w", err)
+	}
+	c.db, c.snap = db, snap
+	return db, nil
+}
+
+// snapshot returns the snapshot of the tables monitored on the
+// ovsdb-server connection.
+func (c *Client) snapshot(ctx context.Context) (*snapshot, error) {
+	if _, err := c.conn(ctx); err != nil {
+		return nil, err
+	}
+	c.dbMu.Lock()
+	defer c.dbMu.Unlock()
+	return c.snap, nil
+}
+
+// run executes cmd, one of ovs-ofctl, ovs-appctl or ip, and returns its
+// combined output. It is also the ovs.ExecFunc of the client, so every
+// command that needs privileges goes through the same path. Commands that
+// run out of time are retried. The commands that only read the host go
+// through query instead.
+func (c *Client) run(cmd string, args ...string) ([]byte, error) {
+	return c.runContext(context.Background(), cmd, args...)
+}
+
+// runContext is run for callers that hold a context, such as data sources
+// reading with ovs-appctl: once ctx is done, the command is killed and no
+// longer retried.
+func (c *Client) runContext(ctx context.Context, cmd string, args ...string) ([]byte, error) {
+	name, argv := c.config.command(cmd, args...)
+	var out []byte
+	err := c.retry(ctx, cmd, func() error {
+		var err error
+		out, err = c.runOnce(ctx, name, argv)
+		return err
+	})
+	return out, err
+}
+
+// query executes cmd, one of cat, id or getent, on the host the provider
+// manages and returns its combined output. Unlike run, it neither escalates
+// privileges nor sets OVS_RUNDIR, and does not retry: these commands read
+// files and databases anyone can read, and do not talk to OVS.
+func (c *Client) query(cmd string, args ...string) ([]byte, error) {
+	return c.runOnce(context.Background(), cmd, args)
+}
+
+// runOnce executes the command line built by command until ctx is done.
+// Over SSH, each attempt is also bounded by the command timeout.
+func (c *Client) runOnce(ctx context.Context, name string, argv []string) ([]byte, error) {
+	if c.ssh != nil {
+		if c.config.CommandTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(c.config.CommandTimeout)*time.Second)
+			defer cancel()
+		}
+		return c.ssh.run(ctx, append([]string{name}, argv...), nil)
+	}
+	return exec.CommandContext(ctx, name, argv...).CombinedOutput()
+}
+
+// pipe is the ovs.PipeFunc of the client. It runs cmd like run, feeding stdin
+// to the process.
+func (c *Client) pipe(stdin io.Reader, cmd string, args ...string) ([]byte, error) {
+	name, argv := c.config.command(cmd, args...)
+	if c.ssh != nil {
+		input, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := c.context()
+		defer cancel()
+		return c.ssh.run(ctx, append([]string{name}, argv...), input)
+	}
+	command := exec.Command(name, argv...)
+	command.Stdin = stdin
+	return command.CombinedOutput()
+}
+
+// username returns the user commands run as before privilege escalation: the
+// SSH login user for a remote host, or the local user.
+func (c *Client) username() (string, error) {
+	if c.ssh != nil {
+		return c.config.SSH.User, nil
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("error getting current user: %w", err)
+	}
+	return u.Username, nil
+}
+
+// dial connects to an OVSDB socket, through the SSH host when one is
+// configured.
+func (c *Client) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	if c.ssh != nil {
+		return c.ssh.dial(ctx, network, address)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, address)
+}
+
+// command builds the command line for cmd. The binary is resolved from the
+// configured paths, the run directory is passed through env so that it
+// survives sudo's environment reset, and the privilege escalation prefix is
+// applied last.
+func (c *Config) command(cmd string, args ...string) (string, []string) {
+	argv := append([]string{c.binary(cmd)}, args...)
+	if c.OVSRunDir != "" && cmd != "ip" {
+		argv = append([]string{"env", "OVS_RUNDIR=" + c.OVSRunDir}, argv...)
+	}
+
+	switch c.PrivilegeEscalation {
+	case privilegeEscalationNone:
+		return argv[0], argv[1:]
+	case privilegeEscalationDoas:
+		return "doas", argv
+	default:
+		return "sudo", argv
+	}
+}
+
+// binary returns the configured path for cmd, or cmd itself.
+func (c *Config) binary(cmd string) string {
+	var path string
+	switch cmd {
+	case "ovs-ofctl":
+		path = c.OVSOfctlPath
+	case "ovs-appctl":
+		path = c.OVSAppctlPath
+	case "ip":
+		path = c.IPPath
+	}
+	if path == "" {
+		return cmd
+	}
+	return path
+}
+
+// validPrivilegeEscalation reports whether method is a known privilege
+// escalation method. The empty string selects the default, sudo.
+func validPrivilegeEscalation(method string) bool {
+	if method == "" {
+		return true
+	}
+	for _, m := range privilegeEscalationMethods {
+		if method == m {
+			return true
+		}
+	}
+	return false
+}
+
+// validOVSDBEndpoint reports whether endpoint uses one of the connection
+// methods understood by ovsdb-server.
+func validOVSDBEndpoint(endpoint string) bool {
+	for _, prefix := range []string{"unix:", "tcp:", "ssl:"} {
+		if strings.HasPrefix(endpoint, prefix) && len(endpoint) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientFromProviderData extracts the configured client handed to resources
+// and data sources. It returns nil when the provider is not configured yet,
+// which happens while Terraform validates configurations.
+func clientFromProviderData(data interface{}) (ovsClient, error) {
+	if data == nil {
+		return nil, nil
+	}
+	client, ok := data.(ovsClient)
+	if !ok || client == nil {
+		return nil, fmt.Errorf("provider is not configured: unexpected provider data type %T", data)
+	}
+	return client, nil
+}
@@ -0,0 +1,174 @@
+package openvswitch
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestConfigCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   Config
+		cmd      string
+		args     []string
+		wantName string
+		wantArgs []string
+	}{
+		{
+			name:     "defaults",
+			config:   Config{},
+			cmd:      "ovs-appctl",
+			args:     []string{"fdb/show", "br0"},
+			wantName: "sudo",
+			wantArgs: []string{"ovs-appctl", "fdb/show", "br0"},
+		},
+		{
+			name:     "endpoint ignored by ovs-ofctl",
+			config:   Config{OVSDBEndpoint: "tcp:127.0.0.1:6640"},
+			cmd:      "ovs-ofctl",
+			args:     []string{"mod-port", "br0", "p0", "up"},
+			wantName: "sudo",
+			wantArgs: []string{"ovs-ofctl", "mod-port", "br0", "p0", "up"},
+		},
+		{
+			name:     "rundir passed through env",
+			config:   Config{OVSRunDir: "/tmp/ovs"},
+			cmd:      "ovs-ofctl",
+			args:     []string{"mod-port", "br0", "p0", "up"},
+			wantName: "sudo",
+			wantArgs: []string{"env", "OVS_RUNDIR=/tmp/ovs", "ovs-ofctl", "mod-port", "br0", "p0", "up"},
+		},
+		{
+			name:     "no privilege escalation",
+			config:   Config{PrivilegeEscalation: "none", OVSOfctlPath: "/usr/local/bin/ovs-ofctl"},
+			cmd:      "ovs-ofctl",
+			args:     []string{"mod-port", "br0", "p0", "up"},
+			wantName: "/usr/local/bin/ovs-ofctl",
+			wantArgs: []string{"mod-port", "br0", "p0", "up"},
+		},
+		{
+			name:     "no privilege escalation with rundir",
+			config:   Config{PrivilegeEscalation: "none", OVSRunDir: "/tmp/ovs"},
+			cmd:      "ovs-appctl",
+			args:     []string{"version"},
+			wantName: "env",
+			wantArgs: []string{"OVS_RUNDIR=/tmp/ovs", "ovs-appctl", "version"},
+		},
+		{
+			name:     "doas with ip path",
+			config:   Config{PrivilegeEscalation: "doas", IPPath: "/sbin/ip", OVSRunDir: "/tmp/ovs"},
+			cmd:      "ip",
+			args:     []string{"tuntap", "del", "dev", "tap0", "mode", "tap"},
+			wantName: "doas",
+			wantArgs: []string{"/sbin/ip", "tuntap", "del", "dev", "tap0", "mode", "tap"},
+		},
+		{
+			name:     "sudo with ovs-appctl path",
+			config:   Config{PrivilegeEscalation: "sudo", OVSAppctlPath: "/opt/ovs/bin/ovs-appctl"},
+			cmd:      "ovs-appctl",
+			args:     []string{"version"},
+			wantName: "sudo",
+			wantArgs: []string{"/opt/ovs/bin/ovs-appctl", "version"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, args := tt.config.command(tt.cmd, tt.args...)
+			if name != tt.wantName {
+				t.Errorf("command name = %q, want %q", name, tt.wantName)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("command args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestValidOVSDBEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		valid    bool
+	}{
+		{endpoint: "unix:/run/openvswitch/db.sock", valid: true},
+		{endpoint: "tcp:127.0.0.1:6640", valid: true},
+		{endpoint: "ssl:10.0.0.1:6640", valid: true},
+		{endpoint: "unix:", valid: false},
+		{endpoint: "/run/openvswitch/db.sock", valid: false},
+		{endpoint: "punix:/run/openvswitch/db.sock", valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			if got := validOVSDBEndpoint(tt.endpoint); got != tt.valid {
+				t.Errorf("validOVSDBEndpoint(%q) = %v, want %v", tt.endpoint, got, tt.valid)
+			}
+		})
+	}
+}
+
+func TestConfigClientRejectsUnknownPrivilegeEscalation(t *testing.T) {
+	config := Config{PrivilegeEscalation: "su"}
+	if _, err := config.Client(); err == nil {
+		t.Fatal("expected an error for privilege_escalation \"su\"")
+	}
+}
+
+func TestProviderConfigure(t *testing.T) {
+	t.Setenv("OVS_SSL_CA_CERT", "/etc/openvswitch/ca.pem")
+
+	model := providerModel{
+		OVSDBEndpoint:  types.StringValue("unix:/var/run/ovs-alt/db.sock"),
+		OVSRunDir:      types.StringValue("/var/run/ovs-alt"),
+		CommandTimeout: types.Int64Value(10),
+
+		PrivilegeEscalation: types.StringValue("doas"),
+		IPPath:              types.StringValue("/usr/sbin/ip"),
+	}
+
+	config, err := model.config()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	want := Config{
+		OVSDBEndpoint:  "unix:/var/run/ovs-alt/db.sock",
+		OVSRunDir:      "/var/run/ovs-alt",
+		CommandTimeout: 10,
+		MaxRetries:     defaultMaxRetries,
+		SSLCACert:      "/etc/openvswitch/ca.pem",
+
+		PrivilegeEscalation: "doas",
+		OVSOfctlPath:        "ovs-ofctl",
+		OVSAppctlPath:       "ovs-appctl",
+		IPPath:              "/usr/sbin/ip",
+	}
+	if *config != want {
+		t.Errorf("config = %+v, want %+v", *config, want)
+	}
+}
+
+func TestProviderConfigureMaxRetries(t *testing.T) {
+	model := providerModel{MaxRetries: types.Int64Value(0)}
+	config, err := model.config()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.MaxRetries != 0 {
+		t.Errorf("expected max_retries = 0 to disable retries, got %d", config.MaxRetries)
+	}
+
+	model.MaxRetries = types.Int64Unknown()
+	if _, err := model.config(); err == nil {
+		t.Error("expected an error for an unknown max_retries")
+	}
+}
+
+func TestProviderConfigureUnknown(t *testing.T) {
+	model := providerModel{OVSDBEndpoint: types.StringUnknown()}
+	if _, err := model.config(); err == nil {
+		t.Error("expected an error for an unknown ovsdb_endpoint")
+	}
+}
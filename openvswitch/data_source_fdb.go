@@ -0,0 +1,143 @@
+package openvswitch
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSourceWithConfigure = (*fdbDataSource)(nil)
+
+// fdbDataSource reads the MAC addresses a bridge learned.
+type fdbDataSource struct {
+	client ovsClient
+}
+
+func newFdbDataSource() datasource.DataSource {
+	return &fdbDataSource{}
+}
+
+// fdbModel is the openvswitch_fdb data source data. The ID is the bridge
+// name.
+type fdbModel struct {
+	ID      types.String    `tfsdk:"id"`
+	Bridge  types.String    `tfsdk:"bridge"`
+	Entries []fdbEntryModel `tfsdk:"entries"`
+}
+
+// fdbEntryModel is a MAC address seen on a port.
+type fdbEntryModel struct {
+	Port   types.String `tfsdk:"port"`
+	VLAN   types.Int64  `tfsdk:"vlan"`
+	MAC    types.String `tfsdk:"mac"`
+	Age    types.Int64  `tfsdk:"age"`
+	Static types.Bool   `tfsdk:"static"`
+}
+
+func (d *fdbDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_fdb"
+}
+
+func (d *fdbDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "MAC addresses a bridge learned, as listed by ovs-appctl fdb/show",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Name of the bridge",
+			},
+			"bridge": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the bridge to read the MAC learning table of",
+			},
+			"entries": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "MAC addresses in the learning table of the bridge, one entry per MAC and VLAN",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"port": schema.StringAttribute{
+							Computed:    true,
+							Description: "Name of the port the MAC address was seen on, or its OpenFlow port number if the port is not on the bridge anymore",
+						},
+						"vlan": schema.Int64Attribute{
+							Computed:    true,
+							Description: "VLAN the MAC address was seen in",
+						},
+						"mac": schema.StringAttribute{
+							Computed:    true,
+							Description: "MAC address",
+						},
+						"age": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Seconds since a frame from the MAC address was seen. Null for static entries",
+						},
+						"static": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the entry was added with ovs-appctl fdb/add, and never ages out",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *fdbDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	client, err := clientFromProviderData(req.ProviderData)
+	if err != nil {
+		resp.Diagnostics.AddError("Unexpected provider data", err.Error())
+		return
+	}
+	d.client = client
+}
+
+func (d *fdbDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config fdbModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	bridge := config.Bridge.ValueString()
+
+	opCtx, cancel := d.client.context()
+	defer cancel()
+
+	// Resolving the ports first reports a missing bridge as such, rather
+	// than with the error of ovs-appctl
+	names, err := ofportNames(opCtx, d.client, bridge)
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading MAC addresses", err.Error())
+		return
+	}
+	entries, err := d.client.fdbShow(opCtx, bridge)
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading MAC addresses", err.Error())
+		return
+	}
+
+	state := fdbModel{
+		ID:      types.StringValue(bridge),
+		Bridge:  config.Bridge,
+		Entries: make([]fdbEntryModel, 0, len(entries)),
+	}
+	for _, e := range entries {
+		port := e.Port
+		if name, ok := names[port]; ok {
+			port = name
+		}
+		entry := fdbEntryModel{
+			Port:   types.StringValue(port),
+			VLAN:   types.Int64Value(int64(e.VLAN)),
+			MAC:    types.StringValue(e.MAC),
+			Age:    types.Int64Value(int64(e.Age)),
+			Static: types.BoolValue(e.Static),
+		}
+		if e.Static {
+			entry.Age = types.Int64Null()
+		}
+		state.Entries = append(state.Entries, entry)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
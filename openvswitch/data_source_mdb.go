@@ -0,0 +1,179 @@
+package openvswitch
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ datasource.DataSourceWithConfigure = (*mdbDataSource)(nil)
+
+// mdbDataSource reads the multicast groups a bridge learned by snooping
+// IGMP and MLD.
+type mdbDataSource struct {
+	client ovsClient
+}
+
+func newMdbDataSource() datasource.DataSource {
+	return &mdbDataSource{}
+}
+
+// mdbModel is the openvswitch_mdb data source data. The ID is the bridge
+// name.
+type mdbModel struct {
+	ID       types.String     `tfsdk:"id"`
+	Bridge   types.String     `tfsdk:"bridge"`
+	Groups   []mdbGroupModel  `tfsdk:"groups"`
+	MRouters []mdbRouterModel `tfsdk:"mrouters"`
+}
+
+// mdbGroupModel is a port that joined a multicast group.
+type mdbGroupModel struct {
+	Port  types.String `tfsdk:"port"`
+	VLAN  types.Int64  `tfsdk:"vlan"`
+	Group types.String `tfsdk:"group"`
+	Age   types.Int64  `tfsdk:"age"`
+}
+
+// mdbRouterModel is a port leading to a multicast router.
+type mdbRouterModel struct {
+	Port types.String `tfsdk:"port"`
+	VLAN types.Int64  `tfsdk:"vlan"`
+	Age  types.Int64  `tfsdk:"age"`
+}
+
+func (d *mdbDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_mdb"
+}
+
+func (d *mdbDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Multicast groups a bridge learned by snooping IGMP and MLD, as listed by ovs-appctl mdb/show",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:    true,
+				Description: "Name of the bridge",
+			},
+			"bridge": schema.StringAttribute{
+				Required:    true,
+				Description: "Name of the bridge to read the multicast groups of. Its lists are empty while mcast_snooping_enable is false",
+			},
+			"groups": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "Multicast groups joined by hosts behind the ports of the bridge, one entry per port and group",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"port": schema.StringAttribute{
+							Computed:    true,
+							Description: "Name of the port that joined the group, or its OpenFlow port number if the port is not on the bridge anymore",
+						},
+						"vlan": schema.Int64Attribute{
+							Computed:    true,
+							Description: "VLAN the group was joined on",
+						},
+						"group": schema.StringAttribute{
+							Computed:    true,
+							Description: "IPv4 or IPv6 address of the group",
+						},
+						"age": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Seconds since the last report for the group on the port",
+						},
+					},
+				},
+			},
+			"mrouters": schema.ListNestedAttribute{
+				Computed:    true,
+				Description: "Ports leading to multicast routers, learned from the queries they send",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"port": schema.StringAttribute{
+							Computed:    true,
+							Description: "Name of the port, or its OpenFlow port number if the port is not on the bridge anymore",
+						},
+						"vlan": schema.Int64Attribute{
+							Computed:    true,
+							Description: "VLAN the queries were received on",
+						},
+						"age": schema.Int64Attribute{
+							Computed:    true,
+							Description: "Seconds since the last query",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *mdbDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	client, err := clientFromProviderData(req.ProviderData)
+	if err != nil {
+		resp.Diagnostics.AddError("Unexpected provider data", err.Error())
+		return
+	}
+	d.client = client
+}
+
+func (d *mdbDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var config mdbModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	bridge := config.Bridge.ValueString()
+
+	opCtx, cancel := d.client.context()
+	defer cancel()
+
+	row, err := d.client.getBridge(opCtx, bridge)
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading multicast groups", err.Error())
+		return
+	}
+
+	// ovs-appctl fails for bridges that do not snoop
+	entries := []mdbEntry{}
+	if snooping, _ := row["mcast_snooping_enable"].(bool); snooping {
+		entries, err = d.client.mdbShow(opCtx, bridge)
+		if err != nil {
+			resp.Diagnostics.AddError("Error reading multicast groups", err.Error())
+			return
+		}
+	}
+	names, err := ofportNames(opCtx, d.client, bridge)
+	if err != nil {
+		resp.Diagnostics.AddError("Error reading multicast groups", err.Error())
+		return
+	}
+
+	state := mdbModel{
+		ID:       types.StringValue(bridge),
+		Bridge:   config.Bridge,
+		Groups:   []mdbGroupModel{},
+		MRouters: []mdbRouterModel{},
+	}
+	for _, e := range entries {
+		port := e.Port
+		if name, ok := names[port]; ok {
+			port = name
+		}
+		if e.Group == mdbQuerier {
+			state.MRouters = append(state.MRouters, mdbRouterModel{
+				Port: types.StringValue(port),
+				VLAN: types.Int64Value(int64(e.VLAN)),
+				Age:  types.Int64Value(int64(e.Age)),
+			})
+			continue
+		}
+		state.Groups = append(state.Groups, mdbGroupModel{
+			Port:  types.StringValue(port),
+			VLAN:  types.Int64Value(int64(e.VLAN)),
+			Group: types.StringValue(e.Group),
+			Age:   types.Int64Value(int64(e.Age)),
+		})
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
@@ -0,0 +1,234 @@
+package openvswitch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// exportableInterfaceTypes lists the Interface types an openvswitch_port
+// can manage: a network device attached by name.
+var exportableInterfaceTypes = map[string]bool{"": true, "system": true, "tap": true}
+
+// Export writes the bridges of the switch reached through config, or only
+// bridge if it is not empty, to w as openvswitch_bridge and openvswitch_port
+// resources, each preceded by the import block that brings it under
+// Terraform. Ports the provider cannot manage are listed in comments.
+func Export(ctx context.Context, w io.Writer, config *Config, bridge string) error {
+	client, err := config.Client()
+	if err != nil {
+		return err
+	}
+	return export(ctx, w, client, bridge)
+}
+
+func export(ctx context.Context, w io.Writer, client ovsClient, only string) error {
+	bridges := []string{only}
+	if only == "" {
+		var err error
+		if bridges, err = client.listBridges(ctx); err != nil {
+			return err
+		}
+	}
+
+	e := newExporter()
+	for _, bridge := range bridges {
+		if err := e.bridge(ctx, client, bridge); err != nil {
+			return err
+		}
+	}
+	_, err := e.file.WriteTo(w)
+	return err
+}
+
+// exporter builds the exported configuration.
+type exporter struct {
+	file *hclwrite.File
+
+	// names holds the resource names in use by resource type.
+	names map[string]map[string]bool
+}
+
+func newExporter() *exporter {
+	return &exporter{
+		file:  hclwrite.NewEmptyFile(),
+		names: make(map[string]map[string]bool),
+	}
+}
+
+// bridge exports bridge and its ports.
+func (e *exporter) bridge(ctx context.Context, client ovsClient, bridge string) error {
+	row, err := client.getBridge(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	ports, err := client.listPorts(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	controllers, err := client.getControllers(ctx, bridge)
+	if err != nil {
+		return err
+	}
+
+	body, ref := e.resource("openvswitch_bridge", bridge, bridge)
+	body.SetAttributeValue("name", cty.StringVal(bridge))
+	switch protocols := ovsdb.Strings(row["protocols"]); len(protocols) {
+	case 0:
+	case 1:
+		body.SetAttributeValue("ofversion", cty.StringVal(protocols[0]))
+	default:
+		values := make([]cty.Value, len(protocols))
+		for i, p := range protocols {
+			values[i] = cty.StringVal(p)
+		}
+		body.SetAttributeValue("protocols", cty.SetVal(values))
+	}
+	if mode := bridgeFailMode(row); mode != failModes[0] {
+		body.SetAttributeValue("fail_mode", cty.StringVal(mode))
+	}
+	if dt := bridgeDatapathType(row); dt != datapathTypes[0] {
+		body.SetAttributeValue("datapath_type", cty.StringVal(dt))
+	}
+	for _, column := range []string{"stp_enable", "rstp_enable", "mcast_snooping_enable"} {
+		if enabled, _ := row[column].(bool); enabled {
+			body.SetAttributeValue(column, cty.True)
+		}
+	}
+	setOtherConfig(body, (&bridgeModel{}).otherConfig(), row)
+	if targets := controllerTargets(controllers); len(targets) > 0 {
+		values := make([]cty.Value, len(targets))
+		for i, target := range targets {
+			values[i] = cty.StringVal(target)
+		}
+		body.SetAttributeValue("controllers", cty.SetVal(values))
+	}
+	if vlans := bridgeFloodVLANs(row); len(vlans) > 0 {
+		values := make([]cty.Value, len(vlans))
+		for i, vlan := range vlans {
+			values[i] = cty.NumberIntVal(int64(vlan))
+		}
+		body.SetAttributeValue("flood_vlans", cty.SetVal(values))
+	}
+
+	ofversion := bridgeOFVersion(row)
+	for _, port := range ports {
+		if err := e.port(ctx, client, ref, bridge, port, ofversion); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// port exports port on bridge, whose resource is ref. The port uses
+// ofversion, the version the provider picks when importing it.
+func (e *exporter) port(ctx context.Context, client ovsClient, ref hcl.Traversal, bridge, port, ofversion string) error {
+	iface, err := client.getInterface(ctx, port)
+	switch {
+	case isNotFound(err):
+		e.comment(fmt.Sprintf("Port %s on bridge %s is not exported: it has no interface of the same name", port, bridge))
+		return nil
+	case err != nil:
+		return err
+	}
+	if kind, _ := iface["type"].(string); !exportableInterfaceTypes[kind] {
+		e.comment(fmt.Sprintf("Port %s on bridge %s is not exported: openvswitch_port does not manage %s interfaces", port, bridge, kind))
+		return nil
+	}
+
+	row, err := client.getPort(ctx, port)
+	if err != nil {
+		return err
+	}
+
+	body, _ := e.resource("openvswitch_port", port, bridge+":"+port)
+	body.SetAttributeValue("name", cty.StringVal(port))
+	body.SetAttributeTraversal("bridge_id", append(ref, hcl.TraverseAttr{Name: "name"}))
+	if ofversion != defaultOFVersion {
+		body.SetAttributeValue("ofversion", cty.StringVal(ofversion))
+	}
+	setOtherConfig(body, (&portModel{}).otherConfig(), row)
+	return nil
+}
+
+// setOtherConfig sets the attributes of attrs found in the other_config
+// column of row on body.
+func setOtherConfig(body *hclwrite.Body, attrs otherConfigAttrs, row ovsdb.Row) {
+	attrs.read(ovsdb.StringMap(row["other_config"]))
+	for _, a := range attrs {
+		switch {
+		case a.str != nil && !a.str.IsNull():
+			body.SetAttributeValue(a.name, cty.StringVal(a.str.ValueString()))
+		case a.num != nil && !a.num.IsNull():
+			body.SetAttributeValue(a.name, cty.NumberIntVal(a.num.ValueInt64()))
+		case a.flag != nil && !a.flag.IsNull():
+			body.SetAttributeValue(a.name, cty.BoolVal(a.flag.ValueBool()))
+		}
+	}
+}
+
+// resource appends an import block with id and a resource block of
+// typeName, named after name, and returns the body of the resource block
+// and a reference to the resource.
+func (e *exporter) resource(typeName, name, id string) (*hclwrite.Body, hcl.Traversal) {
+	name = e.resourceName(typeName, name)
+	ref := hcl.Traversal{hcl.TraverseRoot{Name: typeName}, hcl.TraverseAttr{Name: name}}
+
+	root := e.file.Body()
+	e.separate()
+	imp := root.AppendNewBlock("import", nil).Body()
+	imp.SetAttributeTraversal("to", ref)
+	imp.SetAttributeValue("id", cty.StringVal(id))
+	root.AppendNewline()
+	return root.AppendNewBlock("resource", []string{typeName, name}).Body(), ref
+}
+
+// resourceName returns a resource name for the object called name that is
+// a valid identifier and not yet used for typeName.
+func (e *exporter) resourceName(typeName, name string) string {
+	base := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, name)
+	if c := base[0]; c == '-' || c >= '0' && c <= '9' {
+		base = "_" + base
+	}
+
+	used := e.names[typeName]
+	if used == nil {
+		used = make(map[string]bool)
+		e.names[typeName] = used
+	}
+	name = base
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	used[name] = true
+	return name
+}
+
+// separate appends a blank line unless the file is empty.
+func (e *exporter) separate() {
+	root := e.file.Body()
+	if len(root.BuildTokens(nil)) > 0 {
+		root.AppendNewline()
+	}
+}
+
+// comment appends a comment line.
+func (e *exporter) comment(text string) {
+	e.separate()
+	e.file.Body().AppendUnstructuredTokens(hclwrite.Tokens{
+		{Type: hclsyntax.TokenComment, Bytes: []byte("# " + text + "\n")},
+	})
+}
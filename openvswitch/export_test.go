@@ -0,0 +1,170 @@
+package openvswitch
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+func TestExport(t *testing.T) {
+	fake := newFakeOVS()
+	ctx := context.Background()
+	settings := bridgeSettings{
+		FailMode:          "secure",
+		Controllers:       []string{"tcp:127.0.0.1:6653"},
+		DatapathType:      "netdev",
+		STPEnable:         true,
+		MulticastSnooping: true,
+		FloodVLANs:        []int{20, 10},
+		OtherConfig:       map[string]string{"datapath-id": "00000000000000a1", "stp-priority": "4096", "mcast-snooping-aging-time": "60"},
+	}
+	if err := fake.addBridge(ctx, "br0", []string{"OpenFlow13"}, settings); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := fake.addBridge(ctx, "br-ex", []string{"OpenFlow10", "OpenFlow14"}, bridgeSettings{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, p := range []struct{ bridge, port string }{{"br0", "tap0"}, {"br0", "vnet1.100"}, {"br-ex", "eth1"}} {
+		fake.addDevice(p.port)
+		if err := fake.addPort(ctx, p.bridge, p.port); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if err := fake.setPortOtherConfig(ctx, "br0", "tap0", map[string]string{"stp-path-cost": "100"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := export(ctx, &out, fake, "br0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	want := `import {
+  to = openvswitch_bridge.br0
+  id = "br0"
+}
+
+resource "openvswitch_bridge" "br0" {
+  name                      = "br0"
+  ofversion                 = "OpenFlow13"
+  fail_mode                 = "secure"
+  datapath_type             = "netdev"
+  stp_enable                = true
+  mcast_snooping_enable     = true
+  datapath_id               = "00000000000000a1"
+  stp_priority              = 4096
+  mcast_snooping_aging_time = 60
+  controllers               = ["tcp:127.0.0.1:6653"]
+  flood_vlans               = [10, 20]
+}
+
+import {
+  to = openvswitch_port.tap0
+  id = "br0:tap0"
+}
+
+resource "openvswitch_port" "tap0" {
+  name          = "tap0"
+  bridge_id     = openvswitch_bridge.br0.name
+  stp_path_cost = 100
+}
+
+import {
+  to = openvswitch_port.vnet1_100
+  id = "br0:vnet1.100"
+}
+
+resource "openvswitch_port" "vnet1_100" {
+  name      = "vnet1.100"
+  bridge_id = openvswitch_bridge.br0.name
+}
+`
+	if out.String() != want {
+		t.Errorf("export --bridge br0 =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	// Without a bridge every bridge is exported, and ports use the version
+	// the provider picks when importing them
+	out.Reset()
+	if err := export(ctx, &out, fake, ""); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, s := range []string{
+		`protocols = ["OpenFlow10", "OpenFlow14"]`,
+		`bridge_id = openvswitch_bridge.br-ex.name
+  ofversion = "OpenFlow14"`,
+		`to = openvswitch_bridge.br0`,
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected the export to contain %q, got\n%s", s, out.String())
+		}
+	}
+
+	if err := export(ctx, &out, fake, "missing"); !isNotFound(err) {
+		t.Errorf("expected a not found error for a missing bridge, got %v", err)
+	}
+}
+
+func TestExportSkipsUnmanagedPorts(t *testing.T) {
+	client := newScriptedClient(t, emptyResults)
+	client.snap.update(ovsdb.TableUpdates{
+		"Bridge": {
+			"b": {New: ovsdb.Row{
+				"name":      "br0",
+				"protocols": ovsdb.Set{},
+				"ports":     ovsdb.Set{ovsdb.UUID("1"), ovsdb.UUID("2"), ovsdb.UUID("3"), ovsdb.UUID("4")},
+			}},
+		},
+		"Port": {
+			"1": {New: ovsdb.Row{"name": "br0"}},
+			"2": {New: ovsdb.Row{"name": "bond0"}},
+			"3": {New: ovsdb.Row{"name": "vx0"}},
+			"4": {New: ovsdb.Row{"name": "1eth"}},
+		},
+		"Interface": {
+			"i1": {New: ovsdb.Row{"name": "br0", "type": "internal"}},
+			"i2": {New: ovsdb.Row{"name": "eth2", "type": ""}},
+			"i3": {New: ovsdb.Row{"name": "vx0", "type": "vxlan"}},
+			"i4": {New: ovsdb.Row{"name": "1eth", "type": "system"}},
+		},
+	})
+
+	var out bytes.Buffer
+	if err := export(context.Background(), &out, client, ""); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, s := range []string{
+		"# Port bond0 on bridge br0 is not exported: it has no interface of the same name",
+		"# Port vx0 on bridge br0 is not exported: openvswitch_port does not manage vxlan interfaces",
+		`resource "openvswitch_port" "_1eth"`,
+		`ofversion = "OpenFlow10"`,
+	} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected the export to contain %q, got\n%s", s, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "protocols") {
+		t.Errorf("expected no protocols for a bridge using the OVS defaults, got\n%s", out.String())
+	}
+}
+
+func TestExportResourceName(t *testing.T) {
+	e := newExporter()
+	for _, tt := range []struct{ name, want string }{
+		{"br0", "br0"},
+		{"br-int", "br-int"},
+		{"eth0.100", "eth0_100"},
+		{"eth0_100", "eth0_100_2"},
+		{"9p", "_9p"},
+		{"-x", "_-x"},
+	} {
+		if got := e.resourceName("openvswitch_port", tt.name); got != tt.want {
+			t.Errorf("resourceName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if got := e.resourceName("openvswitch_bridge", "eth0.100"); got != "eth0_100" {
+		t.Errorf("resource names must only be unique per type, got %q", got)
+	}
+}
@@ -0,0 +1,56 @@
+package openvswitch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fdbStatic is the age ovs-appctl fdb/show prints for the MAC addresses
+// added with fdb/add, which never age out.
+const fdbStatic = "static"
+
+// fdbEntry is an entry of the MAC learning table of a bridge: MAC was seen
+// on port in vlan. Port is the OpenFlow port number as ovs-appctl prints
+// it, or LOCAL for the bridge's local port. Age is 0 for static entries.
+type fdbEntry struct {
+	Port   string
+	VLAN   int
+	MAC    string
+	Age    int
+	Static bool
+}
+
+// fdbShow returns the MAC learning table of bridge.
+func (c *Client) fdbShow(ctx context.Context, bridge string) ([]fdbEntry, error) {
+	out, err := c.runContext(ctx, "ovs-appctl", "fdb/show", bridge)
+	if err != nil {
+		return nil, fmt.Errorf("error reading MAC addresses of bridge %s: %w: %s", bridge, err, strings.TrimSpace(string(out)))
+	}
+	return parseFdbShow(string(out))
+}
+
+// parseFdbShow parses the output of ovs-appctl fdb/show:
+//
+//	 port  VLAN  MAC                Age
+//	    1     0  52:54:00:12:34:56    3
+//	LOCAL    10  aa:55:aa:55:00:01    0
+//	    2     0  02:00:00:00:00:02  static
+func parseFdbShow(out string) ([]fdbEntry, error) {
+	rows, err := appctlTable(out, "fdb/show")
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fdbEntry, 0, len(rows))
+	for _, row := range rows {
+		e := fdbEntry{Port: row.Port, VLAN: row.VLAN, MAC: row.Addr, Static: row.Age == fdbStatic}
+		if !e.Static {
+			if e.Age, err = strconv.Atoi(row.Age); err != nil {
+				return nil, fmt.Errorf("unexpected age %q of MAC %s in ovs-appctl fdb/show", row.Age, row.Addr)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,117 @@
+package openvswitch
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+const fdbShow = ` port  VLAN  MAC                Age
+    1     0  52:54:00:12:34:56    3
+LOCAL    10  aa:55:aa:55:00:01    0
+    2     0  02:00:00:00:00:02  static
+`
+
+func TestParseFdbShow(t *testing.T) {
+	entries, err := parseFdbShow(fdbShow)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	want := []fdbEntry{
+		{Port: "1", VLAN: 0, MAC: "52:54:00:12:34:56", Age: 3},
+		{Port: "LOCAL", VLAN: 10, MAC: "aa:55:aa:55:00:01", Age: 0},
+		{Port: "2", VLAN: 0, MAC: "02:00:00:00:00:02", Static: true},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("parseFdbShow = %+v, want %+v", entries, want)
+	}
+
+	if _, err := parseFdbShow("    1     0  52:54:00:12:34:56  old\n"); err == nil {
+		t.Error("expected an error for an unparsable age")
+	}
+	if _, err := parseFdbShow("    1     0  52:54:00:12:34:56\n"); err == nil {
+		t.Error("expected an error for a missing column")
+	}
+}
+
+func TestClientFdbShow(t *testing.T) {
+	client := testPreflightClient(t, `
+case "$2" in
+br0) printf '%s' '`+fdbShow+`' ;;
+*) echo "no such bridge" >&2; exit 2 ;;
+esac`)
+
+	entries, err := client.fdbShow(context.Background(), "br0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 3 || !entries[2].Static {
+		t.Errorf("fdbShow = %+v, want the three entries of the table", entries)
+	}
+
+	_, err = client.fdbShow(context.Background(), "br1")
+	if err == nil || !strings.Contains(err.Error(), "no such bridge") {
+		t.Errorf("expected the error of ovs-appctl, got %v", err)
+	}
+
+	// ovs-appctl does not run for a caller that already gave up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.fdbShow(ctx, "br0"); err == nil {
+		t.Error("expected an error for a canceled context")
+	}
+}
+
+func TestDataSourceFdb(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	if err := fake.addBridge(ctx, "br0", []string{"OpenFlow13"}, bridgeSettings{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	fake.addDevice("tap0")
+	if err := fake.addPort(ctx, "br0", "tap0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	config := map[string]tftypes.Value{"bridge": testString("br0")}
+
+	attrs, diags := testReadDataSource(t, server, "openvswitch_fdb", config)
+	if err := diagnosticsError(diags); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := testObjectList(t, attrs["entries"]); len(got) != 0 {
+		t.Errorf("entries = %v, want none before any traffic", got)
+	}
+
+	fake.learnMAC("tap0", 0, "52:54:00:12:34:56")
+	fake.learnMAC("br0", 10, "aa:55:aa:55:00:01")
+	attrs, diags = testReadDataSource(t, server, "openvswitch_fdb", config)
+	if err := diagnosticsError(diags); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	want := []map[string]string{
+		{"port": "tap0", "vlan": "0", "mac": "52:54:00:12:34:56", "age": "0", "static": "false"},
+		{"port": "br0", "vlan": "10", "mac": "aa:55:aa:55:00:01", "age": "0", "static": "false"},
+	}
+	if got := testObjectList(t, attrs["entries"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+
+	// Removing a port flushes the MAC addresses learned on it
+	if err := fake.deletePort(ctx, "br0", "tap0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	attrs, _ = testReadDataSource(t, server, "openvswitch_fdb", config)
+	if got := testObjectList(t, attrs["entries"]); len(got) != 1 || got[0]["port"] != "br0" {
+		t.Errorf("entries = %v, want only the MAC of br0", got)
+	}
+
+	_, diags = testReadDataSource(t, server, "openvswitch_fdb", map[string]tftypes.Value{"bridge": testString("missing")})
+	if !testHasError(diags) {
+		t.Error("expected an error for a missing bridge")
+	}
+}
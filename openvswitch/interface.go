@@ -0,0 +1,78 @@
+package openvswitch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// interfaceOFPort returns the OpenFlow port number of an Interface row. It
+// is -1 when ovs-vswitchd failed to add the interface, and missing until it
+// has tried.
+func interfaceOFPort(row ovsdb.Row) (int, bool) {
+	for _, e := range ovsdb.Elements(row["ofport"]) {
+		n, ok := e.(int)
+		return n, ok
+	}
+	return 0, false
+}
+
+// interfaceLinkState returns the link_state of an Interface row, up or
+// down, or "" while ovs-vswitchd does not report one.
+func interfaceLinkState(row ovsdb.Row) string {
+	if states := ovsdb.Strings(row["link_state"]); len(states) > 0 {
+		return states[0]
+	}
+	return ""
+}
+
+// interfaceError returns the error ovs-vswitchd reports for an Interface
+// row, such as "could not open network device tap0 (No such device)".
+func interfaceError(row ovsdb.Row) string {
+	if errs := ovsdb.Strings(row["error"]); len(errs) > 0 {
+		return errs[0]
+	}
+	return ""
+}
+
+// interfaceReady reports whether ovs-vswitchd has added the interface to
+// its bridge: it has an OpenFlow port number and a link state. A tap device
+// nobody holds open is ready with its link down.
+func interfaceReady(row ovsdb.Row) bool {
+	ofport, ok := interfaceOFPort(row)
+	return ok && ofport > 0 && interfaceLinkState(row) != ""
+}
+
+// waitForInterface waits until the interface called iface is ready, or
+// until ctx is done. It fails as soon as ovs-vswitchd gives up on the
+// interface, setting its OpenFlow port number to -1 and reporting an error,
+// and otherwise with the error it reports once ctx is done, if any.
+func waitForInterface(ctx context.Context, client ovsClient, iface string) error {
+	delay := 10 * time.Millisecond
+	for {
+		row, err := client.getInterface(ctx, iface)
+		switch {
+		case err == nil && interfaceReady(row):
+			return nil
+		case err != nil && !isNotFound(err):
+			return err
+		}
+		if ofport, ok := interfaceOFPort(row); ok && ofport == -1 && interfaceError(row) != "" {
+			return fmt.Errorf("interface %s is not usable: %s", iface, interfaceError(row))
+		}
+
+		select {
+		case <-ctx.Done():
+			if msg := interfaceError(row); msg != "" {
+				return fmt.Errorf("interface %s is not usable: %s", iface, msg)
+			}
+			return fmt.Errorf("waiting for interface %s to get an OpenFlow port and link state: %w", iface, ctx.Err())
+		case <-time.After(delay):
+		}
+		if delay < 500*time.Millisecond {
+			delay *= 2
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package openvswitch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// bridgeLocks serializes changes to each bridge. Terraform applies up to ten
+// resources at once, and ports added to or modified on the same bridge
+// concurrently race for the bridge's ports column and OpenFlow port numbers.
+// The zero value is ready to use.
+type bridgeLocks struct {
+	mu    sync.Mutex
+	locks map[string]*bridgeLock
+}
+
+// bridgeLock is the lock of one bridge. It is removed from bridgeLocks once
+// nobody holds or waits for it.
+type bridgeLock struct {
+	held chan struct{}
+	refs int
+}
+
+// lock waits until no other change to bridge is in progress, or until ctx
+// is done. The returned function releases the lock.
+func (l *bridgeLocks) lock(ctx context.Context, bridge string) (func(), error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*bridgeLock)
+	}
+	bl, ok := l.locks[bridge]
+	if !ok {
+		bl = &bridgeLock{held: make(chan struct{}, 1)}
+		l.locks[bridge] = bl
+	}
+	bl.refs++
+	l.mu.Unlock()
+
+	select {
+	case bl.held <- struct{}{}:
+	case <-ctx.Done():
+		l.release(bridge, bl)
+		return nil, fmt.Errorf("waiting for other changes to bridge %s: %w", bridge, ctx.Err())
+	}
+	return func() {
+		<-bl.held
+		l.release(bridge, bl)
+	}, nil
+}
+
+func (l *bridgeLocks) release(bridge string, bl *bridgeLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	bl.refs--
+	if bl.refs == 0 {
+		delete(l.locks, bridge)
+	}
+}
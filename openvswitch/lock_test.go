@@ -0,0 +1,92 @@
+package openvswitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBridgeLocksSerialize(t *testing.T) {
+	var locks bridgeLocks
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	holders := map[string]int{}
+	var wg sync.WaitGroup
+	for i := range 20 {
+		bridge := fmt.Sprintf("br%d", i%2)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := locks.lock(ctx, bridge)
+			if err != nil {
+				t.Errorf("err: %s", err)
+				return
+			}
+			defer unlock()
+
+			mu.Lock()
+			holders[bridge]++
+			if holders[bridge] > 1 {
+				t.Errorf("bridge %s locked twice", bridge)
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			holders[bridge]--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(locks.locks) != 0 {
+		t.Errorf("expected released locks to be removed, got %v", locks.locks)
+	}
+}
+
+func TestBridgeLocksIndependent(t *testing.T) {
+	var locks bridgeLocks
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	unlock0, err := locks.lock(ctx, "br0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer unlock0()
+
+	unlock1, err := locks.lock(ctx, "br1")
+	if err != nil {
+		t.Fatalf("expected br1 to be independent of br0: %s", err)
+	}
+	unlock1()
+}
+
+func TestBridgeLocksContext(t *testing.T) {
+	var locks bridgeLocks
+	unlock, err := locks.lock(context.Background(), "br0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := locks.lock(ctx, "br0"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait to time out, got %v", err)
+	}
+
+	unlock()
+	if len(locks.locks) != 0 {
+		t.Errorf("expected released locks to be removed, got %v", locks.locks)
+	}
+	unlock, err = locks.lock(context.Background(), "br0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlock()
+}
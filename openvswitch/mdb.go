@@ -0,0 +1,55 @@
+package openvswitch
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// mdbQuerier is the group ovs-appctl mdb/show lists for the ports leading
+// to a multicast router, learned from the IGMP and MLD queries it sends.
+const mdbQuerier = "querier"
+
+// mdbEntry is an entry of the multicast snooping table of a bridge: port
+// joined group on vlan, or leads to a multicast router if the group is
+// mdbQuerier. Port is the OpenFlow port number as ovs-appctl prints it, or
+// LOCAL for the bridge's local port.
+type mdbEntry struct {
+	Port  string
+	VLAN  int
+	Group string
+	Age   int
+}
+
+// mdbShow returns the multicast snooping table of bridge. It fails unless
+// multicast snooping is enabled on the bridge.
+func (c *Client) mdbShow(ctx context.Context, bridge string) ([]mdbEntry, error) {
+	out, err := c.runContext(ctx, "ovs-appctl", "mdb/show", bridge)
+	if err != nil {
+		return nil, fmt.Errorf("error reading multicast groups of bridge %s: %w: %s", bridge, err, strings.TrimSpace(string(out)))
+	}
+	return parseMdbShow(string(out))
+}
+
+// parseMdbShow parses the output of ovs-appctl mdb/show:
+//
+//	 port  VLAN  GROUP                Age
+//	    1     0  239.1.1.1              12
+//	LOCAL    10  ff0e::101               3
+//	    2     0  querier                 5
+func parseMdbShow(out string) ([]mdbEntry, error) {
+	rows, err := appctlTable(out, "mdb/show")
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]mdbEntry, 0, len(rows))
+	for _, row := range rows {
+		age, err := strconv.Atoi(row.Age)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected age %q of group %s in ovs-appctl mdb/show", row.Age, row.Addr)
+		}
+		entries = append(entries, mdbEntry{Port: row.Port, VLAN: row.VLAN, Group: row.Addr, Age: age})
+	}
+	return entries, nil
+}
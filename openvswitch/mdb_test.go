@@ -0,0 +1,135 @@
+package openvswitch
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+const mdbShow = ` port  VLAN  GROUP                Age
+    1     0  239.1.1.1              12
+LOCAL    10  ff0e::101               3
+    2     0  querier                 5
+`
+
+func TestParseMdbShow(t *testing.T) {
+	entries, err := parseMdbShow(mdbShow)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	want := []mdbEntry{
+		{Port: "1", VLAN: 0, Group: "239.1.1.1", Age: 12},
+		{Port: "LOCAL", VLAN: 10, Group: "ff0e::101", Age: 3},
+		{Port: "2", VLAN: 0, Group: mdbQuerier, Age: 5},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("parseMdbShow = %+v, want %+v", entries, want)
+	}
+
+	if entries, err := parseMdbShow(" port  VLAN  GROUP                Age\n"); err != nil || len(entries) != 0 {
+		t.Errorf("parseMdbShow of an empty table = %v, %v, want no entries", entries, err)
+	}
+	if _, err := parseMdbShow("    1  x  239.1.1.1  12\n"); err == nil {
+		t.Error("expected an error for an unparsable VLAN")
+	}
+}
+
+func TestClientMdbShow(t *testing.T) {
+	client := testPreflightClient(t, `
+case "$2" in
+br0) printf '%s' '`+mdbShow+`' ;;
+br2) exec sleep 10 ;;
+*) echo 'multicast snooping is disabled' >&2; exit 2 ;;
+esac`)
+
+	entries, err := client.mdbShow(context.Background(), "br0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(entries) != 3 || entries[0].Group != "239.1.1.1" {
+		t.Errorf("mdbShow = %+v, want the three entries of the table", entries)
+	}
+
+	_, err = client.mdbShow(context.Background(), "br1")
+	if err == nil || !strings.Contains(err.Error(), "multicast snooping is disabled") {
+		t.Errorf("expected the error of ovs-appctl, got %v", err)
+	}
+
+	// a hung ovs-appctl is killed once the caller gives up
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.mdbShow(ctx, "br2"); err == nil {
+		t.Error("expected an error once the context is done")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("mdbShow returned after %s, want it to stop with the context", elapsed)
+	}
+}
+
+func TestDataSourceMdb(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	if err := fake.addBridge(ctx, "br0", []string{"OpenFlow13"}, bridgeSettings{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, port := range []string{"tap0", "tap1"} {
+		fake.addDevice(port)
+		if err := fake.addPort(ctx, "br0", port); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	config := map[string]tftypes.Value{"bridge": testString("br0")}
+
+	// Bridges that do not snoop have no groups
+	attrs, diags := testReadDataSource(t, server, "openvswitch_mdb", config)
+	if err := diagnosticsError(diags); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := testObjectList(t, attrs["groups"]); len(got) != 0 {
+		t.Errorf("groups = %v, want none while multicast snooping is disabled", got)
+	}
+
+	if err := fake.setBridgeSettings(ctx, "br0", bridgeSettings{MulticastSnooping: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	fake.joinGroup("tap0", 0, "239.1.1.1")
+	fake.joinGroup("br0", 10, "ff0e::101")
+	fake.joinGroup("tap1", 0, mdbQuerier)
+
+	attrs, diags = testReadDataSource(t, server, "openvswitch_mdb", config)
+	if err := diagnosticsError(diags); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	wantGroups := []map[string]string{
+		{"port": "tap0", "vlan": "0", "group": "239.1.1.1", "age": "0"},
+		{"port": "br0", "vlan": "10", "group": "ff0e::101", "age": "0"},
+	}
+	if got := testObjectList(t, attrs["groups"]); !reflect.DeepEqual(got, wantGroups) {
+		t.Errorf("groups = %v, want %v", got, wantGroups)
+	}
+	wantRouters := []map[string]string{{"port": "tap1", "vlan": "0", "age": "0"}}
+	if got := testObjectList(t, attrs["mrouters"]); !reflect.DeepEqual(got, wantRouters) {
+		t.Errorf("mrouters = %v, want %v", got, wantRouters)
+	}
+
+	// Removing a port removes the groups it joined
+	if err := fake.deletePort(ctx, "br0", "tap0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	attrs, _ = testReadDataSource(t, server, "openvswitch_mdb", config)
+	if got := testObjectList(t, attrs["groups"]); len(got) != 1 || got[0]["port"] != "br0" {
+		t.Errorf("groups = %v, want only the group of br0", got)
+	}
+
+	_, diags = testReadDataSource(t, server, "openvswitch_mdb", map[string]tftypes.Value{"bridge": testString("missing")})
+	if !testHasError(diags) {
+		t.Error("expected an error for a missing bridge")
+	}
+}
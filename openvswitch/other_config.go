@@ -0,0 +1,108 @@
+package openvswitch
+
+import (
+	"strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// otherConfigAttr is an attribute of a resource model kept in an
+// other_config column under key. One of str, num and flag points at the
+// field of the model holding it.
+type otherConfigAttr struct {
+	name, key string
+
+	str  *types.String
+	num  *types.Int64
+	flag *types.Bool
+}
+
+// otherConfigAttrs lists the attributes of a resource model kept in an
+// other_config column.
+type otherConfigAttrs []otherConfigAttr
+
+// keys returns the other_config keys of attrs.
+func (attrs otherConfigAttrs) keys() []string {
+	keys := make([]string, len(attrs))
+	for i, a := range attrs {
+		keys[i] = a.key
+	}
+	return keys
+}
+
+// config returns the other_config pairs holding the values of attrs.
+// Null and unknown values are left out.
+func (attrs otherConfigAttrs) config() map[string]string {
+	config := make(map[string]string)
+	for _, a := range attrs {
+		switch {
+		case a.str != nil && !a.str.IsNull() && !a.str.IsUnknown():
+			config[a.key] = a.str.ValueString()
+		case a.num != nil && !a.num.IsNull() && !a.num.IsUnknown():
+			config[a.key] = strconv.FormatInt(a.num.ValueInt64(), 10)
+		case a.flag != nil && !a.flag.IsNull() && !a.flag.IsUnknown():
+			config[a.key] = strconv.FormatBool(a.flag.ValueBool())
+		}
+	}
+	return config
+}
+
+// read sets attrs from the pairs of an other_config column. Attributes
+// whose key is missing, or holds a value ovs-vswitchd would ignore, are set
+// to null.
+func (attrs otherConfigAttrs) read(config map[string]string) {
+	for _, a := range attrs {
+		value, ok := config[a.key]
+		switch {
+		case a.str != nil:
+			*a.str = types.StringNull()
+			if ok {
+				*a.str = types.StringValue(value)
+			}
+		case a.num != nil:
+			*a.num = types.Int64Null()
+			if n, err := strconv.ParseInt(value, 10, 64); ok && err == nil {
+				*a.num = types.Int64Value(n)
+			}
+		case a.flag != nil:
+			*a.flag = types.BoolNull()
+			if b, err := strconv.ParseBool(value); ok && err == nil {
+				*a.flag = types.BoolValue(b)
+			}
+		}
+	}
+}
+
+// changed reports whether any of attrs differs from the attribute at the
+// same position of other, the same attributes of another model.
+func (attrs otherConfigAttrs) changed(other otherConfigAttrs) bool {
+	for i, a := range attrs {
+		b := other[i]
+		switch {
+		case a.str != nil && !a.str.Equal(*b.str),
+			a.num != nil && !a.num.Equal(*b.num),
+			a.flag != nil && !a.flag.Equal(*b.flag):
+			return true
+		}
+	}
+	return false
+}
+
+// otherConfigMutations returns the mutations that remove keys from
+// other_config and then insert the pairs of config, leaving other keys
+// alone.
+func otherConfigMutations(keys []string, config map[string]string) []ovsdb.Mutation {
+	remove := make(ovsdb.Set, 0, len(keys))
+	for _, key := range keys {
+		remove = append(remove, key)
+	}
+	insert := make(ovsdb.Map, len(config))
+	for key, value := range config {
+		insert[key] = value
+	}
+	return []ovsdb.Mutation{
+		{Column: "other_config", Mutator: "delete", Value: remove},
+		{Column: "other_config", Mutator: "insert", Value: insert},
+	}
+}
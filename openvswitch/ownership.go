@@ -0,0 +1,121 @@
+package openvswitch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// external_ids keys stamped on the bridges and ports the provider creates.
+// Terraform does not tell providers the address of a resource, so the
+// resource key records its type and ID instead.
+const (
+	managedByKey   = "managed-by"
+	ownerKey       = "terraform-owner"
+	resourceKey    = "terraform-resource"
+	managedByValue = "terraform-provider-openvswitch"
+)
+
+// ownershipIDs returns the external_ids stamped on an object created for
+// the resource of type typeName with the given ID. owner may be empty.
+func ownershipIDs(owner, typeName, id string) ovsdb.Map {
+	ids := ovsdb.Map{
+		managedByKey: managedByValue,
+		resourceKey:  typeName + " " + id,
+	}
+	if owner != "" {
+		ids[ownerKey] = owner
+	}
+	return ids
+}
+
+// checkOwned returns an error unless row, the Bridge or Port row of what,
+// carries the external_ids the provider stamps on the objects it creates,
+// with owner as its owner.
+func checkOwned(row ovsdb.Row, owner, what string) error {
+	ids := ovsdb.StringMap(row["external_ids"])
+	if ids[managedByKey] != managedByValue {
+		return fmt.Errorf("%s was not created by %s", what, managedByValue)
+	}
+	if ids[ownerKey] != owner {
+		return fmt.Errorf("%s was created for owner %q, but the provider's owner is %q", what, ids[ownerKey], owner)
+	}
+	return nil
+}
+
+// foreignPorts returns the names of the ports on bridge, other than its
+// local port, that the provider does not own.
+func foreignPorts(ctx context.Context, client ovsClient, bridge string) ([]string, error) {
+	ports, err := client.listPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	var foreign []string
+	for _, port := range ports {
+		row, err := client.getPort(ctx, port)
+		if err != nil {
+			return nil, err
+		}
+		if checkOwned(row, client.owner(), "port "+port) != nil {
+			foreign = append(foreign, port)
+		}
+	}
+	return foreign, nil
+}
+
+// foreignDetail explains why deleting the object of table called name was
+// refused, and how to delete it anyway.
+func foreignDetail(err error, table, name, owner string) string {
+	tag := fmt.Sprintf("external_ids:%s=%s", managedByKey, managedByValue)
+	if owner != "" {
+		tag += fmt.Sprintf(" external_ids:%s=%q", ownerKey, owner)
+	}
+	return fmt.Sprintf("%s. The provider only deletes the bridges and ports it created.\n\n"+
+		"To delete it anyway, set force_destroy = true and apply before destroying it. If an earlier version of the provider created it, tag it instead with:\n\n"+
+		"  ovs-vsctl set %s %s %s", err, table, name, tag)
+}
+
+// bridgeDeleteRefusal returns why bridge must not be deleted without
+// force_destroy, or an empty string if it may be: the provider did not
+// create it, or deleting it would delete ports the provider did not create.
+func bridgeDeleteRefusal(ctx context.Context, client ovsClient, bridge string) (string, error) {
+	row, err := client.getBridge(ctx, bridge)
+	switch {
+	case isNotFound(err):
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	if err := checkOwned(row, client.owner(), "bridge "+bridge); err != nil {
+		return foreignDetail(err, "Bridge", bridge, client.owner()), nil
+	}
+
+	foreign, err := foreignPorts(ctx, client, bridge)
+	if err != nil {
+		return "", err
+	}
+	if len(foreign) > 0 {
+		return fmt.Sprintf("bridge %s has ports the provider did not create: %s. Deleting the bridge would delete them too.\n\n"+
+			"Remove them from the bridge first, or set force_destroy = true and apply before destroying the bridge to delete them with it.",
+			bridge, strings.Join(foreign, ", ")), nil
+	}
+	return "", nil
+}
+
+// portDeleteRefusal returns why port must not be deleted without
+// force_destroy, or an empty string if it may be.
+func portDeleteRefusal(ctx context.Context, client ovsClient, port string) (string, error) {
+	row, err := client.getPort(ctx, port)
+	switch {
+	case isNotFound(err):
+		return "", nil
+	case err != nil:
+		return "", err
+	}
+	if err := checkOwned(row, client.owner(), "port "+port); err != nil {
+		return foreignDetail(err, "Port", port, client.owner()), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,261 @@
+package openvswitch
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-openvswitch/ovs"
+)
+
+// portConfigFlag describes a port_config value: the OpenFlow port config
+// flag printed by ovs-ofctl show, and the mod-port actions that set and
+// clear it.
+type portConfigFlag struct {
+	name  string
+	flag  string
+	set   ovs.PortAction
+	clear ovs.PortAction
+
+	// openFlow10 marks flags that only exist in OpenFlow 1.0.
+	openFlow10 bool
+}
+
+// portConfigFlags lists the values accepted in port_config.
+var portConfigFlags = []portConfigFlag{
+	{name: "down", flag: "PORT_DOWN", set: ovs.PortActionDown, clear: ovs.PortActionUp},
+	{name: "no-stp", flag: "NO_STP", set: ovs.PortActionNoSTP, clear: ovs.PortActionSTP, openFlow10: true},
+	{name: "no-receive", flag: "NO_RECV", set: ovs.PortActionNoReceive, clear: ovs.PortActionReceive},
+	{name: "no-receive-stp", flag: "NO_RECV_STP", set: ovs.PortActionNoReceiveSTP, clear: ovs.PortActionReceiveSTP, openFlow10: true},
+	{name: "no-forward", flag: "NO_FWD", set: ovs.PortActionNoForward, clear: ovs.PortActionForward},
+	{name: "no-flood", flag: "NO_FLOOD", set: ovs.PortActionNoFlood, clear: ovs.PortActionFlood, openFlow10: true},
+	{name: "no-packet-in", flag: "NO_PACKET_IN", set: ovs.PortActionNoPacketIn, clear: ovs.PortActionPacketIn},
+}
+
+// portConfigNames returns the names of the values accepted in port_config.
+func portConfigNames() []string {
+	names := make([]string, 0, len(portConfigFlags))
+	for _, f := range portConfigFlags {
+		names = append(names, f.name)
+	}
+	return names
+}
+
+// portStatus holds the OpenFlow config and state of a port, using the
+// port_config names for config flags and lower-case ovs-ofctl names, such as
+// link-down, for state flags.
+type portStatus struct {
+	Config []string
+	State  []string
+}
+
+// showPort returns the status of port on bridge as reported by ovs-ofctl
+// show, speaking OpenFlow version ofversion. The ports of a bridge are read
+// together and shared by the reads that follow within portStatusTTL.
+func (c *Client) showPort(bridge, port, ofversion string) (*portStatus, error) {
+	ports, err := c.portStatuses.get(bridge, ofversion, func() (map[string]*portStatus, error) {
+		out, err := c.run("ovs-ofctl", "--protocols="+ofversion, "show", bridge)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+		}
+		return parseBridgeStatus(string(out))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading port %s on bridge %s: %w", port, bridge, err)
+	}
+	status, ok := ports[port]
+	if !ok {
+		return nil, fmt.Errorf("port %s in ovs-ofctl show: %w", port, errNotFound)
+	}
+	return status, nil
+}
+
+// portStatusTTL is how long the port statuses of a bridge read by showPort
+// are reused, so that refreshing the ports of a bridge runs ovs-ofctl show
+// once rather than once per port.
+const portStatusTTL = 2 * time.Second
+
+// portStatusCache holds the port statuses of bridges, by bridge and
+// OpenFlow version. Reads of a bridge arriving while its ports are read
+// wait for that read instead of starting another.
+type portStatusCache struct {
+	mu      sync.Mutex
+	bridges map[string]map[string]*portStatusRead
+}
+
+// portStatusRead is one ovs-ofctl show of a bridge. ports and err are set
+// before done is closed.
+type portStatusRead struct {
+	done    chan struct{}
+	ports   map[string]*portStatus
+	err     error
+	expires time.Time
+}
+
+// fresh reports whether r is still being read, or was read successfully
+// less than portStatusTTL ago.
+func (r *portStatusRead) fresh() bool {
+	select {
+	case <-r.done:
+		return r.err == nil && time.Now().Before(r.expires)
+	default:
+		return true
+	}
+}
+
+// get returns the port statuses of bridge spoken with ofversion, calling
+// read unless a fresh result is at hand. Failures are not kept.
+func (c *portStatusCache) get(bridge, ofversion string, read func() (map[string]*portStatus, error)) (map[string]*portStatus, error) {
+	c.mu.Lock()
+	if r := c.bridges[bridge][ofversion]; r != nil && r.fresh() {
+		c.mu.Unlock()
+		<-r.done
+		return r.ports, r.err
+	}
+	r := &portStatusRead{done: make(chan struct{})}
+	if c.bridges == nil {
+		c.bridges = make(map[string]map[string]*portStatusRead)
+	}
+	if c.bridges[bridge] == nil {
+		c.bridges[bridge] = make(map[string]*portStatusRead)
+	}
+	c.bridges[bridge][ofversion] = r
+	c.mu.Unlock()
+
+	r.ports, r.err = read()
+	r.expires = time.Now().Add(portStatusTTL)
+	close(r.done)
+	return r.ports, r.err
+}
+
+// forget discards the port statuses of bridge, once mod-port changed them.
+func (c *portStatusCache) forget(bridge string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.bridges, bridge)
+}
+
+// modPort applies a mod-port action to port on bridge, speaking OpenFlow
+// version ofversion.
+func (c *Client) modPort(bridge, port, ofversion string, action ovs.PortAction) error {
+	ctx, cancel := c.context()
+	defer cancel()
+	unlock, err := c.bridges.lock(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	defer c.portStatuses.forget(bridge)
+	if err := c.openFlow(ofversion).ModPort(bridge, port, action); err != nil {
+		return fmt.Errorf("error applying %s to port %s with %s: %w", action, port, ofversion, err)
+	}
+	return nil
+}
+
+// setPortConfig brings the config flags of port in line with want, issuing
+// one mod-port per flag that differs from current.
+func setPortConfig(client ovsClient, bridge, port, ofversion string, want []string, current *portStatus) error {
+	wanted := make(map[string]bool, len(want))
+	for _, name := range want {
+		wanted[name] = true
+	}
+	have := make(map[string]bool, len(current.Config))
+	for _, name := range current.Config {
+		have[name] = true
+	}
+
+	for _, f := range portConfigFlags {
+		if wanted[f.name] == have[f.name] {
+			continue
+		}
+		action := f.clear
+		if wanted[f.name] {
+			action = f.set
+		}
+		if err := client.modPort(bridge, port, ofversion, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parsePortStatus finds port in the output of ovs-ofctl show and parses its
+// config and state lines.
+func parsePortStatus(out, port string) (*portStatus, error) {
+	ports, err := parseBridgeStatus(out)
+	if err != nil {
+		return nil, err
+	}
+	status, ok := ports[port]
+	if !ok {
+		return nil, fmt.Errorf("port %s in ovs-ofctl show: %w", port, errNotFound)
+	}
+	return status, nil
+}
+
+// parseBridgeStatus parses the config and state lines of every port in the
+// output of ovs-ofctl show, by port name:
+//
+//	1(tap0): addr:aa:55:aa:55:00:01
+//	    config:     PORT_DOWN NO_FLOOD
+//	    state:      LINK_DOWN
+//
+// A value of 0 means no flags are set.
+func parseBridgeStatus(out string) (map[string]*portStatus, error) {
+	ports := make(map[string]*portStatus)
+
+	var status *portStatus
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Port headers are indented by one space, their details by more.
+		if !strings.HasPrefix(line, "  ") {
+			status = nil
+			if _, rest, ok := strings.Cut(fields[0], "("); ok && strings.HasSuffix(rest, "):") {
+				status = &portStatus{Config: []string{}, State: []string{}}
+				ports[strings.TrimSuffix(rest, "):")] = status
+			}
+			continue
+		}
+		if status == nil {
+			continue
+		}
+
+		switch fields[0] {
+		case "config:":
+			for _, flag := range fields[1:] {
+				if name, ok := portConfigName(flag); ok {
+					status.Config = append(status.Config, name)
+				}
+			}
+		case "state:":
+			for _, flag := range fields[1:] {
+				if flag != "0" {
+					status.State = append(status.State, strings.ReplaceAll(strings.ToLower(flag), "_", "-"))
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ports, nil
+}
+
+// portConfigName maps an ovs-ofctl config flag to its port_config name.
+func portConfigName(flag string) (string, bool) {
+	for _, f := range portConfigFlags {
+		if f.flag == flag {
+			return f.name, true
+		}
+	}
+	return "", false
+}
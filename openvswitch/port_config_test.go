@@ -0,0 +1,202 @@
+package openvswitch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+const ofctlShowOF10 = `OFPT_FEATURES_REPLY (xid=0x2): dpid:0000b6a1c6b5d74e
+n_tables:254, n_buffers:0
+capabilities: FLOW_STATS TABLE_STATS PORT_STATS QUEUE_STATS ARP_MATCH_IP
+actions: output enqueue set_vlan_vid set_vlan_pcp strip_vlan mod_dl_src mod_dl_dst mod_nw_src mod_nw_dst mod_nw_tos mod_tp_src mod_tp_dst
+ 1(tap0): addr:2e:4f:3a:1c:9d:01
+     config:     PORT_DOWN NO_FLOOD NO_PACKET_IN
+     state:      LINK_DOWN
+     speed: 0 Mbps now, 0 Mbps max
+ 2(tap1): addr:2e:4f:3a:1c:9d:02
+     config:     0
+     state:      0
+     current:    10GB-FD COPPER
+     speed: 10000 Mbps now, 0 Mbps max
+ LOCAL(br0): addr:b6:a1:c6:b5:d7:4e
+     config:     PORT_DOWN
+     state:      LINK_DOWN
+     speed: 0 Mbps now, 0 Mbps max
+OFPT_GET_CONFIG_REPLY (xid=0x4): frags=normal miss_send_len=0
+`
+
+const ofctlShowOF13 = `OFPT_FEATURES_REPLY (OF1.3) (xid=0x2): dpid:0000b6a1c6b5d74e
+n_tables:254, n_buffers:0
+capabilities: FLOW_STATS TABLE_STATS PORT_STATS GROUP_STATS QUEUE_STATS
+OFPST_PORT_DESC reply (OF1.3) (xid=0x3):
+ 1(tap0): addr:2e:4f:3a:1c:9d:01
+     config:     NO_RECV NO_FWD
+     state:      LIVE
+     speed: 0 Mbps now, 0 Mbps max
+ LOCAL(br0): addr:b6:a1:c6:b5:d7:4e
+     config:     0
+     state:      LIVE
+     speed: 0 Mbps now, 0 Mbps max
+OFPT_GET_CONFIG_REPLY (OF1.3) (xid=0x5): frags=normal miss_send_len=0
+`
+
+func TestParsePortStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		out  string
+		port string
+		want *portStatus
+	}{
+		{
+			name: "OpenFlow10 flags",
+			out:  ofctlShowOF10,
+			port: "tap0",
+			want: &portStatus{Config: []string{"down", "no-flood", "no-packet-in"}, State: []string{"link-down"}},
+		},
+		{
+			name: "no flags",
+			out:  ofctlShowOF10,
+			port: "tap1",
+			want: &portStatus{Config: []string{}, State: []string{}},
+		},
+		{
+			name: "local port",
+			out:  ofctlShowOF10,
+			port: "br0",
+			want: &portStatus{Config: []string{"down"}, State: []string{"link-down"}},
+		},
+		{
+			name: "OpenFlow13 flags",
+			out:  ofctlShowOF13,
+			port: "tap0",
+			want: &portStatus{Config: []string{"no-receive", "no-forward"}, State: []string{"live"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePortStatus(tt.out, tt.port)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("status = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parsePortStatus(ofctlShowOF13, "tap"); !isNotFound(err) {
+		t.Errorf("expected a not found error for a port name prefix, got %v", err)
+	}
+}
+
+func TestClientShowPortReadsBridgeOnce(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := filepath.Join(dir, "ovs-ofctl")
+	body := "#!/bin/sh\necho \"$@\" >> " + log + "\ncase \"$*\" in *show*) printf '%s' '" + ofctlShowOF10 + "' ;; esac\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := (&Config{PrivilegeEscalation: "none", OVSOfctlPath: script}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	calls := func() []string {
+		out, err := os.ReadFile(log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSpace(string(out)), "\n")
+	}
+
+	for _, port := range []string{"tap0", "tap1", "br0"} {
+		if _, err := client.showPort("br0", port, "OpenFlow10"); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	if _, err := client.showPort("br0", "tap9", "OpenFlow10"); !isNotFound(err) {
+		t.Errorf("expected a not found error for tap9, got %v", err)
+	}
+	if got := calls(); len(got) != 1 {
+		t.Errorf("ovs-ofctl calls = %q, want one show for the whole bridge", got)
+	}
+
+	// mod-port changes what show reports, so the bridge is read again
+	if err := client.modPort("br0", "tap0", "OpenFlow10", "up"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.showPort("br0", "tap0", "OpenFlow10"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if got := calls(); len(got) != 3 {
+		t.Errorf("ovs-ofctl calls = %q, want show, mod-port and show", got)
+	}
+}
+
+func TestClientApplyPortConfig(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := filepath.Join(dir, "ovs-ofctl")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" >> "+log+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := (&Config{PrivilegeEscalation: "none", OVSOfctlPath: script}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	current := &portStatus{Config: []string{"down", "no-flood"}}
+	if err := setPortConfig(client, "br0", "tap0", "OpenFlow10", []string{"no-flood", "no-packet-in"}, current); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	out, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"mod-port --flow-format=OXM-OpenFlow14 --protocols=OpenFlow10 br0 tap0 up",
+		"mod-port --flow-format=OXM-OpenFlow14 --protocols=OpenFlow10 br0 tap0 no-packet-in",
+	}
+	if got := strings.Split(strings.TrimSpace(string(out)), "\n"); !reflect.DeepEqual(got, want) {
+		t.Errorf("ovs-ofctl calls = %q, want %q", got, want)
+	}
+}
+
+func TestResourcePortPortConfigRequiresOpenFlow10(t *testing.T) {
+	tests := []struct {
+		name      string
+		flags     []string
+		ofversion string
+		valid     bool
+	}{
+		{name: "OpenFlow13 flags", flags: []string{"down", "no-packet-in"}, valid: true},
+		{name: "OpenFlow10 only flag", flags: []string{"no-flood"}, valid: false},
+		{name: "OpenFlow10 only flag with OpenFlow10", flags: []string{"no-flood"}, ofversion: "OpenFlow10", valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]tftypes.Value{
+				"name":        tftypes.NewValue(tftypes.String, "tap0"),
+				"bridge_id":   tftypes.NewValue(tftypes.String, "br0"),
+				"port_config": testStringSet(tt.flags...),
+			}
+			if tt.ofversion != "" {
+				config["ofversion"] = tftypes.NewValue(tftypes.String, tt.ofversion)
+			}
+			diags := testValidateResourceConfig(t, "openvswitch_port", config)
+			if testHasError(diags) == tt.valid {
+				t.Errorf("diagnostics = %v, want valid %v", diags, tt.valid)
+			}
+		})
+	}
+}
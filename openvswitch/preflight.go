@@ -0,0 +1,249 @@
+package openvswitch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// preflightTimeout bounds each preflight check, so that a switch or sudo
+// that does not answer fails the check instead of hanging the provider.
+const preflightTimeout = 10 * time.Second
+
+// kernelModuleDir exists while the openvswitch kernel module is loaded.
+var kernelModuleDir = "/sys/module/openvswitch"
+
+// PreflightCheck is the outcome of one of the checks that the environment
+// the provider depends on is usable.
+type PreflightCheck struct {
+	// Name says what was checked, for example ovs-vswitchd.
+	Name string
+
+	// Result describes what was found when the check passed, such as the
+	// version of a binary.
+	Result string
+
+	// Err is why the check failed, and Hint how to fix it.
+	Err  error
+	Hint string
+
+	// Warning marks a check whose failure does not stop the provider,
+	// because only some configurations need what it checks.
+	Warning bool
+}
+
+// Preflight runs the preflight checks against the Open vSwitch reached
+// through config, as the provider does when it is configured.
+func Preflight(ctx context.Context, config *Config) ([]PreflightCheck, error) {
+	client, err := config.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.preflight(ctx), nil
+}
+
+// preflightStep is a check run by preflight. run returns what it found.
+type preflightStep struct {
+	name, hint string
+	warning    bool
+	run        func(ctx context.Context) (string, error)
+}
+
+// preflight checks that the commands the provider runs can gain the
+// configured privileges, that its binaries are installed, that ovsdb-server
+// and ovs-vswitchd answer, and whether the kernel datapath is available.
+// The versions found are recorded in c, so checks must run before c is
+// shared.
+func (c *Client) preflight(ctx context.Context) []PreflightCheck {
+	steps := c.privilegeSteps()
+	steps = append(steps, c.binarySteps()...)
+	steps = append(steps, c.daemonSteps()...)
+
+	checks := make([]PreflightCheck, 0, len(steps))
+	for _, step := range steps {
+		stepCtx, cancel := context.WithTimeout(ctx, preflightTimeout)
+		result, err := step.run(stepCtx)
+		cancel()
+		if err != nil {
+			checks = append(checks, PreflightCheck{Name: step.name, Err: err, Hint: step.hint, Warning: step.warning})
+			continue
+		}
+		checks = append(checks, PreflightCheck{Name: step.name, Result: result})
+	}
+	return checks
+}
+
+// privilegeSteps checks that the privilege escalation method runs commands
+// without asking for a password, which the provider cannot answer.
+func (c *Client) privilegeSteps() []preflightStep {
+	method := c.config.PrivilegeEscalation
+	switch method {
+	case privilegeEscalationNone:
+		return nil
+	case "":
+		method = privilegeEscalationSudo
+	}
+	return []preflightStep{{
+		name: "privilege escalation",
+		hint: fmt.Sprintf("Allow the user running the provider to use %s without a password, or set privilege_escalation", method),
+		run: func(ctx context.Context) (string, error) {
+			if _, err := c.probe(ctx, method, "-n", "true"); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s runs commands without a password", method), nil
+		},
+	}}
+}
+
+// binarySteps checks that the binaries the provider runs are installed, by
+// asking each for its version. ip is only needed when tap devices are not
+// managed over netlink.
+func (c *Client) binarySteps() []preflightStep {
+	binaries := []struct{ cmd, flag, pkg string }{
+		{"ovs-ofctl", "--version", "Open vSwitch"},
+		{"ovs-appctl", "--version", "Open vSwitch"},
+	}
+	if _, ok := c.taps().(*ipTaps); ok {
+		binaries = append(binaries, struct{ cmd, flag, pkg string }{"ip", "-V", "iproute2"})
+	}
+
+	steps := make([]preflightStep, 0, len(binaries))
+	for _, b := range binaries {
+		steps = append(steps, preflightStep{
+			name: b.cmd,
+			hint: fmt.Sprintf("Install %s, or set %s_path", b.pkg, strings.ReplaceAll(b.cmd, "-", "_")),
+			run: func(ctx context.Context) (string, error) {
+				return c.probeCommand(ctx, b.cmd, b.flag)
+			},
+		})
+	}
+	return steps
+}
+
+// daemonSteps checks that ovsdb-server and ovs-vswitchd answer, recording
+// their versions, and whether the kernel module is loaded. Bridges on the
+// netdev datapath do not need the module, and the provider cannot tell
+// which datapath the bridges to come use, so a missing module is only a
+// warning.
+func (c *Client) daemonSteps() []preflightStep {
+	return []preflightStep{
+		{
+			name: "ovsdb-server",
+			hint: "Start ovsdb-server, or set ovsdb_endpoint to the remote it listens on",
+			run: func(ctx context.Context) (string, error) {
+				version, err := c.readSchemaVersion(ctx)
+				if err != nil {
+					return "", err
+				}
+				c.schemaVersion = version
+				return fmt.Sprintf("%s serves the %s schema %s", c.config.endpoint(), vswitchDatabase, version), nil
+			},
+		},
+		{
+			name: "ovs-vswitchd",
+			hint: "Start ovs-vswitchd, or set ovs_rundir to the directory holding its control socket",
+			run: func(ctx context.Context) (string, error) {
+				out, err := c.probeCommand(ctx, "ovs-appctl", "version")
+				if err != nil {
+					return "", err
+				}
+				c.ovsVersion = parseOVSVersion(out)
+				return out, nil
+			},
+		},
+		{
+			name:    "kernel datapath",
+			hint:    "Load the openvswitch kernel module with modprobe openvswitch. Bridges with datapath_type = \"netdev\" run on the userspace datapath and do not need it",
+			warning: true,
+			run: func(ctx context.Context) (string, error) {
+				if err := c.probeDir(ctx, kernelModuleDir); err != nil {
+					return "", fmt.Errorf("the openvswitch kernel module is not loaded: %w", err)
+				}
+				return "the openvswitch kernel module is loaded", nil
+			},
+		},
+	}
+}
+
+// probeCommand runs cmd like run does, once and bounded by ctx, and returns
+// the first line of its output.
+func (c *Client) probeCommand(ctx context.Context, cmd string, args ...string) (string, error) {
+	name, argv := c.config.command(cmd, args...)
+	out, err := c.probe(ctx, name, argv...)
+	if err != nil {
+		return "", err
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	return line, nil
+}
+
+// probe executes name with argv, locally or on the SSH host, and returns its
+// combined output. Failures include the output, which explains them.
+func (c *Client) probe(ctx context.Context, name string, argv ...string) (string, error) {
+	var out []byte
+	var err error
+	if c.ssh != nil {
+		out, err = c.ssh.run(ctx, append([]string{name}, argv...), nil)
+	} else {
+		out, err = exec.CommandContext(ctx, name, argv...).CombinedOutput()
+	}
+	if err != nil {
+		cmdline := strings.Join(append([]string{name}, argv...), " ")
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return "", fmt.Errorf("%s: %w: %s", cmdline, err, msg)
+		}
+		return "", fmt.Errorf("%s: %w", cmdline, err)
+	}
+	return string(out), nil
+}
+
+// probeDir returns an error unless dir exists on the host OVS runs on.
+func (c *Client) probeDir(ctx context.Context, dir string) error {
+	if c.ssh != nil {
+		_, err := c.probe(ctx, "test", "-d", dir)
+		return err
+	}
+	info, err := os.Stat(dir)
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("%s is not a directory", dir)
+	}
+	return err
+}
+
+// readSchemaVersion returns the version of the Open_vSwitch schema served by
+// ovsdb-server.
+func (c *Client) readSchemaVersion(ctx context.Context) (string, error) {
+	db, err := c.conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	raw, err := db.GetSchema(ctx, vswitchDatabase)
+	if err != nil {
+		return "", fmt.Errorf("error reading the %s schema: %w", vswitchDatabase, err)
+	}
+	var schema struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return "", fmt.Errorf("error decoding the %s schema: %w", vswitchDatabase, err)
+	}
+	if schema.Version == "" {
+		return "", errors.New("the " + vswitchDatabase + " schema has no version")
+	}
+	return schema.Version, nil
+}
+
+// parseOVSVersion extracts the version from the output of ovs-appctl version,
+// such as "ovs-vswitchd (Open vSwitch) 3.1.0".
+func parseOVSVersion(out string) string {
+	fields := strings.Fields(out)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
@@ -0,0 +1,192 @@
+package openvswitch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb/ovsdbtest"
+)
+
+// testPreflightClient returns a Client for the ovsdb-server served by
+// ovsdbtest whose ovs-ofctl and ovs-appctl are shell scripts. ovs-appctl
+// runs appctl as the body of the script.
+func testPreflightClient(t *testing.T, appctl string) *Client {
+	t.Helper()
+
+	dir := t.TempDir()
+	server, err := ovsdbtest.NewServer(filepath.Join(dir, "db.sock"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	ofctl := filepath.Join(dir, "ovs-ofctl")
+	if err := os.WriteFile(ofctl, []byte("#!/bin/sh\necho 'ovs-ofctl (Open vSwitch) 3.1.0'\n"), 0o755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	appctlPath := filepath.Join(dir, "ovs-appctl")
+	if err := os.WriteFile(appctlPath, []byte("#!/bin/sh\n"+appctl+"\n"), 0o755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	client, err := (&Config{
+		OVSDBEndpoint:       server.Endpoint(),
+		PrivilegeEscalation: privilegeEscalationNone,
+		OVSOfctlPath:        ofctl,
+		OVSAppctlPath:       appctlPath,
+	}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return client
+}
+
+// preflightByName indexes checks by name.
+func preflightByName(checks []PreflightCheck) map[string]PreflightCheck {
+	byName := make(map[string]PreflightCheck, len(checks))
+	for _, check := range checks {
+		byName[check.Name] = check
+	}
+	return byName
+}
+
+func TestPreflight(t *testing.T) {
+	client := testPreflightClient(t, `
+case "$1" in
+version) echo 'ovs-vswitchd (Open vSwitch) 3.1.0' ;;
+*) echo 'ovs-appctl (Open vSwitch) 3.1.0' ;;
+esac`)
+
+	checks := preflightByName(client.preflight(context.Background()))
+	for _, name := range []string{"ovs-ofctl", "ovs-appctl", "ovsdb-server", "ovs-vswitchd"} {
+		check, ok := checks[name]
+		if !ok {
+			t.Errorf("missing check %s", name)
+			continue
+		}
+		if check.Err != nil {
+			t.Errorf("check %s failed: %s", name, check.Err)
+		}
+	}
+	if _, ok := checks["privilege escalation"]; ok {
+		t.Error("expected no privilege escalation check with privilege_escalation = none")
+	}
+	if _, ok := checks["kernel datapath"]; !ok {
+		t.Error("missing check kernel datapath")
+	}
+
+	if got := checks["ovs-vswitchd"].Result; got != "ovs-vswitchd (Open vSwitch) 3.1.0" {
+		t.Errorf("ovs-vswitchd result = %q", got)
+	}
+	if client.ovsVersion != "3.1.0" || client.schemaVersion != "8.3.0" {
+		t.Errorf("recorded versions %q and %q, want 3.1.0 and 8.3.0", client.ovsVersion, client.schemaVersion)
+	}
+}
+
+func TestPreflightFailures(t *testing.T) {
+	client := testPreflightClient(t, `echo 'ovs-appctl: cannot read pidfile "/var/run/openvswitch/ovs-vswitchd.pid" (No such file or directory)' >&2; exit 1`)
+	client.config.OVSDBEndpoint = "unix:" + filepath.Join(t.TempDir(), "missing.sock")
+
+	checks := preflightByName(client.preflight(context.Background()))
+	for _, tt := range []struct{ name, err, hint string }{
+		{"ovsdb-server", "error connecting to ovsdb-server", "set ovsdb_endpoint"},
+		{"ovs-vswitchd", "cannot read pidfile", "Start ovs-vswitchd"},
+	} {
+		check := checks[tt.name]
+		if check.Err == nil || !strings.Contains(check.Err.Error(), tt.err) {
+			t.Errorf("check %s: expected an error containing %q, got %v", tt.name, tt.err, check.Err)
+		}
+		if !strings.Contains(check.Hint, tt.hint) {
+			t.Errorf("check %s: expected a hint containing %q, got %q", tt.name, tt.hint, check.Hint)
+		}
+	}
+}
+
+func TestPreflightNetdevOnly(t *testing.T) {
+	// A host running only netdev bridges, without the kernel module
+	client := testPreflightClient(t, `echo 'ovs-vswitchd (Open vSwitch) 3.1.0'`)
+	defer func(dir string) { kernelModuleDir = dir }(kernelModuleDir)
+	kernelModuleDir = filepath.Join(t.TempDir(), "missing")
+
+	check := preflightByName(client.preflight(context.Background()))["kernel datapath"]
+	if check.Err == nil || !check.Warning {
+		t.Errorf("expected the kernel module check to fail as a warning, got %+v", check)
+	}
+
+	diags := preflightDiagnostics(context.Background(), client)
+	if diags.HasError() {
+		t.Fatalf("expected no errors without the kernel module, got %v", diags)
+	}
+	if len(diags) != 1 || !strings.Contains(diags[0].Detail(), `datapath_type = "netdev"`) {
+		t.Errorf("expected a warning naming the netdev datapath, got %v", diags)
+	}
+}
+
+func TestParseOVSVersion(t *testing.T) {
+	for out, want := range map[string]string{
+		"ovs-vswitchd (Open vSwitch) 3.1.0": "3.1.0",
+		"":                                  "",
+	} {
+		if got := parseOVSVersion(out); got != want {
+			t.Errorf("parseOVSVersion(%q) = %q, want %q", out, got, want)
+		}
+	}
+}
+
+func TestProviderConfigurePreflight(t *testing.T) {
+	endpoint := "unix:" + filepath.Join(t.TempDir(), "missing.sock")
+	configure := func(values map[string]tftypes.Value) []*tfprotov6.Diagnostic {
+		t.Helper()
+
+		ctx := context.Background()
+		server, err := providerserver.NewProtocol6WithError(New("test")())()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		providerType, ok := schemas.Provider.ValueType().(tftypes.Object)
+		if !ok {
+			t.Fatalf("provider schema has type %s, want an object", schemas.Provider.ValueType())
+		}
+		config, err := tfprotov6.NewDynamicValue(providerType, testObject(providerType, values))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		resp, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{Config: &config})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return resp.Diagnostics
+	}
+
+	values := map[string]tftypes.Value{
+		"ovsdb_endpoint":       testString(endpoint),
+		"privilege_escalation": testString(privilegeEscalationNone),
+	}
+	found := false
+	for _, d := range configure(values) {
+		if d.Summary == "Open vSwitch preflight check failed: ovsdb-server" {
+			found = true
+			if !strings.Contains(d.Detail, "set preflight_checks = false") {
+				t.Errorf("expected the detail to say how to skip the checks, got %q", d.Detail)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected configuring the provider to fail the ovsdb-server check")
+	}
+
+	values["preflight_checks"] = testBool(false)
+	if diags := configure(values); testHasError(diags) {
+		t.Errorf("expected no errors with preflight_checks = false, got %v", diagnosticsError(diags))
+	}
+}
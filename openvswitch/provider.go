@@ -1,20 +1,335 @@
 package openvswitch
 
 import (
-	"github.com/hashicorp/terraform/helper/schema"
-	"github.com/hashicorp/terraform/terraform"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/listvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
-// Provider returns a schema.Provider for OpenVSwitch.
-func Provider() terraform.ResourceProvider {
-	return &schema.Provider{
-		Schema: map[string]*schema.Schema{},
+var _ provider.Provider = (*openvswitchProvider)(nil)
+
+// openvswitchProvider is the Open vSwitch provider.
+type openvswitchProvider struct {
+	version string
+
+	// client, when set, is handed to resources in place of a Client built
+	// from the provider configuration.
+	client ovsClient
+}
+
+// New returns a function creating the provider, as expected by
+// providerserver.
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &openvswitchProvider{version: version}
+	}
+}
+
+// providerModel is the provider configuration.
+type providerModel struct {
+	OVSDBEndpoint  types.String `tfsdk:"ovsdb_endpoint"`
+	OVSRunDir      types.String `tfsdk:"ovs_rundir"`
+	CommandTimeout types.Int64  `tfsdk:"command_timeout"`
+	MaxRetries     types.Int64  `tfsdk:"max_retries"`
+	Preflight      types.Bool   `tfsdk:"preflight_checks"`
+	Owner          types.String `tfsdk:"owner"`
 
-		ResourcesMap: map[string]*schema.Resource{
-			"openvswitch_bridge": resourceBridge(),
-			"openvswitch_port":   resourcePort(),
+	SSLPrivateKey  types.String `tfsdk:"ssl_private_key"`
+	SSLCertificate types.String `tfsdk:"ssl_certificate"`
+	SSLCACert      types.String `tfsdk:"ssl_ca_cert"`
+
+	PrivilegeEscalation types.String `tfsdk:"privilege_escalation"`
+	OVSOfctlPath        types.String `tfsdk:"ovs_ofctl_path"`
+	OVSAppctlPath       types.String `tfsdk:"ovs_appctl_path"`
+	IPPath              types.String `tfsdk:"ip_path"`
+
+	SSH []sshModel `tfsdk:"ssh"`
+}
+
+// sshModel is the ssh block of the provider configuration.
+type sshModel struct {
+	Host       types.String `tfsdk:"host"`
+	User       types.String `tfsdk:"user"`
+	PrivateKey types.String `tfsdk:"private_key"`
+	KnownHosts types.String `tfsdk:"known_hosts"`
+}
+
+func (p *openvswitchProvider) Metadata(_ context.Context, _ provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "openvswitch"
+	resp.Version = p.version
+}
+
+func (p *openvswitchProvider) Schema(_ context.Context, _ provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"ovsdb_endpoint": schema.StringAttribute{
+				Optional:    true,
+				Validators:  []validator.String{ovsdbEndpointValidator{}},
+				Description: "OVSDB remote to manage, e.g. unix:/run/openvswitch/db.sock or tcp:127.0.0.1:6640. Defaults to the OVSDB_ENDPOINT environment variable, then db.sock in ovs_rundir",
+			},
+			"ovs_rundir": schema.StringAttribute{
+				Optional:    true,
+				Description: "Directory holding the OVS control and bridge management sockets. Defaults to the OVS_RUNDIR environment variable, then the OVS default run directory",
+			},
+			"command_timeout": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.AtLeast(0)},
+				Description: "Timeout in seconds for every OVS command and OVSDB transaction. 0 (default) uses the OVS default",
+			},
+			"max_retries": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.AtLeast(0)},
+				Description: "How many times an OVSDB transaction that another client raced, or an OVS command that times out, is retried with exponential backoff. Defaults to 5, 0 disables retries",
+			},
+			"preflight_checks": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to check, when the provider is configured, that privilege escalation works, the OVS binaries are installed, ovsdb-server and ovs-vswitchd answer and the kernel module is loaded. Defaults to true",
+			},
+			"owner": schema.StringAttribute{
+				Optional:    true,
+				Description: "Owner recorded in the external_ids of the bridges and ports the provider creates, such as the name of the configuration or workspace. Bridges and ports created under another owner are only deleted with force_destroy",
+			},
+			"ssl_private_key": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the PEM private key used for ssl: endpoints. Defaults to the OVS_SSL_PRIVATE_KEY environment variable",
+			},
+			"ssl_certificate": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the PEM certificate used for ssl: endpoints. Defaults to the OVS_SSL_CERTIFICATE environment variable",
+			},
+			"ssl_ca_cert": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the PEM CA certificate used to verify ssl: endpoints. Defaults to the OVS_SSL_CA_CERT environment variable",
+			},
+			"privilege_escalation": schema.StringAttribute{
+				Optional:    true,
+				Validators:  []validator.String{stringvalidator.OneOf(privilegeEscalationMethods...)},
+				Description: "How commands gain root privileges: none, sudo (default) or doas. Applies to every command the provider runs",
+			},
+			"ovs_ofctl_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the ovs-ofctl binary. Defaults to ovs-ofctl",
+			},
+			"ovs_appctl_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the ovs-appctl binary. Defaults to ovs-appctl",
+			},
+			"ip_path": schema.StringAttribute{
+				Optional:    true,
+				Description: "Path to the iproute2 ip binary. Defaults to ip",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"ssh": schema.ListNestedBlock{
+				Description: "Run every command and OVSDB connection on a remote host over SSH",
+				Validators:  []validator.List{listvalidator.SizeAtMost(1)},
+				NestedObject: schema.NestedBlockObject{
+					Attributes: map[string]schema.Attribute{
+						"host": schema.StringAttribute{
+							Required:    true,
+							Description: "Remote host name or address, optionally with a :port suffix (default port 22)",
+						},
+						"user": schema.StringAttribute{
+							Required:    true,
+							Description: "Login user on the remote host",
+						},
+						"private_key": schema.StringAttribute{
+							Required:    true,
+							Sensitive:   true,
+							Description: "PEM encoded private key used to authenticate",
+						},
+						"known_hosts": schema.StringAttribute{
+							Optional:    true,
+							Description: "known_hosts lines used to verify the host key. Defaults to ~/.ssh/known_hosts",
+						},
+					},
+				},
+			},
 		},
+	}
+}
+
+func (p *openvswitchProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var model providerModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if p.client != nil {
+		resp.ResourceData = p.client
+		resp.DataSourceData = p.client
+		return
+	}
+
+	config, err := model.config()
+	if err != nil {
+		resp.Diagnostics.AddError("Invalid provider configuration", err.Error())
+		return
+	}
+	client, err := config.Client()
+	if err != nil {
+		resp.Diagnostics.AddError("Error configuring the Open vSwitch client", err.Error())
+		return
+	}
 
-		DataSourcesMap: map[string]*schema.Resource{},
+	switch {
+	case model.Preflight.IsUnknown():
+		resp.Diagnostics.AddError("Invalid provider configuration", "preflight_checks must be known when the provider is configured")
+		return
+	case model.Preflight.IsNull() || model.Preflight.ValueBool():
+		resp.Diagnostics.Append(preflightDiagnostics(ctx, client)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
+	resp.ResourceData = client
+	resp.DataSourceData = client
+}
+
+// preflightDiagnostics runs the preflight checks of client and reports each
+// failure as an error, so that a broken environment stops Terraform before
+// any resource is touched. Failed checks marked as warnings are reported as
+// such.
+func preflightDiagnostics(ctx context.Context, client *Client) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for _, check := range client.preflight(ctx) {
+		if check.Err != nil && check.Warning {
+			diags.AddWarning(
+				fmt.Sprintf("Open vSwitch preflight check failed: %s", check.Name),
+				fmt.Sprintf("%s\n\n%s.", check.Err, check.Hint),
+			)
+			continue
+		}
+		if check.Err != nil {
+			diags.AddError(
+				fmt.Sprintf("Open vSwitch preflight check failed: %s", check.Name),
+				fmt.Sprintf("%s\n\n%s. To skip the checks, set preflight_checks = false.", check.Err, check.Hint),
+			)
+			continue
+		}
+		tflog.Debug(ctx, "preflight check passed", map[string]interface{}{
+			"check":  check.Name,
+			"result": check.Result,
+		})
+	}
+	if !diags.HasError() {
+		tflog.Info(ctx, "connected to Open vSwitch", map[string]interface{}{
+			"ovs_version":    client.ovsVersion,
+			"schema_version": client.schemaVersion,
+		})
+	}
+	return diags
+}
+
+func (p *openvswitchProvider) Resources(_ context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newBridgeResource,
+		newPortResource,
+	}
+}
+
+func (p *openvswitchProvider) DataSources(_ context.Context) []func() datasource.DataSource {
+	return []func() datasource.DataSource{
+		newFdbDataSource,
+		newMdbDataSource,
+	}
+}
+
+// config builds the client Config from the provider configuration, applying
+// environment and built-in defaults to unset arguments.
+func (m *providerModel) config() (*Config, error) {
+	config := &Config{
+		CommandTimeout: int(m.CommandTimeout.ValueInt64()),
+	}
+
+	for _, field := range []struct {
+		key   string
+		value types.String
+		env   string
+		def   string
+		dst   *string
+	}{
+		{key: "ovsdb_endpoint", value: m.OVSDBEndpoint, env: "OVSDB_ENDPOINT", dst: &config.OVSDBEndpoint},
+		{key: "ovs_rundir", value: m.OVSRunDir, env: "OVS_RUNDIR", dst: &config.OVSRunDir},
+		{key: "ssl_private_key", value: m.SSLPrivateKey, env: "OVS_SSL_PRIVATE_KEY", dst: &config.SSLPrivateKey},
+		{key: "ssl_certificate", value: m.SSLCertificate, env: "OVS_SSL_CERTIFICATE", dst: &config.SSLCertificate},
+		{key: "ssl_ca_cert", value: m.SSLCACert, env: "OVS_SSL_CA_CERT", dst: &config.SSLCACert},
+		{key: "owner", value: m.Owner, dst: &config.Owner},
+
+		{key: "privilege_escalation", value: m.PrivilegeEscalation, def: privilegeEscalationSudo, dst: &config.PrivilegeEscalation},
+		{key: "ovs_ofctl_path", value: m.OVSOfctlPath, def: "ovs-ofctl", dst: &config.OVSOfctlPath},
+		{key: "ovs_appctl_path", value: m.OVSAppctlPath, def: "ovs-appctl", dst: &config.OVSAppctlPath},
+		{key: "ip_path", value: m.IPPath, def: "ip", dst: &config.IPPath},
+	} {
+		switch {
+		case field.value.IsUnknown():
+			return nil, fmt.Errorf("%s must be known when the provider is configured", field.key)
+		case !field.value.IsNull():
+			*field.dst = field.value.ValueString()
+		case field.env != "":
+			*field.dst = os.Getenv(field.env)
+		default:
+			*field.dst = field.def
+		}
+	}
+	if m.CommandTimeout.IsUnknown() {
+		return nil, fmt.Errorf("command_timeout must be known when the provider is configured")
+	}
+	switch {
+	case m.MaxRetries.IsUnknown():
+		return nil, fmt.Errorf("max_retries must be known when the provider is configured")
+	case m.MaxRetries.IsNull():
+		config.MaxRetries = defaultMaxRetries
+	default:
+		config.MaxRetries = int(m.MaxRetries.ValueInt64())
+	}
+
+	if len(m.SSH) == 1 {
+		ssh := m.SSH[0]
+		config.SSH = &SSHConfig{
+			Host:       ssh.Host.ValueString(),
+			User:       ssh.User.ValueString(),
+			PrivateKey: ssh.PrivateKey.ValueString(),
+			KnownHosts: ssh.KnownHosts.ValueString(),
+		}
+	}
+
+	return config, nil
+}
+
+// ovsdbEndpointValidator checks that ovsdb_endpoint uses a connection method
+// understood by ovsdb-server.
+type ovsdbEndpointValidator struct{}
+
+func (v ovsdbEndpointValidator) Description(_ context.Context) string {
+	return "value must start with unix:, tcp: or ssl:"
+}
+
+func (v ovsdbEndpointValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v ovsdbEndpointValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if value := req.ConfigValue.ValueString(); !validOVSDBEndpoint(value) {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid OVSDB endpoint",
+			fmt.Sprintf("%s %s, got %q", path.Root("ovsdb_endpoint"), v.Description(ctx), value))
 	}
 }
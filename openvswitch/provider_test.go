@@ -1,18 +1,257 @@
 package openvswitch
 
 import (
+	"context"
+	"fmt"
+	"math/big"
 	"testing"
 
-	"github.com/hashicorp/terraform/helper/schema"
-	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 )
 
 func TestProvider(t *testing.T) {
-	if err := Provider().(*schema.Provider).InternalValidate(); err != nil {
+	// The framework validates the provider and resource schemas when
+	// serving them
+	resp, err := testProviderServer(t).GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
+	for _, d := range resp.Diagnostics {
+		t.Errorf("%s: %s: %s", d.Severity, d.Summary, d.Detail)
+	}
+	for _, name := range []string{"openvswitch_bridge", "openvswitch_port"} {
+		if _, ok := resp.ResourceSchemas[name]; !ok {
+			t.Errorf("missing resource %s", name)
+		}
+	}
+	for _, name := range []string{"openvswitch_fdb", "openvswitch_mdb"} {
+		if _, ok := resp.DataSourceSchemas[name]; !ok {
+			t.Errorf("missing data source %s", name)
+		}
+	}
 }
 
 func TestProvider_impl(t *testing.T) {
-	var _ terraform.ResourceProvider = Provider()
+	var _ provider.Provider = New("test")()
+}
+
+// testProviderServer returns the provider served over plugin protocol v6.
+func testProviderServer(t *testing.T) tfprotov6.ProviderServer {
+	t.Helper()
+
+	server, err := providerserver.NewProtocol6WithError(New("test")())()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return server
+}
+
+// testResourceType returns the object type of the resource typeName.
+func testResourceType(t *testing.T, server tfprotov6.ProviderServer, typeName string) tftypes.Object {
+	t.Helper()
+
+	resp, err := server.GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s, ok := resp.ResourceSchemas[typeName]
+	if !ok {
+		t.Fatalf("missing resource %s", typeName)
+	}
+	objectType, ok := s.ValueType().(tftypes.Object)
+	if !ok {
+		t.Fatalf("resource %s has type %s, want an object", typeName, s.ValueType())
+	}
+	return objectType
+}
+
+// testValidateResourceConfig validates a configuration of the resource
+// typeName in which the attributes missing from values are null, and
+// returns the resulting diagnostics.
+func testValidateResourceConfig(t *testing.T, typeName string, values map[string]tftypes.Value) []*tfprotov6.Diagnostic {
+	t.Helper()
+
+	server := testProviderServer(t)
+	objectType := testResourceType(t, server, typeName)
+
+	config, err := tfprotov6.NewDynamicValue(objectType, testObject(objectType, values))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	resp, err := server.ValidateResourceConfig(context.Background(), &tfprotov6.ValidateResourceConfigRequest{
+		TypeName: typeName,
+		Config:   &config,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return resp.Diagnostics
+}
+
+// testReadDataSource reads the data source typeName configured with values,
+// the attributes missing from values being null, and returns its
+// attributes and the resulting diagnostics.
+func testReadDataSource(t *testing.T, server tfprotov6.ProviderServer, typeName string, values map[string]tftypes.Value) (map[string]tftypes.Value, []*tfprotov6.Diagnostic) {
+	t.Helper()
+
+	ctx := context.Background()
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s, ok := schemas.DataSourceSchemas[typeName]
+	if !ok {
+		t.Fatalf("missing data source %s", typeName)
+	}
+	objectType, ok := s.ValueType().(tftypes.Object)
+	if !ok {
+		t.Fatalf("data source %s has type %s, want an object", typeName, s.ValueType())
+	}
+
+	config, err := tfprotov6.NewDynamicValue(objectType, testObject(objectType, values))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp, err := server.ReadDataSource(ctx, &tfprotov6.ReadDataSourceRequest{
+		TypeName: typeName,
+		Config:   &config,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if resp.State == nil {
+		return nil, resp.Diagnostics
+	}
+	state, err := resp.State.Unmarshal(objectType)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var attrs map[string]tftypes.Value
+	if err := state.As(&attrs); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return attrs, resp.Diagnostics
+}
+
+// testObjectList returns the objects of a list of objects, their
+// attributes formatted as strings. Null attributes are left out.
+func testObjectList(t *testing.T, list tftypes.Value) []map[string]string {
+	t.Helper()
+
+	var elems []tftypes.Value
+	if err := list.As(&elems); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	objects := make([]map[string]string, 0, len(elems))
+	for _, elem := range elems {
+		var attrs map[string]tftypes.Value
+		if err := elem.As(&attrs); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		object := make(map[string]string, len(attrs))
+		for name, v := range attrs {
+			if v.IsNull() {
+				continue
+			}
+			var err error
+			switch {
+			case v.Type().Is(tftypes.Number):
+				var n *big.Float
+				err = v.As(&n)
+				object[name] = n.String()
+			case v.Type().Is(tftypes.Bool):
+				var b bool
+				err = v.As(&b)
+				object[name] = fmt.Sprint(b)
+			default:
+				var s string
+				err = v.As(&s)
+				object[name] = s
+			}
+			if err != nil {
+				t.Fatalf("%s: %s", name, err)
+			}
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+// testObject returns an object of objectType holding values, with the
+// attributes missing from values set to null.
+func testObject(objectType tftypes.Object, values map[string]tftypes.Value) tftypes.Value {
+	attrs := make(map[string]tftypes.Value, len(objectType.AttributeTypes))
+	for name, attrType := range objectType.AttributeTypes {
+		if v, ok := values[name]; ok {
+			attrs[name] = v
+		} else {
+			attrs[name] = tftypes.NewValue(attrType, nil)
+		}
+	}
+	return tftypes.NewValue(objectType, attrs)
+}
+
+// testHasError reports whether diags holds an error.
+func testHasError(diags []*tfprotov6.Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// testStringSet returns a set of strings as a configuration value.
+func testStringSet(values ...string) tftypes.Value {
+	elems := make([]tftypes.Value, 0, len(values))
+	for _, v := range values {
+		elems = append(elems, tftypes.NewValue(tftypes.String, v))
+	}
+	return tftypes.NewValue(tftypes.Set{ElementType: tftypes.String}, elems)
+}
+
+// testNumberSet returns a set of numbers as a configuration value.
+func testNumberSet(values ...int) tftypes.Value {
+	elems := make([]tftypes.Value, 0, len(values))
+	for _, v := range values {
+		elems = append(elems, tftypes.NewValue(tftypes.Number, v))
+	}
+	return tftypes.NewValue(tftypes.Set{ElementType: tftypes.Number}, elems)
+}
+
+// testEmptyState returns an empty state for r, as handed to ImportState.
+func testEmptyState(t *testing.T, r resource.Resource) tfsdk.State {
+	t.Helper()
+
+	ctx := context.Background()
+	var resp resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("schema diagnostics: %v", resp.Diagnostics)
+	}
+	return tfsdk.State{
+		Schema: resp.Schema,
+		Raw:    tftypes.NewValue(resp.Schema.Type().TerraformType(ctx), nil),
+	}
+}
+
+// testString returns a string as a configuration value.
+func testString(s string) tftypes.Value {
+	return tftypes.NewValue(tftypes.String, s)
+}
+
+// testBool returns a bool as a configuration value.
+func testBool(b bool) tftypes.Value {
+	return tftypes.NewValue(tftypes.Bool, b)
+}
+
+// testNumber returns a number as a configuration value.
+func testNumber(n int) tftypes.Value {
+	return tftypes.NewValue(tftypes.Number, n)
 }
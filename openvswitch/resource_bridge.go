@@ -1,123 +1,809 @@
 package openvswitch
 
 import (
+	"context"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
-	"github.com/digitalocean/go-openvswitch/ovs"
-	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/setplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
 )
 
-// Resource Definition
-func resourceBridge() *schema.Resource {
-	return &schema.Resource{
-		Create: resourceBridgeCreate,
-		Read:   resourceBridgeRead,
-		Update: resourceBridgeUpdate,
-		Delete: resourceBridgeDelete,
-
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Name of the bridge to create",
-			},
-			"ofversion": {
-				Type:     schema.TypeString,
+// openFlowVersions lists the OpenFlow versions OVS can enable, oldest first.
+var openFlowVersions = []string{
+	"OpenFlow10",
+	"OpenFlow11",
+	"OpenFlow12",
+	"OpenFlow13",
+	"OpenFlow14",
+	"OpenFlow15",
+}
+
+var (
+	datapathIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{16}$`)
+	hwaddrPattern     = regexp.MustCompile(`^[0-9a-fA-F]{2}(:[0-9a-fA-F]{2}){5}$`)
+)
+
+// defaultOFVersion is the OpenFlow version enabled on bridges created
+// without ofversion or protocols.
+const defaultOFVersion = "OpenFlow13"
+
+var (
+	_ resource.ResourceWithConfigure      = (*bridgeResource)(nil)
+	_ resource.ResourceWithImportState    = (*bridgeResource)(nil)
+	_ resource.ResourceWithModifyPlan     = (*bridgeResource)(nil)
+	_ resource.ResourceWithValidateConfig = (*bridgeResource)(nil)
+)
+
+// bridgeResource manages an OVS bridge.
+type bridgeResource struct {
+	client ovsClient
+}
+
+func newBridgeResource() resource.Resource {
+	return &bridgeResource{}
+}
+
+// bridgeModel is the openvswitch_bridge resource data. The ID is the bridge
+// name.
+type bridgeModel struct {
+	ID        types.String `tfsdk:"id"`
+	Name      types.String `tfsdk:"name"`
+	OFVersion types.String `tfsdk:"ofversion"`
+	Protocols types.Set    `tfsdk:"protocols"`
+
+	FailMode         types.String `tfsdk:"fail_mode"`
+	Controllers      types.Set    `tfsdk:"controllers"`
+	ControllerStatus types.Map    `tfsdk:"controller_status"`
+
+	DatapathType        types.String `tfsdk:"datapath_type"`
+	DatapathID          types.String `tfsdk:"datapath_id"`
+	HWAddr              types.String `tfsdk:"hwaddr"`
+	EffectiveDatapathID types.String `tfsdk:"effective_datapath_id"`
+	EffectiveHWAddr     types.String `tfsdk:"effective_hwaddr"`
+
+	STPEnable    types.Bool  `tfsdk:"stp_enable"`
+	RSTPEnable   types.Bool  `tfsdk:"rstp_enable"`
+	STPPriority  types.Int64 `tfsdk:"stp_priority"`
+	STPHelloTime types.Int64 `tfsdk:"stp_hello_time"`
+	STPMaxAge    types.Int64 `tfsdk:"stp_max_age"`
+	RSTPPriority types.Int64 `tfsdk:"rstp_priority"`
+	RSTPMaxAge   types.Int64 `tfsdk:"rstp_max_age"`
+	STPStatus    types.Map   `tfsdk:"stp_status"`
+	RSTPStatus   types.Map   `tfsdk:"rstp_status"`
+
+	MulticastSnooping                 types.Bool  `tfsdk:"mcast_snooping_enable"`
+	MulticastTableSize                types.Int64 `tfsdk:"mcast_snooping_table_size"`
+	MulticastAgingTime                types.Int64 `tfsdk:"mcast_snooping_aging_time"`
+	MulticastDisableFloodUnregistered types.Bool  `tfsdk:"mcast_snooping_disable_flood_unregistered"`
+
+	MACAgingTime types.Int64 `tfsdk:"mac_aging_time"`
+	MACTableSize types.Int64 `tfsdk:"mac_table_size"`
+	FloodVLANs   types.Set   `tfsdk:"flood_vlans"`
+	ForwardBPDU  types.Bool  `tfsdk:"forward_bpdu"`
+
+	ForceDestroy types.Bool `tfsdk:"force_destroy"`
+}
+
+// otherConfig returns the attributes of m kept in the Bridge other_config
+// column.
+func (m *bridgeModel) otherConfig() otherConfigAttrs {
+	return otherConfigAttrs{
+		{name: "datapath_id", key: "datapath-id", str: &m.DatapathID},
+		{name: "hwaddr", key: "hwaddr", str: &m.HWAddr},
+		{name: "stp_priority", key: "stp-priority", num: &m.STPPriority},
+		{name: "stp_hello_time", key: "stp-hello-time", num: &m.STPHelloTime},
+		{name: "stp_max_age", key: "stp-max-age", num: &m.STPMaxAge},
+		{name: "rstp_priority", key: "rstp-priority", num: &m.RSTPPriority},
+		{name: "rstp_max_age", key: "rstp-max-age", num: &m.RSTPMaxAge},
+		{name: "mcast_snooping_table_size", key: "mcast-snooping-table-size", num: &m.MulticastTableSize},
+		{name: "mcast_snooping_aging_time", key: "mcast-snooping-aging-time", num: &m.MulticastAgingTime},
+		{name: "mcast_snooping_disable_flood_unregistered", key: "mcast-snooping-disable-flood-unregistered", flag: &m.MulticastDisableFloodUnregistered},
+		{name: "mac_aging_time", key: "mac-aging-time", num: &m.MACAgingTime},
+		{name: "mac_table_size", key: "mac-table-size", num: &m.MACTableSize},
+		{name: "forward_bpdu", key: "forward-bpdu", flag: &m.ForwardBPDU},
+	}
+}
+
+// controllerStatusModel is the connection state of a controller, as
+// reported by ovs-vswitchd.
+type controllerStatusModel struct {
+	Connected types.Bool   `tfsdk:"connected"`
+	State     types.String `tfsdk:"state"`
+	Role      types.String `tfsdk:"role"`
+}
+
+// controllerStatusType is the object type of controllerStatusModel.
+var controllerStatusType = types.ObjectType{AttrTypes: map[string]attr.Type{
+	"connected": types.BoolType,
+	"state":     types.StringType,
+	"role":      types.StringType,
+}}
+
+func (r *bridgeResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_bridge"
+}
+
+func (r *bridgeResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description:   "Name of the bridge",
+			},
+			"name": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description:   "Name of the bridge to create",
+			},
+			"ofversion": schema.StringAttribute{
 				Optional: true,
-				Default:  "OpenFlow13",
-				ValidateFunc: func(v interface{}, k string) (warnings []string, errors []error) {
-					value, ok := v.(string)
-					if !ok {
-						errors = append(errors, fmt.Errorf("%q must be a string", k))
-						return warnings, errors
-					}
-					validVersions := []string{
-						"OpenFlow10",
-						"OpenFlow11",
-						"OpenFlow12",
-						"OpenFlow13",
-						"OpenFlow14",
-						"OpenFlow15",
-					}
-					for _, version := range validVersions {
-						if value == version {
-							return nil, nil
-						}
-					}
-					errors = append(errors, fmt.Errorf(
-						"%q must be one of: %v", k, validVersions))
-					return warnings, errors
+				Computed: true,
+				Validators: []validator.String{
+					stringvalidator.OneOf(openFlowVersions...),
+					stringvalidator.ConflictsWith(path.MatchRoot("protocols")),
+				},
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description:   "Single OpenFlow protocol version to enable (OpenFlow10, OpenFlow11, OpenFlow12, OpenFlow13, OpenFlow14, or OpenFlow15). Defaults to OpenFlow13 unless protocols is set",
+			},
+			"protocols": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Validators: []validator.Set{
+					setvalidator.ValueStringsAre(stringvalidator.OneOf(openFlowVersions...)),
+					setvalidator.ConflictsWith(path.MatchRoot("ofversion")),
+				},
+				PlanModifiers: []planmodifier.Set{setplanmodifier.UseStateForUnknown()},
+				Description:   "OpenFlow protocol versions to enable on the bridge",
+			},
+			"fail_mode": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(failModes[0]),
+				Validators:  []validator.String{stringvalidator.OneOf(failModes...)},
+				Description: "What the bridge does while no controller is connected: standalone (default) forwards like a learning switch, secure only forwards according to the flows already installed",
+			},
+			"controllers": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Default:     setdefault.StaticValue(types.SetValueMust(types.StringType, nil)),
+				Validators:  []validator.Set{setvalidator.ValueStringsAre(controllerTargetValidator{})},
+				Description: "OpenFlow controllers to connect to, such as tcp:127.0.0.1:6653, or to accept connections from, such as ptcp:6653",
+			},
+			"controller_status": schema.MapNestedAttribute{
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"connected": schema.BoolAttribute{
+							Computed:    true,
+							Description: "Whether the bridge is connected to the controller",
+						},
+						"state": schema.StringAttribute{
+							Computed:    true,
+							Description: "State of the connection: VOID, BACKOFF, CONNECTING, ACTIVE or IDLE. Empty until ovs-vswitchd reports it",
+						},
+						"role": schema.StringAttribute{
+							Computed:    true,
+							Description: "OpenFlow role of the controller: other, master or slave. Empty while not connected",
+						},
+					},
+				},
+				Description: "Connection state of each controller, by target",
+			},
+			"datapath_type": schema.StringAttribute{
+				Optional:      true,
+				Computed:      true,
+				Validators:    []validator.String{stringvalidator.OneOf(datapathTypes...)},
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description:   "Datapath the bridge runs on: system, the kernel module, or netdev, the userspace datapath. Defaults to system",
+			},
+			"datapath_id": schema.StringAttribute{
+				Optional:    true,
+				Validators:  []validator.String{stringvalidator.RegexMatches(datapathIDPattern, "must be 16 hex digits")},
+				Description: "Datapath ID to use, as 16 hex digits, so that controllers recognize the bridge when it is recreated. By default ovs-vswitchd derives it from the bridge's MAC",
+			},
+			"hwaddr": schema.StringAttribute{
+				Optional:    true,
+				Validators:  []validator.String{stringvalidator.RegexMatches(hwaddrPattern, "must be a MAC address such as 02:00:00:00:00:01")},
+				Description: "MAC address of the bridge's local port. By default ovs-vswitchd picks the MAC of one of the bridge's ports",
+			},
+			"effective_datapath_id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description:   "Datapath ID ovs-vswitchd uses for the bridge",
+			},
+			"effective_hwaddr": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description:   "MAC address ovs-vswitchd uses for the bridge's local port",
+			},
+			"stp_enable": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Whether to run the Spanning Tree Protocol (802.1D) on the bridge. Conflicts with rstp_enable",
+			},
+			"rstp_enable": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Whether to run the Rapid Spanning Tree Protocol (802.1D-2004) on the bridge. Conflicts with stp_enable",
+			},
+			"stp_priority": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(0, 65535)},
+				Description: "STP bridge priority, from 0 to 65535. The bridge with the lowest priority becomes the root. Defaults to 32768",
+			},
+			"stp_hello_time": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(1, 10)},
+				Description: "Seconds between the configuration messages STP sends while the bridge is the root, from 1 to 10. Defaults to 2",
+			},
+			"stp_max_age": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(6, 40)},
+				Description: "Seconds STP keeps the information it received before discarding it, from 6 to 40. Defaults to 20",
+			},
+			"rstp_priority": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(0, 61440), multipleOfValidator{step: 4096}},
+				Description: "RSTP bridge priority, a multiple of 4096 from 0 to 61440. The bridge with the lowest priority becomes the root. Defaults to 32768",
+			},
+			"rstp_max_age": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(6, 40)},
+				Description: "Seconds RSTP keeps the information it received before discarding it, from 6 to 40. Defaults to 20",
+			},
+			"stp_status": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "STP status ovs-vswitchd reports for the bridge: stp_bridge_id, stp_designated_root and stp_root_path_cost. The bridge is the root when its ID is the designated root. Empty while STP is disabled",
+			},
+			"rstp_status": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "RSTP status ovs-vswitchd reports for the bridge, such as rstp_bridge_id, rstp_root_id and rstp_root_path_cost. Empty while RSTP is disabled",
+			},
+			"mcast_snooping_enable": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Whether to snoop IGMP and MLD traffic, forwarding multicast only to the ports that joined a group",
+			},
+			"mcast_snooping_table_size": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.AtLeast(1)},
+				Description: "Maximum number of multicast groups the bridge learns. Defaults to 2048",
+			},
+			"mcast_snooping_aging_time": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(15, 3600)},
+				Description: "Seconds a learned group is kept without a report refreshing it, from 15 to 3600. Defaults to 300",
+			},
+			"mcast_snooping_disable_flood_unregistered": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to drop multicast packets to groups no port joined, instead of flooding them. Defaults to false",
+			},
+			"mac_aging_time": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(15, 3600)},
+				Description: "Seconds a learned MAC address is kept without traffic from it, from 15 to 3600. Defaults to 300",
+			},
+			"mac_table_size": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.AtLeast(1)},
+				Description: "Maximum number of MAC addresses the bridge learns. Defaults to 8192",
+			},
+			"flood_vlans": schema.SetAttribute{
+				ElementType: types.Int64Type,
+				Optional:    true,
+				Computed:    true,
+				Default:     setdefault.StaticValue(types.SetValueMust(types.Int64Type, nil)),
+				Validators: []validator.Set{
+					setvalidator.ValueInt64sAre(int64validator.Between(0, 4095)),
 				},
-				Description: "OpenFlow protocol version (OpenFlow10, OpenFlow11, OpenFlow12, OpenFlow13, OpenFlow14, or OpenFlow15)",
+				Description: "VLANs on which the bridge floods every packet instead of learning MAC addresses. Defaults to none",
+			},
+			"forward_bpdu": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to forward the reserved multicast frames of link protocols, such as STP BPDUs and LLDP, that the bridge does not run itself, instead of dropping them. Defaults to false",
+			},
+			"force_destroy": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Whether to delete the bridge, and the ports on it, even if the provider did not create them or created them under another owner. Must be applied before destroying the bridge",
 			},
 		},
 	}
 }
 
-func resourceBridgeCreate(d *schema.ResourceData, m interface{}) error {
-	bridge, ok := d.Get("name").(string)
-	if !ok {
-		return fmt.Errorf("name must be a string")
+func (r *bridgeResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	client, err := clientFromProviderData(req.ProviderData)
+	if err != nil {
+		resp.Diagnostics.AddError("Unexpected provider data", err.Error())
+		return
 	}
+	r.client = client
+}
 
-	ofversion, ok := d.Get("ofversion").(string)
-	if !ok {
-		return fmt.Errorf("ofversion must be a string")
+// ValidateConfig checks that STP and RSTP are not both enabled, since
+// ovs-vswitchd only runs one of them.
+func (r *bridgeResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config bridgeModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if config.STPEnable.ValueBool() && config.RSTPEnable.ValueBool() {
+		resp.Diagnostics.AddAttributeError(path.Root("rstp_enable"), "Conflicting spanning tree protocols",
+			"stp_enable and rstp_enable cannot both be true: a bridge runs either STP or RSTP")
 	}
+}
 
-	ver := []string{ofversion}
-	bridge_options := ovs.BridgeOptions{Protocols: ver}
+// ModifyPlan marks ofversion and protocols, which describe the same column,
+// as unknown when the other one changes, and the effective datapath ID and
+// MAC when the settings they follow from change.
+func (r *bridgeResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.State.Raw.IsNull() || req.Plan.Raw.IsNull() {
+		return
+	}
 
-	if err := c.VSwitch.AddBridge(bridge); err != nil {
-		return err
+	var plan, state bridgeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	if err := c.VSwitch.Set.Bridge(bridge, bridge_options); err != nil {
-		return err
+	switch {
+	case !plan.Protocols.IsUnknown() && !plan.Protocols.Equal(state.Protocols):
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("ofversion"), types.StringUnknown())...)
+	case !plan.OFVersion.IsUnknown() && !plan.OFVersion.Equal(state.OFVersion):
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("protocols"), types.SetUnknown(types.StringType))...)
 	}
 
-	// Set the ID to the bridge name to ensure Terraform can track the resource
-	d.SetId(bridge)
-	return resourceBridgeRead(d, m)
+	if !plan.DatapathType.Equal(state.DatapathType) || !plan.DatapathID.Equal(state.DatapathID) || !plan.HWAddr.Equal(state.HWAddr) {
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("effective_datapath_id"), types.StringUnknown())...)
+		resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("effective_hwaddr"), types.StringUnknown())...)
+	}
 }
 
-func resourceBridgeRead(d *schema.ResourceData, m interface{}) error {
-	bridge := d.Id()
+// bridgeProtocolsToApply returns the protocols to enable on the bridge,
+// giving precedence to whichever of protocols and ofversion changed. state
+// is nil when the bridge is created.
+func bridgeProtocolsToApply(ctx context.Context, plan, state *bridgeModel) ([]string, diag.Diagnostics) {
+	var diags diag.Diagnostics
 
-	// Check if bridge exists by attempting to list its ports
-	// If bridge doesn't exist, this will return an error
-	_, err := c.VSwitch.ListPorts(bridge)
-	if err != nil {
-		// Bridge doesn't exist, remove from state
-		d.SetId("")
+	if !plan.Protocols.IsNull() && !plan.Protocols.IsUnknown() &&
+		(state == nil || !plan.Protocols.Equal(state.Protocols)) {
+		var protocols []string
+		diags.Append(plan.Protocols.ElementsAs(ctx, &protocols, false)...)
+		if len(protocols) > 0 {
+			sort.Strings(protocols)
+			return protocols, diags
+		}
+	}
+
+	ofversion := plan.OFVersion.ValueString()
+	if ofversion == "" {
+		ofversion = defaultOFVersion
+	}
+	return []string{ofversion}, diags
+}
+
+// bridgeSettingsFromModel returns the bridge settings planned in m. An
+// unknown datapath_type, planned when it is not configured on create,
+// leaves the default.
+func bridgeSettingsFromModel(ctx context.Context, m *bridgeModel) (bridgeSettings, diag.Diagnostics) {
+	settings := bridgeSettings{
+		FailMode:          m.FailMode.ValueString(),
+		DatapathType:      m.DatapathType.ValueString(),
+		STPEnable:         m.STPEnable.ValueBool(),
+		RSTPEnable:        m.RSTPEnable.ValueBool(),
+		MulticastSnooping: m.MulticastSnooping.ValueBool(),
+		OtherConfig:       m.otherConfig().config(),
+	}
+	diags := m.Controllers.ElementsAs(ctx, &settings.Controllers, false)
+	diags.Append(m.FloodVLANs.ElementsAs(ctx, &settings.FloodVLANs, false)...)
+	return settings, diags
+}
+
+// bridgeSettingsChanged reports whether plan changes the settings of state.
+func bridgeSettingsChanged(plan, state *bridgeModel) bool {
+	return !plan.FailMode.Equal(state.FailMode) ||
+		!plan.Controllers.Equal(state.Controllers) ||
+		!plan.DatapathType.Equal(state.DatapathType) ||
+		!plan.STPEnable.Equal(state.STPEnable) ||
+		!plan.RSTPEnable.Equal(state.RSTPEnable) ||
+		!plan.MulticastSnooping.Equal(state.MulticastSnooping) ||
+		!plan.FloodVLANs.Equal(state.FloodVLANs) ||
+		plan.otherConfig().changed(state.otherConfig())
+}
+
+func (r *bridgeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan bridgeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	protocols, diags := bridgeProtocolsToApply(ctx, &plan, nil)
+	resp.Diagnostics.Append(diags...)
+	settings, diags := bridgeSettingsFromModel(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	bridge := plan.Name.ValueString()
+
+	opCtx, cancel := r.client.context()
+	defer cancel()
+
+	if err := r.client.addBridge(opCtx, bridge, protocols, settings); err != nil {
+		resp.Diagnostics.AddError("Error creating bridge", err.Error())
+		return
+	}
+
+	// The bridge is deleted again if it cannot be read back, so that it
+	// is not left behind outside the state
+	rb := newRollback("bridge " + bridge)
+	rb.add("deleted bridge "+bridge, "Bridge "+bridge, func() error {
+		ctx, cancel := r.client.context()
+		defer cancel()
+		if err := r.client.deleteBridge(ctx, bridge); err != nil && !isNotFound(err) {
+			return err
+		}
 		return nil
+	})
+
+	plan.ID = types.StringValue(bridge)
+	found, diags := r.read(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if !found && !resp.Diagnostics.HasError() {
+		resp.Diagnostics.AddError("Error creating bridge", fmt.Sprintf("bridge %s disappeared after it was created", bridge))
 	}
+	if resp.Diagnostics.HasError() {
+		rb.run(&resp.Diagnostics)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
 
-	// Bridge exists, set attributes
-	if err := d.Set("name", bridge); err != nil {
-		return fmt.Errorf("error setting name: %w", err)
+func (r *bridgeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state bridgeModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	// Keep the ofversion attribute in the state if it's already there
-	// This ensures we don't lose attributes after apply
-	if ofversion, ok := d.GetOk("ofversion"); ok {
-		if err := d.Set("ofversion", ofversion); err != nil {
-			return fmt.Errorf("error setting ofversion: %w", err)
+	found, diags := r.read(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !found {
+		// Bridge doesn't exist, remove from state
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// read refreshes m from the bridge named by its ID. It reports false when
+// the bridge does not exist.
+func (r *bridgeResource) read(ctx context.Context, m *bridgeModel) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	bridge := m.ID.ValueString()
+
+	opCtx, cancel := r.client.context()
+	defer cancel()
+
+	row, err := r.client.getBridge(opCtx, bridge)
+	if err != nil {
+		if isNotFound(err) {
+			return false, diags
 		}
+		diags.AddError("Error reading bridge", err.Error())
+		return false, diags
 	}
 
-	return nil
+	m.Name = types.StringValue(bridge)
+	if m.ForceDestroy.IsNull() {
+		// Imported, or created before force_destroy was added
+		m.ForceDestroy = types.BoolValue(false)
+	}
+	m.OFVersion = types.StringValue(bridgeProtocols(row))
+	protocols, d := types.SetValueFrom(ctx, types.StringType, ovsdb.Strings(row["protocols"]))
+	diags.Append(d...)
+	m.Protocols = protocols
+	m.FailMode = types.StringValue(bridgeFailMode(row))
+	readDatapath(m, row)
+	m.otherConfig().read(ovsdb.StringMap(row["other_config"]))
+	stpEnable, _ := row["stp_enable"].(bool)
+	rstpEnable, _ := row["rstp_enable"].(bool)
+	m.STPEnable = types.BoolValue(stpEnable)
+	m.RSTPEnable = types.BoolValue(rstpEnable)
+	mcastSnooping, _ := row["mcast_snooping_enable"].(bool)
+	m.MulticastSnooping = types.BoolValue(mcastSnooping)
+	m.FloodVLANs, d = types.SetValueFrom(ctx, types.Int64Type, bridgeFloodVLANs(row))
+	diags.Append(d...)
+	m.STPStatus, m.RSTPStatus, d = readSpanningTree(ctx, row)
+	diags.Append(d...)
+
+	controllers, err := r.client.getControllers(opCtx, bridge)
+	if err != nil {
+		diags.AddError("Error reading bridge controllers", err.Error())
+		return false, diags
+	}
+	diags.Append(readControllers(ctx, m, controllers)...)
+
+	local, err := r.client.getInterface(opCtx, bridge)
+	if err != nil && !isNotFound(err) {
+		diags.AddError("Error reading bridge", err.Error())
+		return false, diags
+	}
+	m.EffectiveHWAddr = types.StringValue(optionalString(local["mac_in_use"]))
+	return true, diags
 }
 
-func resourceBridgeUpdate(d *schema.ResourceData, m interface{}) error {
-	return resourceBridgeRead(d, m)
+// readDatapath sets the datapath type of m, and the datapath ID in use,
+// from a Bridge row.
+func readDatapath(m *bridgeModel, row ovsdb.Row) {
+	m.DatapathType = types.StringValue(bridgeDatapathType(row))
+	m.EffectiveDatapathID = types.StringValue(optionalString(row["datapath_id"]))
 }
 
-func resourceBridgeDelete(d *schema.ResourceData, m interface{}) error {
-	bridge, ok := d.Get("name").(string)
-	if !ok {
-		return fmt.Errorf("name must be a string")
+// readSpanningTree returns the STP status of a Bridge or Port row, the
+// pairs of its status column whose keys start with stp_, and its RSTP
+// status.
+func readSpanningTree(ctx context.Context, row ovsdb.Row) (types.Map, types.Map, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	stp, rstp := make(map[string]string), make(map[string]string)
+	for key, value := range ovsdb.StringMap(row["status"]) {
+		if strings.HasPrefix(key, "stp_") {
+			stp[key] = value
+		}
+	}
+	for key, value := range ovsdb.StringMap(row["rstp_status"]) {
+		rstp[key] = value
+	}
+	stpStatus, d := types.MapValueFrom(ctx, types.StringType, stp)
+	diags.Append(d...)
+	rstpStatus, d := types.MapValueFrom(ctx, types.StringType, rstp)
+	diags.Append(d...)
+	return stpStatus, rstpStatus, diags
+}
+
+// readControllers sets the controllers of m and their status from the
+// bridge's Controller rows.
+func readControllers(ctx context.Context, m *bridgeModel, rows []ovsdb.Row) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	status := make(map[string]controllerStatusModel, len(rows))
+	for _, row := range rows {
+		target, _ := row["target"].(string)
+		connected, _ := row["is_connected"].(bool)
+		role := ovsdb.Strings(row["role"])
+		s := controllerStatusModel{
+			Connected: types.BoolValue(connected),
+			State:     types.StringValue(ovsdb.StringMap(row["status"])["state"]),
+			Role:      types.StringValue(""),
+		}
+		if len(role) == 1 {
+			s.Role = types.StringValue(role[0])
+		}
+		status[target] = s
+	}
+
+	controllers, d := types.SetValueFrom(ctx, types.StringType, controllerTargets(rows))
+	diags.Append(d...)
+	m.Controllers = controllers
+	controllerStatus, d := types.MapValueFrom(ctx, controllerStatusType, status)
+	diags.Append(d...)
+	m.ControllerStatus = controllerStatus
+	return diags
+}
+
+func (r *bridgeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state bridgeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !plan.OFVersion.Equal(state.OFVersion) || !plan.Protocols.Equal(state.Protocols) {
+		protocols, diags := bridgeProtocolsToApply(ctx, &plan, &state)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		opCtx, cancel := r.client.context()
+		defer cancel()
+
+		if err := r.client.setBridgeProtocols(opCtx, state.ID.ValueString(), protocols); err != nil {
+			resp.Diagnostics.AddError("Error updating bridge protocols", err.Error())
+			return
+		}
+	}
+
+	if bridgeSettingsChanged(&plan, &state) {
+		settings, diags := bridgeSettingsFromModel(ctx, &plan)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		opCtx, cancel := r.client.context()
+		defer cancel()
+
+		if err := r.client.setBridgeSettings(opCtx, state.ID.ValueString(), settings); err != nil {
+			resp.Diagnostics.AddError("Error updating bridge", err.Error())
+			return
+		}
+	}
+
+	plan.ID = state.ID
+	found, diags := r.read(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if !found {
+		resp.Diagnostics.AddError("Error updating bridge", fmt.Sprintf("bridge %s no longer exists", state.ID.ValueString()))
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *bridgeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state bridgeModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	bridge := state.Name.ValueString()
+
+	opCtx, cancel := r.client.context()
+	defer cancel()
+
+	if !state.ForceDestroy.ValueBool() {
+		refusal, err := bridgeDeleteRefusal(opCtx, r.client, bridge)
+		if err != nil {
+			resp.Diagnostics.AddError("Error deleting bridge", err.Error())
+			return
+		}
+		if refusal != "" {
+			resp.Diagnostics.AddError("Refusing to delete bridge "+bridge, refusal)
+			return
+		}
+	}
+
+	if err := r.client.deleteBridge(opCtx, bridge); err != nil && !isNotFound(err) {
+		resp.Diagnostics.AddError("Error deleting bridge", err.Error())
+	}
+}
+
+// ImportState imports the bridge named by the ID. Its attributes are filled
+// in by the Read that follows.
+func (r *bridgeResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	opCtx, cancel := r.client.context()
+	defer cancel()
+
+	if _, err := r.client.getBridge(opCtx, req.ID); err != nil {
+		resp.Diagnostics.AddError("Error importing bridge", err.Error())
+		return
+	}
+
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), req.ID)...)
+}
+
+// bridgeProtocols returns the ofversion value matching the protocols column
+// of a Bridge row: the enabled version if there is exactly one, and
+// otherwise the enabled versions joined by commas, so that a bridge with
+// anything but the single configured version shows up as drift.
+func bridgeProtocols(row ovsdb.Row) string {
+	protocols := ovsdb.Strings(row["protocols"])
+	sort.Strings(protocols)
+	return strings.Join(protocols, ",")
+}
+
+// bridgeOFVersion returns the newest OpenFlow version enabled on a Bridge
+// row. An empty protocols column enables the OVS defaults, which always
+// include OpenFlow10.
+func bridgeOFVersion(row ovsdb.Row) string {
+	enabled := make(map[string]bool)
+	for _, p := range ovsdb.Strings(row["protocols"]) {
+		enabled[p] = true
+	}
+	for i := len(openFlowVersions) - 1; i >= 0; i-- {
+		if enabled[openFlowVersions[i]] {
+			return openFlowVersions[i]
+		}
+	}
+	return openFlowVersions[0]
+}
+
+// controllerTargetValidator checks that the elements of controllers use a
+// connection method understood by ovs-vswitchd.
+type controllerTargetValidator struct{}
+
+func (v controllerTargetValidator) Description(_ context.Context) string {
+	return "value must start with tcp:, ssl:, unix:, ptcp:, pssl: or punix:"
+}
+
+func (v controllerTargetValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v controllerTargetValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if value := req.ConfigValue.ValueString(); !validControllerTarget(value) {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid controller target",
+			fmt.Sprintf("controller target %s, got %q", v.Description(ctx), value))
+	}
+}
+
+// validControllerTarget reports whether target is an active controller
+// connection, which needs an address, or a passive one, whose port and
+// address may be left to the defaults.
+func validControllerTarget(target string) bool {
+	for _, prefix := range []string{"tcp:", "ssl:", "unix:", "punix:"} {
+		if strings.HasPrefix(target, prefix) && len(target) > len(prefix) {
+			return true
+		}
+	}
+	return strings.HasPrefix(target, "ptcp:") || strings.HasPrefix(target, "pssl:")
+}
+
+// multipleOfValidator checks that a number is a multiple of step, as the
+// RSTP bridge and port priorities must be.
+type multipleOfValidator struct {
+	step int64
+}
+
+func (v multipleOfValidator) Description(_ context.Context) string {
+	return fmt.Sprintf("value must be a multiple of %d", v.step)
+}
+
+func (v multipleOfValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v multipleOfValidator) ValidateInt64(ctx context.Context, req validator.Int64Request, resp *validator.Int64Response) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if value := req.ConfigValue.ValueInt64(); value%v.step != 0 {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid priority",
+			fmt.Sprintf("%s, got %d", v.Description(ctx), value))
 	}
-	return c.VSwitch.DeleteBridge(bridge)
 }
@@ -0,0 +1,191 @@
+package openvswitch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+func TestBridgeProtocols(t *testing.T) {
+	tests := []struct {
+		name      string
+		protocols interface{}
+		want      string
+	}{
+		{name: "single atom", protocols: "OpenFlow13", want: "OpenFlow13"},
+		{name: "set", protocols: ovsdb.Set{"OpenFlow13", "OpenFlow10"}, want: "OpenFlow10,OpenFlow13"},
+		{name: "empty", protocols: ovsdb.Set{}, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bridgeProtocols(ovsdb.Row{"protocols": tt.protocols}); got != tt.want {
+				t.Errorf("bridgeProtocols = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceBridgeReadDetectsProtocolDrift(t *testing.T) {
+	client := newScriptedClient(t, emptyResults)
+	client.snap.update(ovsdb.TableUpdates{
+		"Bridge": {"b": {New: ovsdb.Row{"name": "br0", "protocols": "OpenFlow10"}}},
+	})
+
+	r := &bridgeResource{client: client}
+	m := bridgeModel{
+		ID:        types.StringValue("br0"),
+		OFVersion: types.StringValue("OpenFlow13"),
+	}
+
+	found, diags := r.read(context.Background(), &m)
+	if diags.HasError() {
+		t.Fatalf("diagnostics: %v", diags)
+	}
+	if !found {
+		t.Fatal("bridge br0 not found")
+	}
+	if got := m.OFVersion.ValueString(); got != "OpenFlow10" {
+		t.Errorf("ofversion = %v, want the OpenFlow10 set outside Terraform", got)
+	}
+}
+
+func TestResourceBridgeReadNotFound(t *testing.T) {
+	client := newScriptedClient(t, emptyResults)
+
+	r := &bridgeResource{client: client}
+	m := bridgeModel{ID: types.StringValue("br0")}
+
+	found, diags := r.read(context.Background(), &m)
+	if diags.HasError() {
+		t.Fatalf("diagnostics: %v", diags)
+	}
+	if found {
+		t.Error("expected a missing bridge to be reported as not found")
+	}
+}
+
+func TestBridgeProtocolsToApply(t *testing.T) {
+	ctx := context.Background()
+	set := func(values ...string) types.Set {
+		s, diags := types.SetValueFrom(ctx, types.StringType, values)
+		if diags.HasError() {
+			t.Fatalf("diagnostics: %v", diags)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		plan  bridgeModel
+		state *bridgeModel
+		want  []string
+	}{
+		{
+			name: "default",
+			plan: bridgeModel{OFVersion: types.StringUnknown(), Protocols: types.SetUnknown(types.StringType)},
+			want: []string{"OpenFlow13"},
+		},
+		{
+			name: "ofversion",
+			plan: bridgeModel{OFVersion: types.StringValue("OpenFlow10"), Protocols: types.SetUnknown(types.StringType)},
+			want: []string{"OpenFlow10"},
+		},
+		{
+			name: "protocols",
+			plan: bridgeModel{OFVersion: types.StringUnknown(), Protocols: set("OpenFlow15", "OpenFlow13")},
+			want: []string{"OpenFlow13", "OpenFlow15"},
+		},
+		{
+			name:  "ofversion changed",
+			plan:  bridgeModel{OFVersion: types.StringValue("OpenFlow14"), Protocols: set("OpenFlow13")},
+			state: &bridgeModel{OFVersion: types.StringValue("OpenFlow13"), Protocols: set("OpenFlow13")},
+			want:  []string{"OpenFlow14"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, diags := bridgeProtocolsToApply(ctx, &tt.plan, tt.state)
+			if diags.HasError() {
+				t.Fatalf("diagnostics: %v", diags)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("protocols = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceBridgeOFVersionConflictsWithProtocols(t *testing.T) {
+	diags := testValidateResourceConfig(t, "openvswitch_bridge", map[string]tftypes.Value{
+		"name":      tftypes.NewValue(tftypes.String, "br0"),
+		"ofversion": tftypes.NewValue(tftypes.String, "OpenFlow13"),
+		"protocols": testStringSet("OpenFlow13"),
+	})
+	if !testHasError(diags) {
+		t.Error("expected ofversion and protocols to conflict")
+	}
+}
+
+func TestResourceBridgeValidatesProtocols(t *testing.T) {
+	diags := testValidateResourceConfig(t, "openvswitch_bridge", map[string]tftypes.Value{
+		"name":      tftypes.NewValue(tftypes.String, "br0"),
+		"protocols": testStringSet("OpenFlow13", "OpenFlow16"),
+	})
+	if !testHasError(diags) {
+		t.Error("expected an error for protocol OpenFlow16")
+	}
+}
+
+func TestResourceBridgeValidatesSettings(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config map[string]tftypes.Value
+	}{
+		{name: "target", config: map[string]tftypes.Value{"controllers": testStringSet("tcp:127.0.0.1:6653", "127.0.0.1:6653")}},
+		{name: "fail_mode", config: map[string]tftypes.Value{"fail_mode": testString("closed")}},
+		{name: "datapath_type", config: map[string]tftypes.Value{"datapath_type": testString("dummy")}},
+		{name: "datapath_id", config: map[string]tftypes.Value{"datapath_id": testString("0xa1")}},
+		{name: "hwaddr", config: map[string]tftypes.Value{"hwaddr": testString("02-00-00-00-00-a1")}},
+		{name: "stp_priority", config: map[string]tftypes.Value{"stp_priority": testNumber(65536)}},
+		{name: "rstp_priority", config: map[string]tftypes.Value{"rstp_priority": testNumber(5000)}},
+		{name: "stp_hello_time", config: map[string]tftypes.Value{"stp_hello_time": testNumber(0)}},
+		{name: "mcast_snooping_table_size", config: map[string]tftypes.Value{"mcast_snooping_table_size": testNumber(0)}},
+		{name: "mcast_snooping_aging_time", config: map[string]tftypes.Value{"mcast_snooping_aging_time": testNumber(5)}},
+		{name: "mac_aging_time", config: map[string]tftypes.Value{"mac_aging_time": testNumber(3601)}},
+		{name: "mac_table_size", config: map[string]tftypes.Value{"mac_table_size": testNumber(0)}},
+		{name: "flood_vlans", config: map[string]tftypes.Value{"flood_vlans": testNumberSet(10, 4096)}},
+		{name: "both protocols", config: map[string]tftypes.Value{"stp_enable": testBool(true), "rstp_enable": testBool(true)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config["name"] = testString("br0")
+			if diags := testValidateResourceConfig(t, "openvswitch_bridge", tt.config); !testHasError(diags) {
+				t.Errorf("expected an error for %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestValidControllerTarget(t *testing.T) {
+	for target, valid := range map[string]bool{
+		"tcp:127.0.0.1:6653":               true,
+		"ssl:[2001:db8::1]:6653":           true,
+		"unix:/run/openvswitch/ctl.sock":   true,
+		"ptcp:":                            true,
+		"pssl:6653:127.0.0.1":              true,
+		"punix:/run/openvswitch/ctl.sock":  true,
+		"tcp:":                             false,
+		"punix:":                           false,
+		"127.0.0.1:6653":                   false,
+		"http://controller.example.com:80": false,
+	} {
+		if got := validControllerTarget(target); got != valid {
+			t.Errorf("validControllerTarget(%q) = %v, want %v", target, got, valid)
+		}
+	}
+}
@@ -2,74 +2,163 @@ package openvswitch
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
 	"testing"
 
-	"github.com/hashicorp/terraform/helper/resource"
-	"github.com/hashicorp/terraform/helper/schema"
-	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
-// Skip tests if ovs-vsctl is not available
-func skipIfOvsNotInstalled(t *testing.T) {
-	_, err := exec.LookPath("ovs-vsctl")
-	if err != nil {
-		t.Skip("ovs-vsctl not found, skipping test")
-	}
-}
-
-// Check if sudo is available to run the tests
-func skipIfNoSudo(t *testing.T) {
-	if os.Getuid() != 0 {
-		_, err := exec.LookPath("sudo")
-		if err != nil {
-			t.Skip("sudo not found and not running as root, skipping test")
-		}
-	}
-}
-
 func TestAccBridge_basic(t *testing.T) {
-	skipIfOvsNotInstalled(t)
-	skipIfNoSudo(t)
+	client := testAccSandbox(t)
+	bridgeName := testAccName("br")
 
-	var bridgeName = "testbridge"
-
-	resource.Test(t, resource.TestCase{
-		PreCheck:     func() { testAccPreCheck(t) },
-		Providers:    testAccProviders,
-		CheckDestroy: testAccCheckBridgeDestroy,
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccSandboxProviderFactories(client),
+		CheckDestroy:             testAccCheckBridgeDestroy(client),
 		Steps: []resource.TestStep{
 			{
 				Config: testAccBridgeConfig(bridgeName),
 				Check: resource.ComposeTestCheckFunc(
-					testAccCheckBridgeExists("openvswitch_bridge.test"),
+					testAccCheckBridgeExists(client, "openvswitch_bridge.test"),
 					resource.TestCheckResourceAttr("openvswitch_bridge.test", "name", bridgeName),
 					resource.TestCheckResourceAttr("openvswitch_bridge.test", "ofversion", "OpenFlow13"),
 				),
 			},
+			{
+				ResourceName:      "openvswitch_bridge.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccBridge_updateOFVersion(t *testing.T) {
+	client := testAccSandbox(t)
+	bridgeName := testAccName("br")
+
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccSandboxProviderFactories(client),
+		CheckDestroy:             testAccCheckBridgeDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccBridgeConfig(bridgeName),
+				Check:  testAccCheckBridgeProtocols(client, bridgeName, "OpenFlow13"),
+			},
+			{
+				Config: testAccBridgeConfigOFVersion(bridgeName, "OpenFlow10"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("openvswitch_bridge.test", "ofversion", "OpenFlow10"),
+					testAccCheckBridgeProtocols(client, bridgeName, "OpenFlow10"),
+				),
+			},
+			{
+				// A change made outside Terraform is detected and reverted
+				PreConfig: func() {
+					ctx, cancel := client.context()
+					defer cancel()
+					if err := client.setBridgeProtocols(ctx, bridgeName, []string{"OpenFlow14"}); err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				},
+				Config: testAccBridgeConfigOFVersion(bridgeName, "OpenFlow10"),
+				Check:  testAccCheckBridgeProtocols(client, bridgeName, "OpenFlow10"),
+			},
 		},
 	})
 }
 
-func testAccCheckBridgeDestroy(s *terraform.State) error {
-	for _, rs := range s.RootModule().Resources {
-		if rs.Type != "openvswitch_bridge" {
-			continue
+// TestAccBridge_ovsdbServer runs Terraform against the in-process
+// ovsdb-server, so it needs neither root nor an installed Open vSwitch.
+func TestAccBridge_ovsdbServer(t *testing.T) {
+	client := newServedClient(t)
+	config := func(ofversion string) string {
+		return fmt.Sprintf(`
+provider "openvswitch" {
+  ovsdb_endpoint = "%s"
+}
+%s`, client.config.OVSDBEndpoint, testAccBridgeConfigOFVersion("testbridge", ofversion))
+	}
+	checkProtocols := func(want string) resource.TestCheckFunc {
+		return func(*terraform.State) error {
+			ctx, cancel := client.context()
+			defer cancel()
+			row, err := client.getBridge(ctx, "testbridge")
+			if err != nil {
+				return err
+			}
+			if got := bridgeProtocols(row); got != want {
+				return fmt.Errorf("Bridge testbridge protocols = %s, want %s", got, want)
+			}
+			return nil
 		}
+	}
 
-		bridgeName := rs.Primary.Attributes["name"]
-		cmd := exec.Command("ovs-vsctl", "br-exists", bridgeName)
-		err := cmd.Run()
-		if err == nil {
-			return fmt.Errorf("Bridge %s still exists", bridgeName)
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			ctx, cancel := client.context()
+			defer cancel()
+			if _, err := client.getBridge(ctx, "testbridge"); !isNotFound(err) {
+				return fmt.Errorf("Bridge testbridge still exists")
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: config("OpenFlow13"),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("openvswitch_bridge.test", "id", "testbridge"),
+					checkProtocols("OpenFlow13"),
+				),
+			},
+			{
+				Config: config("OpenFlow10"),
+				Check:  checkProtocols("OpenFlow10"),
+			},
+		},
+	})
+}
+
+func testAccCheckBridgeProtocols(client *Client, bridgeName, want string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		ctx, cancel := client.context()
+		defer cancel()
+		row, err := client.getBridge(ctx, bridgeName)
+		if err != nil {
+			return fmt.Errorf("Error reading protocols of bridge %s: %w", bridgeName, err)
 		}
+		if got := bridgeProtocols(row); got != want {
+			return fmt.Errorf("Bridge %s protocols = %s, want %s", bridgeName, got, want)
+		}
+		return nil
 	}
+}
+
+func testAccCheckBridgeDestroy(client *Client) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		ctx, cancel := client.context()
+		defer cancel()
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "openvswitch_bridge" {
+				continue
+			}
+
+			bridgeName := rs.Primary.Attributes["name"]
+			if _, err := client.getBridge(ctx, bridgeName); !isNotFound(err) {
+				return fmt.Errorf("Bridge %s still exists", bridgeName)
+			}
+		}
 
-	return nil
+		return nil
+	}
 }
 
-func testAccCheckBridgeExists(n string) resource.TestCheckFunc {
+func testAccCheckBridgeExists(client *Client, n string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
 		if !ok {
@@ -80,10 +169,10 @@ func testAccCheckBridgeExists(n string) resource.TestCheckFunc {
 			return fmt.Errorf("No ID is set")
 		}
 
+		ctx, cancel := client.context()
+		defer cancel()
 		bridgeName := rs.Primary.Attributes["name"]
-		cmd := exec.Command("ovs-vsctl", "br-exists", bridgeName)
-		err := cmd.Run()
-		if err != nil {
+		if _, err := client.getBridge(ctx, bridgeName); err != nil {
 			return fmt.Errorf("Error checking bridge %s: %w", bridgeName, err)
 		}
 
@@ -100,16 +189,21 @@ resource "openvswitch_bridge" "test" {
 `, bridgeName)
 }
 
+func testAccBridgeConfigOFVersion(bridgeName, ofversion string) string {
+	return fmt.Sprintf(`
+resource "openvswitch_bridge" "test" {
+  name = "%s"
+  ofversion = "%s"
+}
+`, bridgeName, ofversion)
+}
+
 func testAccPreCheck(t *testing.T) {
 	// Could add environment checks here if needed
 }
 
-var testAccProviders map[string]terraform.ResourceProvider
-var testAccProvider *schema.Provider
-
-func init() {
-	testAccProvider = Provider().(*schema.Provider)
-	testAccProviders = map[string]terraform.ResourceProvider{
-		"openvswitch": testAccProvider,
-	}
+// testAccProtoV6ProviderFactories serves the provider to Terraform or
+// OpenTofu over plugin protocol v6.
+var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServer, error){
+	"openvswitch": providerserver.NewProtocol6WithError(New("test")()),
 }
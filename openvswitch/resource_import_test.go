@@ -0,0 +1,137 @@
+package openvswitch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+func TestBridgeOFVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		protocols interface{}
+		want      string
+	}{
+		{name: "single", protocols: "OpenFlow13", want: "OpenFlow13"},
+		{name: "newest wins", protocols: ovsdb.Set{"OpenFlow10", "OpenFlow14", "OpenFlow13"}, want: "OpenFlow14"},
+		{name: "empty", protocols: ovsdb.Set{}, want: "OpenFlow10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bridgeOFVersion(ovsdb.Row{"protocols": tt.protocols}); got != tt.want {
+				t.Errorf("bridgeOFVersion = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// bridgeWithPort is a snapshot holding bridge br0 with port tfimport0.
+var bridgeWithPort = ovsdb.TableUpdates{
+	"Bridge": {
+		"b": {New: ovsdb.Row{
+			"name":      "br0",
+			"protocols": ovsdb.Set{"OpenFlow13", "OpenFlow15"},
+			"ports":     ovsdb.Set{ovsdb.UUID("1"), ovsdb.UUID("2")},
+		}},
+	},
+	"Port": {
+		"1": {New: ovsdb.Row{"name": "br0"}},
+		"2": {New: ovsdb.Row{"name": "tfimport0"}},
+	},
+}
+
+func TestResourceBridgeImport(t *testing.T) {
+	ctx := context.Background()
+	client := newScriptedClient(t, emptyResults)
+	client.snap.update(bridgeWithPort)
+	r := &bridgeResource{client: client}
+
+	resp := resource.ImportStateResponse{State: testEmptyState(t, r)}
+	r.ImportState(ctx, resource.ImportStateRequest{ID: "br0"}, &resp)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("diagnostics: %v", resp.Diagnostics)
+	}
+
+	// The framework reads the imported resource back
+	var m bridgeModel
+	if diags := resp.State.Get(ctx, &m); diags.HasError() {
+		t.Fatalf("diagnostics: %v", diags)
+	}
+	if found, diags := r.read(ctx, &m); diags.HasError() || !found {
+		t.Fatalf("read found = %v, diagnostics: %v", found, diags)
+	}
+	if got := m.Name.ValueString(); got != "br0" {
+		t.Errorf("name = %v, want br0", got)
+	}
+	if got := m.OFVersion.ValueString(); got != "OpenFlow13,OpenFlow15" {
+		t.Errorf("ofversion = %v, want OpenFlow13,OpenFlow15", got)
+	}
+}
+
+func TestResourceBridgeImportNotFound(t *testing.T) {
+	client := newScriptedClient(t, emptyResults)
+	r := &bridgeResource{client: client}
+
+	resp := resource.ImportStateResponse{State: testEmptyState(t, r)}
+	r.ImportState(context.Background(), resource.ImportStateRequest{ID: "missing"}, &resp)
+	if !resp.Diagnostics.HasError() {
+		t.Error("expected an error importing a missing bridge")
+	}
+}
+
+func TestImportPort(t *testing.T) {
+	client := newScriptedClient(t, emptyResults)
+	client.snap.update(bridgeWithPort)
+
+	m, err := importPort(client, "br0:tfimport0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// No network device called tfimport0 exists, so the tap settings are
+	// the defaults of a device the provider does not own.
+	want := portModel{
+		ID:         types.StringValue("br0:tfimport0"),
+		Name:       types.StringValue("tfimport0"),
+		BridgeID:   types.StringValue("br0"),
+		Action:     types.StringValue("up"),
+		OFVersion:  types.StringValue("OpenFlow15"),
+		PortConfig: types.SetNull(types.StringType),
+		PortState:  types.SetNull(types.StringType),
+		TapOwner:   types.StringValue(""),
+		TapGroup:   types.StringValue(""),
+		MultiQueue: types.BoolValue(false),
+		VnetHdr:    types.BoolValue(false),
+		Persist:    types.BoolValue(true),
+		TapCreated: types.BoolValue(false),
+
+		ForceDestroy: types.BoolValue(false),
+		STPStatus:    types.MapNull(types.StringType),
+		RSTPStatus:   types.MapNull(types.StringType),
+		Timeouts:     nullTimeouts(),
+	}
+	if !reflect.DeepEqual(*m, want) {
+		t.Errorf("imported = %+v, want %+v", *m, want)
+	}
+}
+
+func TestImportPortErrors(t *testing.T) {
+	client := newScriptedClient(t, emptyResults)
+	client.snap.update(bridgeWithPort)
+
+	for _, id := range []string{"tfimport0", "br0:", "br0:tfimport0:x"} {
+		if _, err := importPort(client, id); err == nil {
+			t.Errorf("expected an error for ID %q", id)
+		}
+	}
+
+	if _, err := importPort(client, "br0:tap9"); !isNotFound(err) {
+		t.Errorf("expected a not found error for a missing port, got %v", err)
+	}
+}
@@ -0,0 +1,1348 @@
+package openvswitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// testFakeProviderServer serves the provider backed by client instead of a
+// live OVS, and configures it.
+func testFakeProviderServer(t *testing.T, client ovsClient) tfprotov6.ProviderServer {
+	t.Helper()
+
+	ctx := context.Background()
+	server, err := providerserver.NewProtocol6WithError(&openvswitchProvider{version: "test", client: client})()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	schemas, err := server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	providerType, ok := schemas.Provider.ValueType().(tftypes.Object)
+	if !ok {
+		t.Fatalf("provider schema has type %s, want an object", schemas.Provider.ValueType())
+	}
+	config, err := tfprotov6.NewDynamicValue(providerType, testObject(providerType, nil))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	resp, err := server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{Config: &config})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		t.Fatalf("configuring provider: %s", err)
+	}
+	return server
+}
+
+// testResource drives one resource instance through the plan, apply, read
+// and import calls Terraform makes, enforcing the consistency rules
+// Terraform applies to their results.
+type testResource struct {
+	t          *testing.T
+	server     tfprotov6.ProviderServer
+	typeName   string
+	schema     *tfprotov6.Schema
+	objectType tftypes.Object
+
+	// state is the current state of the instance, null when it does not
+	// exist.
+	state tftypes.Value
+
+	// diags holds the diagnostics of the last apply or refresh.
+	diags []*tfprotov6.Diagnostic
+}
+
+func newTestResource(t *testing.T, server tfprotov6.ProviderServer, typeName string) *testResource {
+	t.Helper()
+
+	schemas, err := server.GetProviderSchema(context.Background(), &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s, ok := schemas.ResourceSchemas[typeName]
+	if !ok {
+		t.Fatalf("missing resource %s", typeName)
+	}
+	objectType, ok := s.ValueType().(tftypes.Object)
+	if !ok {
+		t.Fatalf("resource %s has type %s, want an object", typeName, s.ValueType())
+	}
+	return &testResource{
+		t:          t,
+		server:     server,
+		typeName:   typeName,
+		schema:     s,
+		objectType: objectType,
+		state:      tftypes.NewValue(objectType, nil),
+	}
+}
+
+// apply plans and applies config, replacing the instance when the plan
+// requires it.
+func (r *testResource) apply(config map[string]tftypes.Value) error {
+	r.t.Helper()
+
+	cfg := testObject(r.objectType, config)
+	planned, replace, private, err := r.plan(r.state, cfg)
+	if err != nil {
+		return err
+	}
+	if replace && !r.state.IsNull() {
+		if err := r.destroy(); err != nil {
+			return err
+		}
+		if planned, _, private, err = r.plan(r.state, cfg); err != nil {
+			return err
+		}
+	}
+	if !r.state.IsNull() && planned.Equal(r.state) {
+		return nil
+	}
+	return r.applyPlanned(cfg, planned, private)
+}
+
+// destroy plans and applies the deletion of the instance.
+func (r *testResource) destroy() error {
+	r.t.Helper()
+
+	null := tftypes.NewValue(r.objectType, nil)
+	planned, _, private, err := r.plan(r.state, null)
+	if err != nil {
+		return err
+	}
+	return r.applyPlanned(null, planned, private)
+}
+
+// refresh reads the instance back, as Terraform does before planning.
+func (r *testResource) refresh() error {
+	r.t.Helper()
+
+	current := r.dynamicValue(r.state)
+	resp, err := r.server.ReadResource(context.Background(), &tfprotov6.ReadResourceRequest{
+		TypeName:     r.typeName,
+		CurrentState: &current,
+	})
+	if err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	r.diags = resp.Diagnostics
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		return err
+	}
+	r.state = r.value(resp.NewState)
+	r.checkKnown(r.state)
+	return nil
+}
+
+// importState imports the instance with the given ID and reads it back.
+func (r *testResource) importState(id string) error {
+	r.t.Helper()
+
+	resp, err := r.server.ImportResourceState(context.Background(), &tfprotov6.ImportResourceStateRequest{
+		TypeName: r.typeName,
+		ID:       id,
+	})
+	if err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		return err
+	}
+	if len(resp.ImportedResources) != 1 {
+		r.t.Fatalf("imported %d resources, want 1", len(resp.ImportedResources))
+	}
+	r.state = r.value(resp.ImportedResources[0].State)
+	return r.refresh()
+}
+
+func (r *testResource) plan(prior, config tftypes.Value) (tftypes.Value, bool, []byte, error) {
+	r.t.Helper()
+
+	priorState := r.dynamicValue(prior)
+	proposed := r.dynamicValue(r.proposedNewState(prior, config))
+	cfg := r.dynamicValue(config)
+	resp, err := r.server.PlanResourceChange(context.Background(), &tfprotov6.PlanResourceChangeRequest{
+		TypeName:         r.typeName,
+		PriorState:       &priorState,
+		ProposedNewState: &proposed,
+		Config:           &cfg,
+	})
+	if err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		return tftypes.Value{}, false, nil, err
+	}
+	return r.value(resp.PlannedState), len(resp.RequiresReplace) > 0, resp.PlannedPrivate, nil
+}
+
+// proposedNewState merges config with prior the way Terraform does before
+// planning: computed attributes left out of config keep their prior value,
+// and blocks are taken from config.
+func (r *testResource) proposedNewState(prior, config tftypes.Value) tftypes.Value {
+	if config.IsNull() || prior.IsNull() {
+		return config
+	}
+
+	var priorAttrs, configAttrs map[string]tftypes.Value
+	if err := prior.As(&priorAttrs); err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	if err := config.As(&configAttrs); err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	proposed := make(map[string]tftypes.Value, len(configAttrs))
+	for _, attr := range r.schema.Block.Attributes {
+		proposed[attr.Name] = configAttrs[attr.Name]
+		if attr.Computed && configAttrs[attr.Name].IsNull() {
+			proposed[attr.Name] = priorAttrs[attr.Name]
+		}
+	}
+	for _, block := range r.schema.Block.BlockTypes {
+		proposed[block.TypeName] = configAttrs[block.TypeName]
+	}
+	return tftypes.NewValue(r.objectType, proposed)
+}
+
+func (r *testResource) applyPlanned(config, planned tftypes.Value, private []byte) error {
+	r.t.Helper()
+
+	priorState := r.dynamicValue(r.state)
+	plannedState := r.dynamicValue(planned)
+	cfg := r.dynamicValue(config)
+	resp, err := r.server.ApplyResourceChange(context.Background(), &tfprotov6.ApplyResourceChangeRequest{
+		TypeName:       r.typeName,
+		PriorState:     &priorState,
+		PlannedState:   &plannedState,
+		Config:         &cfg,
+		PlannedPrivate: private,
+	})
+	if err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+
+	// Terraform keeps whatever state is returned, even on errors
+	r.diags = resp.Diagnostics
+	newState := r.value(resp.NewState)
+	if err := diagnosticsError(resp.Diagnostics); err != nil {
+		r.state = newState
+		return err
+	}
+	r.checkKnown(newState)
+	r.checkConsistent(planned, newState)
+	r.state = newState
+	return nil
+}
+
+// checkKnown fails the test if state holds unknown values, which Terraform
+// rejects after apply and refresh.
+func (r *testResource) checkKnown(state tftypes.Value) {
+	r.t.Helper()
+
+	if !state.IsFullyKnown() {
+		r.t.Fatalf("%s state holds unknown values: %s", r.typeName, state)
+	}
+}
+
+// checkConsistent fails the test if applying changed a value that was known
+// when planned, which Terraform reports as an inconsistent result.
+func (r *testResource) checkConsistent(planned, applied tftypes.Value) {
+	r.t.Helper()
+
+	if planned.IsNull() || applied.IsNull() {
+		return
+	}
+	var plannedAttrs, appliedAttrs map[string]tftypes.Value
+	if err := planned.As(&plannedAttrs); err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	if err := applied.As(&appliedAttrs); err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	for name, value := range plannedAttrs {
+		if value.IsFullyKnown() && !value.Equal(appliedAttrs[name]) {
+			r.t.Errorf("%s.%s was planned as %s but applied as %s", r.typeName, name, value, appliedAttrs[name])
+		}
+	}
+}
+
+// exists reports whether the instance is in state.
+func (r *testResource) exists() bool {
+	return !r.state.IsNull()
+}
+
+// attr returns the value of the attribute name in state.
+func (r *testResource) attr(name string) tftypes.Value {
+	r.t.Helper()
+
+	if r.state.IsNull() {
+		r.t.Fatalf("%s is not in state", r.typeName)
+	}
+	var attrs map[string]tftypes.Value
+	if err := r.state.As(&attrs); err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	return attrs[name]
+}
+
+// str returns the string attribute name.
+func (r *testResource) str(name string) string {
+	r.t.Helper()
+
+	var s string
+	if err := r.attr(name).As(&s); err != nil {
+		r.t.Fatalf("%s: %s", name, err)
+	}
+	return s
+}
+
+// stringSet returns the set of strings attribute name, sorted.
+func (r *testResource) stringSet(name string) []string {
+	r.t.Helper()
+
+	var elems []tftypes.Value
+	if err := r.attr(name).As(&elems); err != nil {
+		r.t.Fatalf("%s: %s", name, err)
+	}
+	values := []string{}
+	for _, e := range elems {
+		var s string
+		if err := e.As(&s); err != nil {
+			r.t.Fatalf("%s: %s", name, err)
+		}
+		values = append(values, s)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// stringMap returns the map of strings attribute name.
+func (r *testResource) stringMap(name string) map[string]string {
+	r.t.Helper()
+
+	var elems map[string]tftypes.Value
+	if err := r.attr(name).As(&elems); err != nil {
+		r.t.Fatalf("%s: %s", name, err)
+	}
+	values := make(map[string]string, len(elems))
+	for key, e := range elems {
+		var s string
+		if err := e.As(&s); err != nil {
+			r.t.Fatalf("%s: %s", name, err)
+		}
+		values[key] = s
+	}
+	return values
+}
+
+func (r *testResource) dynamicValue(v tftypes.Value) tfprotov6.DynamicValue {
+	r.t.Helper()
+
+	dv, err := tfprotov6.NewDynamicValue(r.objectType, v)
+	if err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	return dv
+}
+
+func (r *testResource) value(dv *tfprotov6.DynamicValue) tftypes.Value {
+	r.t.Helper()
+
+	if dv == nil {
+		return tftypes.NewValue(r.objectType, nil)
+	}
+	v, err := dv.Unmarshal(r.objectType)
+	if err != nil {
+		r.t.Fatalf("err: %s", err)
+	}
+	return v
+}
+
+// diagnosticsError joins the error diagnostics in diags.
+func diagnosticsError(diags []*tfprotov6.Diagnostic) error {
+	var errs []error
+	for _, d := range diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityError {
+			errs = append(errs, fmt.Errorf("%s: %s", d.Summary, d.Detail))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func TestBridgeLifecycle(t *testing.T) {
+	t.Run("fake", func(t *testing.T) {
+		testBridgeLifecycle(t, newFakeOVS())
+	})
+	t.Run("ovsdb-server", func(t *testing.T) {
+		testBridgeLifecycle(t, newServedClient(t))
+	})
+}
+
+// testBridgeLifecycle creates, updates, refreshes and destroys a bridge
+// through the provider backed by client.
+func testBridgeLifecycle(t *testing.T, client ovsClient) {
+	server := testFakeProviderServer(t, client)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	if got := bridge.str("id"); got != "br0" {
+		t.Errorf("id = %q, want br0", got)
+	}
+	if got := bridge.str("ofversion"); got != "OpenFlow13" {
+		t.Errorf("ofversion = %q, want the OpenFlow13 default", got)
+	}
+
+	// Switching to protocols updates the bridge in place
+	config := map[string]tftypes.Value{
+		"name":      testString("br0"),
+		"protocols": testStringSet("OpenFlow15", "OpenFlow13"),
+	}
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	if got := bridge.str("ofversion"); got != "OpenFlow13,OpenFlow15" {
+		t.Errorf("ofversion = %q, want OpenFlow13,OpenFlow15", got)
+	}
+	if got := bridge.str("id"); got != "br0" {
+		t.Errorf("id = %q, want the bridge updated in place", got)
+	}
+
+	// Changes made outside Terraform show up on refresh and are reverted
+	if err := client.setBridgeProtocols(ctx, "br0", []string{"OpenFlow10"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bridge.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	if got := bridge.stringSet("protocols"); !reflect.DeepEqual(got, []string{"OpenFlow10"}) {
+		t.Errorf("protocols = %v, want the drift to OpenFlow10", got)
+	}
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	row, err := client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bridgeProtocols(row); got != "OpenFlow13,OpenFlow15" {
+		t.Errorf("bridge protocols = %q, want OpenFlow13,OpenFlow15", got)
+	}
+
+	if err := bridge.destroy(); err != nil {
+		t.Fatalf("destroy: %s", err)
+	}
+	if _, err := client.getBridge(ctx, "br0"); !isNotFound(err) {
+		t.Errorf("bridge br0 still exists after destroy: %v", err)
+	}
+}
+
+func TestBridgeControllers(t *testing.T) {
+	fake := newFakeOVS()
+	fake.listening = map[string]bool{"tcp:127.0.0.1:6653": true}
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	if got := bridge.str("fail_mode"); got != "standalone" {
+		t.Errorf("fail_mode = %q, want the standalone default", got)
+	}
+	if got := bridge.stringSet("controllers"); len(got) != 0 {
+		t.Errorf("controllers = %v, want none", got)
+	}
+
+	config := map[string]tftypes.Value{
+		"name":        testString("br0"),
+		"fail_mode":   testString("secure"),
+		"controllers": testStringSet("tcp:127.0.0.1:6653", "tcp:192.0.2.1:6653"),
+	}
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	row, err := fake.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bridgeFailMode(row); got != "secure" {
+		t.Errorf("bridge fail_mode = %q, want secure", got)
+	}
+
+	var status map[string]tftypes.Value
+	if err := bridge.attr("controller_status").As(&status); err != nil {
+		t.Fatalf("controller_status: %s", err)
+	}
+	for target, want := range map[string]string{"tcp:127.0.0.1:6653": "ACTIVE", "tcp:192.0.2.1:6653": "BACKOFF"} {
+		var attrs map[string]tftypes.Value
+		if err := status[target].As(&attrs); err != nil {
+			t.Fatalf("controller_status[%q]: %s", target, err)
+		}
+		var state string
+		if err := attrs["state"].As(&state); err != nil {
+			t.Fatal(err)
+		}
+		if state != want {
+			t.Errorf("controller_status[%q].state = %q, want %q", target, state, want)
+		}
+		if connected := attrs["connected"]; !connected.Equal(testBool(want == "ACTIVE")) {
+			t.Errorf("controller_status[%q].connected = %s", target, connected)
+		}
+	}
+
+	// Changes made outside Terraform show up on refresh and are reverted
+	if err := fake.setBridgeSettings(ctx, "br0", bridgeSettings{Controllers: []string{"ptcp:6653"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bridge.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	if got := bridge.str("fail_mode"); got != "standalone" {
+		t.Errorf("fail_mode = %q, want the drift to standalone", got)
+	}
+	if got := bridge.stringSet("controllers"); !reflect.DeepEqual(got, []string{"ptcp:6653"}) {
+		t.Errorf("controllers = %v, want the drift to [ptcp:6653]", got)
+	}
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	controllers, err := fake.getControllers(ctx, "br0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := controllerTargets(controllers); !reflect.DeepEqual(got, []string{"tcp:127.0.0.1:6653", "tcp:192.0.2.1:6653"}) {
+		t.Errorf("bridge controllers = %v, want the configured ones", got)
+	}
+}
+
+func TestBridgeDatapath(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	config := map[string]tftypes.Value{
+		"name":          testString("br0"),
+		"datapath_type": testString("netdev"),
+		"datapath_id":   testString("00000000000000a1"),
+		"hwaddr":        testString("02:00:00:00:00:a1"),
+	}
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	for attr, want := range map[string]string{
+		"datapath_type":         "netdev",
+		"effective_datapath_id": "00000000000000a1",
+		"effective_hwaddr":      "02:00:00:00:00:a1",
+	} {
+		if got := bridge.str(attr); got != want {
+			t.Errorf("%s = %q, want %q", attr, got, want)
+		}
+	}
+
+	// Without a datapath ID, ovs-vswitchd derives it from the MAC
+	delete(config, "datapath_id")
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	if got := bridge.attr("datapath_id"); !got.IsNull() {
+		t.Errorf("datapath_id = %s, want null", got)
+	}
+	if got := bridge.str("effective_datapath_id"); got != "00000200000000a1" {
+		t.Errorf("effective_datapath_id = %q, want 00000200000000a1 derived from the MAC", got)
+	}
+
+	// Refreshing finds nothing to change
+	if err := bridge.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	planned, _, _, err := bridge.plan(bridge.state, testObject(bridge.objectType, config))
+	if err != nil {
+		t.Fatalf("plan: %s", err)
+	}
+	if !planned.Equal(bridge.state) {
+		t.Errorf("expected an empty plan, got %s", planned)
+	}
+}
+
+func TestSpanningTree(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	bridgeConfig := map[string]tftypes.Value{
+		"name":           testString("br0"),
+		"stp_enable":     testBool(true),
+		"stp_priority":   testNumber(4096),
+		"stp_hello_time": testNumber(1),
+	}
+	if err := bridge.apply(bridgeConfig); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+	port := newTestResource(t, server, "openvswitch_port")
+	portConfig := map[string]tftypes.Value{
+		"name":              testString("tap0"),
+		"bridge_id":         testString("br0"),
+		"stp_path_cost":     testNumber(100),
+		"stp_port_priority": testNumber(32),
+	}
+	if err := port.apply(portConfig); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+
+	// The bridge is the root, and its priority leads its ID
+	status := bridge.stringMap("stp_status")
+	if id := status["stp_bridge_id"]; !strings.HasPrefix(id, "1000.") || status["stp_designated_root"] != id {
+		t.Errorf("stp_status = %v, want the bridge with priority 4096 as the root", status)
+	}
+	if got := port.stringMap("stp_status"); got["stp_port_id"] != "2001" || got["stp_role"] != "designated" {
+		t.Errorf("port stp_status = %v, want the designated port 2001", got)
+	}
+	if got := bridge.stringMap("rstp_status"); len(got) != 0 {
+		t.Errorf("rstp_status = %v, want none while RSTP is disabled", got)
+	}
+	row, err := fake.getPort(ctx, "tap0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"stp-path-cost": "100", "stp-port-priority": "32"}
+	if got := ovsdb.StringMap(row["other_config"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("port other_config = %v, want %v", got, want)
+	}
+
+	// Switching to RSTP replaces the STP settings
+	delete(bridgeConfig, "stp_enable")
+	delete(bridgeConfig, "stp_priority")
+	delete(bridgeConfig, "stp_hello_time")
+	bridgeConfig["rstp_enable"] = testBool(true)
+	bridgeConfig["rstp_priority"] = testNumber(8192)
+	if err := bridge.apply(bridgeConfig); err != nil {
+		t.Fatalf("update bridge: %s", err)
+	}
+	portConfig = map[string]tftypes.Value{
+		"name":            testString("tap0"),
+		"bridge_id":       testString("br0"),
+		"rstp_admin_edge": testBool(true),
+	}
+	if err := port.apply(portConfig); err != nil {
+		t.Fatalf("update port: %s", err)
+	}
+	if got := bridge.stringMap("stp_status"); len(got) != 0 {
+		t.Errorf("stp_status = %v, want none once STP is disabled", got)
+	}
+	if got := bridge.stringMap("rstp_status")["rstp_root_id"]; !strings.HasPrefix(got, "2000.") {
+		t.Errorf("rstp_root_id = %q, want the bridge with priority 8192", got)
+	}
+	if got := port.stringMap("rstp_status")["rstp_port_role"]; got != "Designated" {
+		t.Errorf("port rstp_port_role = %q, want Designated", got)
+	}
+	row, err = fake.getPort(ctx, "tap0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{"rstp-port-admin-edge": "true"}
+	if got := ovsdb.StringMap(row["other_config"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("port other_config = %v, want %v", got, want)
+	}
+
+	// Refreshing finds nothing to change
+	for _, r := range []struct {
+		res    *testResource
+		config map[string]tftypes.Value
+	}{{bridge, bridgeConfig}, {port, portConfig}} {
+		if err := r.res.refresh(); err != nil {
+			t.Fatalf("refresh: %s", err)
+		}
+		planned, _, _, err := r.res.plan(r.res.state, testObject(r.res.objectType, r.config))
+		if err != nil {
+			t.Fatalf("plan: %s", err)
+		}
+		if !planned.Equal(r.res.state) {
+			t.Errorf("expected an empty plan for %s, got %s", r.res.typeName, planned)
+		}
+	}
+}
+
+func TestMulticastSnooping(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	bridgeConfig := map[string]tftypes.Value{
+		"name":                                      testString("br0"),
+		"mcast_snooping_enable":                     testBool(true),
+		"mcast_snooping_table_size":                 testNumber(4096),
+		"mcast_snooping_aging_time":                 testNumber(60),
+		"mcast_snooping_disable_flood_unregistered": testBool(true),
+	}
+	if err := bridge.apply(bridgeConfig); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+	port := newTestResource(t, server, "openvswitch_port")
+	portConfig := map[string]tftypes.Value{
+		"name":                         testString("tap0"),
+		"bridge_id":                    testString("br0"),
+		"mcast_snooping_flood":         testBool(true),
+		"mcast_snooping_flood_reports": testBool(true),
+	}
+	if err := port.apply(portConfig); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+
+	row, err := fake.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := row["mcast_snooping_enable"].(bool); !enabled {
+		t.Error("expected multicast snooping to be enabled on the bridge")
+	}
+	want := map[string]string{
+		"mcast-snooping-table-size":                 "4096",
+		"mcast-snooping-aging-time":                 "60",
+		"mcast-snooping-disable-flood-unregistered": "true",
+	}
+	if got := ovsdb.StringMap(row["other_config"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("bridge other_config = %v, want %v", got, want)
+	}
+	row, err = fake.getPort(ctx, "tap0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{"mcast-snooping-flood": "true", "mcast-snooping-flood-reports": "true"}
+	if got := ovsdb.StringMap(row["other_config"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("port other_config = %v, want %v", got, want)
+	}
+
+	// Turning snooping off leaves the defaults to OVS
+	bridgeConfig = map[string]tftypes.Value{"name": testString("br0")}
+	if err := bridge.apply(bridgeConfig); err != nil {
+		t.Fatalf("update bridge: %s", err)
+	}
+	row, err = fake.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled, _ := row["mcast_snooping_enable"].(bool); enabled {
+		t.Error("expected multicast snooping to be disabled on the bridge")
+	}
+	if got := ovsdb.StringMap(row["other_config"]); len(got) != 0 {
+		t.Errorf("bridge other_config = %v, want none", got)
+	}
+
+	if err := bridge.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	planned, _, _, err := bridge.plan(bridge.state, testObject(bridge.objectType, bridgeConfig))
+	if err != nil {
+		t.Fatalf("plan: %s", err)
+	}
+	if !planned.Equal(bridge.state) {
+		t.Errorf("expected an empty plan, got %s", planned)
+	}
+}
+
+func TestMACLearning(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	config := map[string]tftypes.Value{
+		"name":           testString("br0"),
+		"mac_aging_time": testNumber(60),
+		"mac_table_size": testNumber(65536),
+		"flood_vlans":    testNumberSet(20, 10),
+		"forward_bpdu":   testBool(true),
+	}
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+
+	row, err := fake.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bridgeFloodVLANs(row); !reflect.DeepEqual(got, []int{10, 20}) {
+		t.Errorf("flood_vlans = %v, want [10 20]", got)
+	}
+	want := map[string]string{"mac-aging-time": "60", "mac-table-size": "65536", "forward-bpdu": "true"}
+	if got := ovsdb.StringMap(row["other_config"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("bridge other_config = %v, want %v", got, want)
+	}
+
+	// Flood VLANs changed outside Terraform show as drift
+	if err := fake.setBridgeSettings(ctx, "br0", bridgeSettings{FloodVLANs: []int{10}, OtherConfig: want}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bridge.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	planned, _, _, err := bridge.plan(bridge.state, testObject(bridge.objectType, config))
+	if err != nil {
+		t.Fatalf("plan: %s", err)
+	}
+	if planned.Equal(bridge.state) {
+		t.Error("expected a plan restoring flood_vlans")
+	}
+
+	// Removing the settings leaves the defaults to OVS
+	config = map[string]tftypes.Value{"name": testString("br0")}
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("update bridge: %s", err)
+	}
+	if row, err = fake.getBridge(ctx, "br0"); err != nil {
+		t.Fatal(err)
+	}
+	if got := bridgeFloodVLANs(row); len(got) != 0 {
+		t.Errorf("flood_vlans = %v, want none", got)
+	}
+	if got := ovsdb.StringMap(row["other_config"]); len(got) != 0 {
+		t.Errorf("bridge other_config = %v, want none", got)
+	}
+}
+
+func TestBridgeRemovedOutsideTerraform(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	if err := fake.deleteBridge(context.Background(), "br0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := bridge.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	if bridge.exists() {
+		t.Error("expected the deleted bridge to be removed from state")
+	}
+}
+
+func TestBridgeDestroyRefusesForeign(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	config := map[string]tftypes.Value{"name": testString("br0")}
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+	if got := bridge.attr("force_destroy"); !got.Equal(testBool(false)) {
+		t.Errorf("force_destroy = %s, want false", got)
+	}
+
+	// A port added outside Terraform would be deleted with the bridge
+	fake.addDevice("eth1")
+	if err := fake.addPort(ctx, "br0", "eth1"); err != nil {
+		t.Fatal(err)
+	}
+	fake.disown("eth1")
+	err := bridge.destroy()
+	if err == nil || !strings.Contains(err.Error(), "ports the provider did not create: eth1") {
+		t.Errorf("expected destroying a bridge with a foreign port to fail, got %v", err)
+	}
+
+	// So would a bridge the provider did not create
+	if err := fake.deletePort(ctx, "br0", "eth1"); err != nil {
+		t.Fatal(err)
+	}
+	fake.disown("br0")
+	err = bridge.destroy()
+	if err == nil || !strings.Contains(err.Error(), "bridge br0 was not created by terraform-provider-openvswitch") {
+		t.Errorf("expected destroying a foreign bridge to fail, got %v", err)
+	}
+	if _, err := fake.getBridge(ctx, "br0"); err != nil {
+		t.Errorf("expected the bridge to be kept: %s", err)
+	}
+
+	config["force_destroy"] = testBool(true)
+	if err := bridge.apply(config); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	if err := bridge.destroy(); err != nil {
+		t.Fatalf("destroy: %s", err)
+	}
+	if _, err := fake.getBridge(ctx, "br0"); !isNotFound(err) {
+		t.Errorf("bridge br0 still exists after destroy: %v", err)
+	}
+}
+
+func TestPortDestroyRefusesForeign(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	if err := fake.addBridge(ctx, "br0", nil, bridgeSettings{}); err != nil {
+		t.Fatal(err)
+	}
+	port := newTestResource(t, server, "openvswitch_port")
+	config := map[string]tftypes.Value{
+		"name":      testString("tap0"),
+		"bridge_id": testString("br0"),
+	}
+	if err := port.apply(config); err != nil {
+		t.Fatalf("create: %s", err)
+	}
+
+	// Ports created for another owner are not deleted either
+	fake.ownerName = "staging"
+	err := port.destroy()
+	if err == nil || !strings.Contains(err.Error(), `was created for owner "", but the provider's owner is "staging"`) {
+		t.Errorf("expected destroying a port of another owner to fail, got %v", err)
+	}
+	fake.ownerName = ""
+
+	fake.disown("tap0")
+	err = port.destroy()
+	if err == nil || !strings.Contains(err.Error(), "ovs-vsctl set Port tap0 external_ids:managed-by=terraform-provider-openvswitch") {
+		t.Errorf("expected destroying a foreign port to fail with how to tag it, got %v", err)
+	}
+	if !fake.hasDevice("tap0") {
+		t.Error("expected the tap device of the port to be kept")
+	}
+
+	config["force_destroy"] = testBool(true)
+	if err := port.apply(config); err != nil {
+		t.Fatalf("update: %s", err)
+	}
+	if err := port.destroy(); err != nil {
+		t.Fatalf("destroy: %s", err)
+	}
+	if ports, _ := fake.listPorts(ctx, "br0"); len(ports) != 0 {
+		t.Errorf("ports = %v, want none", ports)
+	}
+}
+
+func TestPortLifecycle(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+
+	port := newTestResource(t, server, "openvswitch_port")
+	config := map[string]tftypes.Value{
+		"name":        testString("tap0"),
+		"bridge_id":   testString("br0"),
+		"port_config": testStringSet("down"),
+		"tap_group":   testString("kvm"),
+	}
+	if err := port.apply(config); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+
+	if got := port.str("id"); got != "br0:tap0" {
+		t.Errorf("id = %q, want br0:tap0", got)
+	}
+	if got := port.attr("tap_created"); !got.Equal(testBool(true)) {
+		t.Errorf("tap_created = %s, want true", got)
+	}
+	if got := port.str("tap_owner"); got != "0" {
+		t.Errorf("tap_owner = %q, want the provider user's ID", got)
+	}
+	if got := port.str("tap_group"); got != "kvm" {
+		t.Errorf("tap_group = %q, want kvm", got)
+	}
+	if got := port.stringSet("port_state"); !reflect.DeepEqual(got, []string{"link-down"}) {
+		t.Errorf("port_state = %v, want [link-down]", got)
+	}
+	if got := fake.portConfig("tap0"); !reflect.DeepEqual(got, []string{"down"}) {
+		t.Errorf("port config = %v, want [down]", got)
+	}
+
+	// port_config is updated in place
+	config["port_config"] = testStringSet("no-forward", "no-packet-in")
+	if err := port.apply(config); err != nil {
+		t.Fatalf("update port: %s", err)
+	}
+	if got := fake.portConfig("tap0"); !reflect.DeepEqual(got, []string{"no-forward", "no-packet-in"}) {
+		t.Errorf("port config = %v, want [no-forward no-packet-in]", got)
+	}
+	if got := port.str("id"); got != "br0:tap0" {
+		t.Errorf("id = %q, want the port updated in place", got)
+	}
+
+	// Planning the same configuration again is a no-op
+	if err := port.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	planned, _, _, err := port.plan(port.state, testObject(port.objectType, config))
+	if err != nil {
+		t.Fatalf("plan: %s", err)
+	}
+	if !planned.Equal(port.state) {
+		t.Errorf("expected an empty plan, got %s", planned)
+	}
+
+	if err := port.destroy(); err != nil {
+		t.Fatalf("destroy port: %s", err)
+	}
+	if ports, _ := fake.listPorts(ctx, "br0"); len(ports) != 0 {
+		t.Errorf("ports = %v, want none", ports)
+	}
+	if fake.hasDevice("tap0") {
+		t.Error("tap device tap0 created by the provider still exists")
+	}
+}
+
+func TestPortCreateRollsBack(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+
+	fake.modPortErr = errors.New("ovs-ofctl: br0: failed to connect to socket (Connection refused)")
+	port := newTestResource(t, server, "openvswitch_port")
+	err := port.apply(map[string]tftypes.Value{"name": testString("tap0"), "bridge_id": testString("br0")})
+	if err == nil || !strings.Contains(err.Error(), "Connection refused") {
+		t.Fatalf("expected the failed mod-port to fail the apply, got %v", err)
+	}
+
+	if port.exists() {
+		t.Error("expected the port to stay out of the state")
+	}
+	if ports, _ := fake.listPorts(ctx, "br0"); len(ports) != 0 {
+		t.Errorf("ports = %v, want the port removed again", ports)
+	}
+	if fake.hasDevice("tap0") {
+		t.Error("expected the tap device to be removed again")
+	}
+
+	var rolledBack string
+	for _, d := range port.diags {
+		if d.Severity == tfprotov6.DiagnosticSeverityWarning && d.Summary == "Rolled back the creation of port tap0" {
+			rolledBack = d.Detail
+		}
+	}
+	if !strings.Contains(rolledBack, "removed port tap0 from bridge br0, deleted tap device tap0") {
+		t.Errorf("expected a warning listing the undone steps, got %q", rolledBack)
+	}
+
+	// The next apply starts from scratch
+	fake.modPortErr = nil
+	if err := port.apply(map[string]tftypes.Value{"name": testString("tap0"), "bridge_id": testString("br0")}); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+}
+
+func TestPortCreateWaitsForInterface(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+
+	fake.unusable = map[string]string{"tap0": "could not add network device tap0 to ofproto (Invalid argument)"}
+	port := newTestResource(t, server, "openvswitch_port")
+	timeoutsType, ok := port.objectType.AttributeTypes["timeouts"].(tftypes.Object)
+	if !ok {
+		t.Fatalf("unexpected timeouts type %s", port.objectType.AttributeTypes["timeouts"])
+	}
+	config := map[string]tftypes.Value{
+		"name":      testString("tap0"),
+		"bridge_id": testString("br0"),
+		"timeouts":  testObject(timeoutsType, map[string]tftypes.Value{"create": testString("50ms")}),
+	}
+	err := port.apply(config)
+	if err == nil || !strings.Contains(err.Error(), "interface tap0 is not usable: could not add network device tap0 to ofproto (Invalid argument)") {
+		t.Fatalf("expected the interface error to fail the apply, got %v", err)
+	}
+	if port.exists() {
+		t.Error("expected the port to stay out of the state")
+	}
+	if ports, _ := fake.listPorts(ctx, "br0"); len(ports) != 0 {
+		t.Errorf("ports = %v, want the port removed again", ports)
+	}
+
+	fake.unusable = nil
+	if err := port.apply(config); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+	// The rolled back port used OpenFlow port 1
+	if got := port.attr("ofport"); !got.Equal(tftypes.NewValue(tftypes.Number, 2)) {
+		t.Errorf("ofport = %s, want 2", got)
+	}
+	if got := port.attr("link_state"); !got.Equal(testString("down")) {
+		t.Errorf("link_state = %s, want down", got)
+	}
+}
+
+func TestPortCreateConfiguresAfterSlowInterface(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+
+	// The interface takes longer to come up than one command may take,
+	// which must not cut short the steps after the wait
+	fake.commandTimeout = 100 * time.Millisecond
+	fake.addedAt = map[string]time.Time{"tap0": time.Now().Add(300 * time.Millisecond)}
+	port := newTestResource(t, server, "openvswitch_port")
+	if err := port.apply(map[string]tftypes.Value{
+		"name":                 testString("tap0"),
+		"bridge_id":            testString("br0"),
+		"mcast_snooping_flood": testBool(true),
+	}); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+	if !port.attr("mcast_snooping_flood").Equal(testBool(true)) {
+		t.Errorf("mcast_snooping_flood = %s, want true", port.attr("mcast_snooping_flood"))
+	}
+}
+
+func TestPortCreateFailsFastOnInterfaceError(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+
+	// ovs-vswitchd has given up on the interface, so the create does not
+	// wait out its timeout
+	fake.unusable = map[string]string{"tap0": "could not add network device tap0 to ofproto (Invalid argument)"}
+	port := newTestResource(t, server, "openvswitch_port")
+	timeoutsType, ok := port.objectType.AttributeTypes["timeouts"].(tftypes.Object)
+	if !ok {
+		t.Fatalf("unexpected timeouts type %s", port.objectType.AttributeTypes["timeouts"])
+	}
+	start := time.Now()
+	err := port.apply(map[string]tftypes.Value{
+		"name":      testString("tap0"),
+		"bridge_id": testString("br0"),
+		"timeouts":  testObject(timeoutsType, map[string]tftypes.Value{"create": testString("10m")}),
+	})
+	if err == nil || !strings.Contains(err.Error(), "interface tap0 is not usable") {
+		t.Fatalf("expected the interface error to fail the apply, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("the apply failed after %s, want it to fail without waiting for the timeout", elapsed)
+	}
+}
+
+func TestPortInterfaceErrorWarns(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+	port := newTestResource(t, server, "openvswitch_port")
+	if err := port.apply(map[string]tftypes.Value{"name": testString("tap0"), "bridge_id": testString("br0")}); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+
+	const msg = "could not open network device tap0 (No such device)"
+	fake.unusable = map[string]string{"tap0": msg}
+	warnings := func() []string {
+		var details []string
+		for _, d := range port.diags {
+			if d.Severity == tfprotov6.DiagnosticSeverityWarning && d.Summary == "Interface error on port tap0" {
+				details = append(details, d.Detail)
+			}
+		}
+		return details
+	}
+
+	if err := port.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	if got := warnings(); len(got) != 1 || !strings.Contains(got[0], msg) {
+		t.Errorf("expected a warning with the interface error, got %v", got)
+	}
+	if got := port.attr("interface_error"); !got.Equal(testString(msg)) {
+		t.Errorf("interface_error = %s, want %q", got, msg)
+	}
+	if got := port.attr("ofport"); !got.Equal(tftypes.NewValue(tftypes.Number, -1)) {
+		t.Errorf("ofport = %s, want -1", got)
+	}
+
+	// The error is only reported when it appears
+	if err := port.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	if got := warnings(); len(got) != 0 {
+		t.Errorf("expected no warning for a known interface error, got %v", got)
+	}
+}
+
+func TestPortVanishesWithBridge(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+	port := newTestResource(t, server, "openvswitch_port")
+	if err := port.apply(map[string]tftypes.Value{"name": testString("tap0"), "bridge_id": testString("br0")}); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+
+	if err := bridge.destroy(); err != nil {
+		t.Fatalf("destroy bridge: %s", err)
+	}
+	if err := port.refresh(); err != nil {
+		t.Fatalf("refresh: %s", err)
+	}
+	if port.exists() {
+		t.Error("expected the port to be removed from state with its bridge")
+	}
+}
+
+func TestPortAdoptsExistingDevice(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	fake.addDevice("veth0")
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+	port := newTestResource(t, server, "openvswitch_port")
+	if err := port.apply(map[string]tftypes.Value{"name": testString("veth0"), "bridge_id": testString("br0")}); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+	if got := port.attr("tap_created"); !got.Equal(testBool(false)) {
+		t.Errorf("tap_created = %s, want false", got)
+	}
+
+	if err := port.destroy(); err != nil {
+		t.Fatalf("destroy port: %s", err)
+	}
+	if !fake.hasDevice("veth0") {
+		t.Error("destroying the port deleted a device the provider did not create")
+	}
+}
+
+func TestPortOFVersionNotEnabled(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0"), "ofversion": testString("OpenFlow10")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+	port := newTestResource(t, server, "openvswitch_port")
+	err := port.apply(map[string]tftypes.Value{"name": testString("tap0"), "bridge_id": testString("br0")})
+	if err == nil || !strings.Contains(err.Error(), "not enabled on bridge br0") {
+		t.Fatalf("expected creating the port to reject OpenFlow13, got %v", err)
+	}
+	if port.exists() || fake.hasDevice("tap0") {
+		t.Error("expected nothing to be created")
+	}
+}
+
+func TestPortOFVersionEnabledInSameApply(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+
+	bridge := newTestResource(t, server, "openvswitch_bridge")
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0"), "ofversion": testString("OpenFlow10")}); err != nil {
+		t.Fatalf("create bridge: %s", err)
+	}
+
+	// The port is planned against the bridge as it is, which only warns
+	port := newTestResource(t, server, "openvswitch_port")
+	config := testObject(port.objectType, map[string]tftypes.Value{"name": testString("tap0"), "bridge_id": testString("br0")})
+	planned, _, private, err := port.plan(port.state, config)
+	if err != nil {
+		t.Fatalf("expected the plan to only warn about OpenFlow13, got %v", err)
+	}
+
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0"), "ofversion": testString("OpenFlow13")}); err != nil {
+		t.Fatalf("update bridge: %s", err)
+	}
+	if err := port.applyPlanned(config, planned, private); err != nil {
+		t.Fatalf("create port: %s", err)
+	}
+
+	// Changing the action configures the port with ofversion again
+	if err := bridge.apply(map[string]tftypes.Value{"name": testString("br0"), "ofversion": testString("OpenFlow10")}); err != nil {
+		t.Fatalf("update bridge: %s", err)
+	}
+	err = port.apply(map[string]tftypes.Value{"name": testString("tap0"), "bridge_id": testString("br0"), "action": testString("down")})
+	if err == nil || !strings.Contains(err.Error(), "not enabled on bridge br0") {
+		t.Errorf("expected updating the port to reject OpenFlow13, got %v", err)
+	}
+}
+
+func TestPortImportLifecycle(t *testing.T) {
+	fake := newFakeOVS()
+	server := testFakeProviderServer(t, fake)
+	ctx := context.Background()
+
+	if err := fake.addBridge(ctx, "br0", []string{"OpenFlow10", "OpenFlow13"}, bridgeSettings{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.taps().addTap(tapDevice{Name: "tap0", Owner: 107, Group: noID, Persist: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.addPort(ctx, "br0", "tap0"); err != nil {
+		t.Fatal(err)
+	}
+
+	port := newTestResource(t, server, "openvswitch_port")
+	if err := port.importState("br0:tap0"); err != nil {
+		t.Fatalf("import: %s", err)
+	}
+	if got := port.str("ofversion"); got != "OpenFlow13" {
+		t.Errorf("ofversion = %q, want OpenFlow13", got)
+	}
+	if got := port.str("tap_owner"); got != "107" {
+		t.Errorf("tap_owner = %q, want 107", got)
+	}
+	if got := port.attr("tap_created"); !got.Equal(testBool(false)) {
+		t.Errorf("tap_created = %s, want false", got)
+	}
+
+	// The imported state matches the configuration it came from
+	planned, replace, _, err := port.plan(port.state, testObject(port.objectType, map[string]tftypes.Value{
+		"name":      testString("tap0"),
+		"bridge_id": testString("br0"),
+		"tap_owner": testString("107"),
+	}))
+	if err != nil {
+		t.Fatalf("plan: %s", err)
+	}
+	if replace || !planned.Equal(port.state) {
+		t.Errorf("expected an empty plan after import, got %s", planned)
+	}
+}
@@ -1,112 +1,407 @@
 package openvswitch
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os/exec"
-	"os/user"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/digitalocean/go-openvswitch/ovs"
-	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework-validators/int64validator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/setvalidator"
+	"github.com/hashicorp/terraform-plugin-framework-validators/stringvalidator"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/schema/validator"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
 )
 
-// OVS Connection
-var c = ovs.New(
-	ovs.FlowFormat("OXM-OpenFlow14"),
-	ovs.Protocols([]string{
-		"OpenFlow10",
-		"OpenFlow11",
-		"OpenFlow12",
-		"OpenFlow13",
-		"OpenFlow14",
-		"OpenFlow15",
-	}),
-	ovs.Sudo(),
+// portActions lists the valid values of the deprecated action attribute.
+var portActions = []string{
+	"up", "down", "stp", "no-stp", "receive", "no-receive",
+	"no-receive-stp", "forward", "no-forward", "flood",
+	"no-flood", "packet-in", "no-packet-in",
+}
+
+var (
+	_ resource.ResourceWithConfigure      = (*portResource)(nil)
+	_ resource.ResourceWithImportState    = (*portResource)(nil)
+	_ resource.ResourceWithModifyPlan     = (*portResource)(nil)
+	_ resource.ResourceWithUpgradeState   = (*portResource)(nil)
+	_ resource.ResourceWithValidateConfig = (*portResource)(nil)
 )
 
-// Resource Definition
-func resourcePort() *schema.Resource {
-	return &schema.Resource{
-		Create: resourcePortCreate,
-		Read:   resourcePortRead,
-		Update: resourcePortUpdate,
-		Delete: resourcePortDelete,
-
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Name of the port to create",
+// portResource manages an OVS port and the tap device backing it.
+type portResource struct {
+	client ovsClient
+}
+
+func newPortResource() resource.Resource {
+	return &portResource{}
+}
+
+// portModel is the openvswitch_port resource data. The ID has the form
+// bridge:port.
+type portModel struct {
+	ID         types.String `tfsdk:"id"`
+	Name       types.String `tfsdk:"name"`
+	BridgeID   types.String `tfsdk:"bridge_id"`
+	Action     types.String `tfsdk:"action"`
+	OFVersion  types.String `tfsdk:"ofversion"`
+	PortConfig types.Set    `tfsdk:"port_config"`
+	PortState  types.Set    `tfsdk:"port_state"`
+	TapOwner   types.String `tfsdk:"tap_owner"`
+	TapGroup   types.String `tfsdk:"tap_group"`
+	MultiQueue types.Bool   `tfsdk:"multi_queue"`
+	VnetHdr    types.Bool   `tfsdk:"vnet_hdr"`
+	Persist    types.Bool   `tfsdk:"persist"`
+	TapCreated types.Bool   `tfsdk:"tap_created"`
+
+	ForceDestroy types.Bool `tfsdk:"force_destroy"`
+
+	STPPathCost      types.Int64 `tfsdk:"stp_path_cost"`
+	STPPortPriority  types.Int64 `tfsdk:"stp_port_priority"`
+	RSTPPathCost     types.Int64 `tfsdk:"rstp_path_cost"`
+	RSTPPortPriority types.Int64 `tfsdk:"rstp_port_priority"`
+	RSTPAdminEdge    types.Bool  `tfsdk:"rstp_admin_edge"`
+	STPStatus        types.Map   `tfsdk:"stp_status"`
+	RSTPStatus       types.Map   `tfsdk:"rstp_status"`
+
+	MulticastFlood        types.Bool `tfsdk:"mcast_snooping_flood"`
+	MulticastFloodReports types.Bool `tfsdk:"mcast_snooping_flood_reports"`
+
+	OFPort         types.Int64  `tfsdk:"ofport"`
+	LinkState      types.String `tfsdk:"link_state"`
+	InterfaceError types.String `tfsdk:"interface_error"`
+
+	Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+// otherConfig returns the attributes of m kept in the Port other_config
+// column.
+func (m *portModel) otherConfig() otherConfigAttrs {
+	return otherConfigAttrs{
+		{name: "stp_path_cost", key: "stp-path-cost", num: &m.STPPathCost},
+		{name: "stp_port_priority", key: "stp-port-priority", num: &m.STPPortPriority},
+		{name: "rstp_path_cost", key: "rstp-port-path-cost", num: &m.RSTPPathCost},
+		{name: "rstp_port_priority", key: "rstp-port-priority", num: &m.RSTPPortPriority},
+		{name: "rstp_admin_edge", key: "rstp-port-admin-edge", flag: &m.RSTPAdminEdge},
+		{name: "mcast_snooping_flood", key: "mcast-snooping-flood", flag: &m.MulticastFlood},
+		{name: "mcast_snooping_flood_reports", key: "mcast-snooping-flood-reports", flag: &m.MulticastFloodReports},
+	}
+}
+
+// portModelV0 is the openvswitch_port resource data before the tap device
+// settings were added.
+type portModelV0 struct {
+	ID        types.String `tfsdk:"id"`
+	Name      types.String `tfsdk:"name"`
+	BridgeID  types.String `tfsdk:"bridge_id"`
+	Action    types.String `tfsdk:"action"`
+	OFVersion types.String `tfsdk:"ofversion"`
+}
+
+func (r *portResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_port"
+}
+
+func (r *portResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Version: 1,
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+				Description:   "Bridge and port names, as bridge:port",
 			},
 
-			"bridge_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				ForceNew:    true,
-				Description: "Name of the bridge to attach the port to",
+			"name": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description:   "Name of the port to create",
 			},
 
-			"action": {
-				Type:     schema.TypeString,
-				Optional: true,
-				Default:  "up",
-				ValidateFunc: func(v interface{}, k string) (warnings []string, errors []error) {
-					value, ok := v.(string)
-					if !ok {
-						errors = append(errors, fmt.Errorf("%q must be a string", k))
-						return warnings, errors
-					}
-					validActions := []string{
-						"up", "down", "stp", "no-stp", "receive", "no-receive",
-						"no-receive-stp", "forward", "no-forward", "flood",
-						"no-flood", "packet-in", "no-packet-in",
-					}
-					for _, action := range validActions {
-						if value == action {
-							return nil, nil
-						}
-					}
-					errors = append(errors, fmt.Errorf(
-						"%q must be one of: %v", k, validActions))
-					return warnings, errors
+			"bridge_id": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+				Description:   "Name of the bridge to attach the port to",
+			},
+
+			"action": schema.StringAttribute{
+				Optional:           true,
+				Computed:           true,
+				Default:            stringdefault.StaticString("up"),
+				DeprecationMessage: "Use port_config instead",
+				Validators: []validator.String{
+					stringvalidator.OneOf(portActions...),
+					stringvalidator.ConflictsWith(path.MatchRoot("port_config")),
 				},
 				Description: "Port action (up, down, stp, no-stp, receive, no-receive, no-receive-stp, forward, no-forward, flood, no-flood, packet-in, or no-packet-in)",
 			},
-			"ofversion": {
-				Type:     schema.TypeString,
+			"ofversion": schema.StringAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString(defaultOFVersion),
+				Validators:  []validator.String{stringvalidator.OneOf(openFlowVersions...)},
+				Description: "OpenFlow protocol version used to configure the port (OpenFlow10, OpenFlow11, OpenFlow12, OpenFlow13, OpenFlow14, or OpenFlow15). Must be enabled on the bridge",
+			},
+
+			"port_config": schema.SetAttribute{
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+				Validators: []validator.Set{
+					setvalidator.ValueStringsAre(stringvalidator.OneOf(portConfigNames()...)),
+					setvalidator.ConflictsWith(path.MatchRoot("action")),
+				},
+				Description: "OpenFlow port config flags to set (down, no-receive, no-forward, no-packet-in, and with OpenFlow10 also no-stp, no-receive-stp and no-flood). Flags left out are cleared",
+			},
+
+			"port_state": schema.SetAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "OpenFlow port state flags reported by the switch, such as link-down or live",
+			},
+
+			"tap_owner": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
+				},
+				Validators:  []validator.String{tapIDValidator{}},
+				Description: "User owning the tap device, by name or numeric ID. Defaults to the user the provider runs commands as",
+			},
+
+			"tap_group": schema.StringAttribute{
 				Optional: true,
-				Default:  "OpenFlow13",
-				ValidateFunc: func(v interface{}, k string) (warnings []string, errors []error) {
-					value, ok := v.(string)
-					if !ok {
-						errors = append(errors, fmt.Errorf("%q must be a string", k))
-						return warnings, errors
-					}
-					validVersions := []string{
-						"OpenFlow10",
-						"OpenFlow11",
-						"OpenFlow12",
-						"OpenFlow13",
-						"OpenFlow14",
-						"OpenFlow15",
-					}
-					for _, version := range validVersions {
-						if value == version {
-							return nil, nil
-						}
-					}
-					errors = append(errors, fmt.Errorf(
-						"%q must be one of: %v", k, validVersions))
-					return warnings, errors
+				Computed: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+					stringplanmodifier.RequiresReplace(),
 				},
-				Description: "OpenFlow protocol version (OpenFlow10, OpenFlow11, OpenFlow12, OpenFlow13, OpenFlow14, or OpenFlow15)",
+				Validators:  []validator.String{tapIDValidator{}},
+				Description: "Group allowed to use the tap device, by name or numeric ID",
 			},
+
+			"multi_queue": schema.BoolAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description:   "Create the tap device with multiple queues",
+			},
+
+			"vnet_hdr": schema.BoolAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(false),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description:   "Create the tap device with virtio-net headers",
+			},
+
+			"persist": schema.BoolAttribute{
+				Optional:      true,
+				Computed:      true,
+				Default:       booldefault.StaticBool(true),
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.RequiresReplace()},
+				Description:   "Whether the tap device persists when no process holds it open. Only existing devices may be non-persistent",
+			},
+
+			"tap_created": schema.BoolAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.Bool{boolplanmodifier.UseStateForUnknown()},
+				Description:   "Whether the provider created the tap device, and so deletes it with the port",
+			},
+
+			"force_destroy": schema.BoolAttribute{
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+				Description: "Whether to delete the port even if the provider did not create it or created it under another owner. Must be applied before destroying the port",
+			},
+
+			"stp_path_cost": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(0, 65535)},
+				Description: "STP path cost of the port, from 0 to 65535. Defaults to a cost derived from the link speed",
+			},
+
+			"stp_port_priority": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(0, 255)},
+				Description: "STP port priority, from 0 to 255. Of two ports to the same segment, the one with the lower priority forwards. Defaults to 128",
+			},
+
+			"rstp_path_cost": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(1, 200000000)},
+				Description: "RSTP path cost of the port, from 1 to 200000000. Defaults to a cost derived from the link speed",
+			},
+
+			"rstp_port_priority": schema.Int64Attribute{
+				Optional:    true,
+				Validators:  []validator.Int64{int64validator.Between(0, 240), multipleOfValidator{step: 16}},
+				Description: "RSTP port priority, a multiple of 16 from 0 to 240. Defaults to 128",
+			},
+
+			"rstp_admin_edge": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether the port is an RSTP edge port, connected to a host rather than a bridge, which forwards as soon as it comes up",
+			},
+			"mcast_snooping_flood": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to flood multicast traffic to the port even when multicast snooping is enabled on its bridge, as for an uplink to a router",
+			},
+			"mcast_snooping_flood_reports": schema.BoolAttribute{
+				Optional:    true,
+				Description: "Whether to forward the IGMP and MLD reports the bridge receives to the port, as for an uplink to a querier",
+			},
+
+			"stp_status": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "STP status ovs-vswitchd reports for the port, such as stp_port_id, stp_state and stp_role. Empty while STP is disabled on the bridge",
+			},
+
+			"rstp_status": schema.MapAttribute{
+				ElementType: types.StringType,
+				Computed:    true,
+				Description: "RSTP status ovs-vswitchd reports for the port, such as rstp_port_id, rstp_port_role and rstp_port_state. Empty while RSTP is disabled on the bridge",
+			},
+
+			"ofport": schema.Int64Attribute{
+				Computed:    true,
+				Description: "OpenFlow port number ovs-vswitchd assigned to the port's interface, -1 if it could not add the interface",
+			},
+
+			"link_state": schema.StringAttribute{
+				Computed:    true,
+				Description: "Link state of the port's interface, up or down",
+			},
+
+			"interface_error": schema.StringAttribute{
+				Computed:    true,
+				Description: "Error ovs-vswitchd reports for the port's interface, empty if there is none",
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"timeouts": timeouts.Block(ctx, timeouts.Opts{
+				Create:            true,
+				CreateDescription: fmt.Sprintf("How long Create waits for ovs-vswitchd, as a duration such as 30s or 2m. Defaults to %s", defaultCreateTimeout),
+			}),
 		},
 	}
 }
 
+func (r *portResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	client, err := clientFromProviderData(req.ProviderData)
+	if err != nil {
+		resp.Diagnostics.AddError("Unexpected provider data", err.Error())
+		return
+	}
+	r.client = client
+}
+
+// UpgradeState upgrades ports created before the tap device settings were
+// added. Their tap devices are marked as created by the provider, since
+// those versions always deleted them with the port.
+func (r *portResource) UpgradeState(_ context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		0: {
+			PriorSchema: &schema.Schema{
+				Attributes: map[string]schema.Attribute{
+					"id":        schema.StringAttribute{Computed: true},
+					"name":      schema.StringAttribute{Required: true},
+					"bridge_id": schema.StringAttribute{Required: true},
+					"action":    schema.StringAttribute{Optional: true},
+					"ofversion": schema.StringAttribute{Optional: true},
+				},
+			},
+			StateUpgrader: upgradePortStateV0,
+		},
+	}
+}
+
+func upgradePortStateV0(ctx context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	var prior portModelV0
+	resp.Diagnostics.Append(req.State.Get(ctx, &prior)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	upgraded := portModel{
+		ID:         prior.ID,
+		Name:       prior.Name,
+		BridgeID:   prior.BridgeID,
+		Action:     prior.Action,
+		OFVersion:  prior.OFVersion,
+		PortConfig: types.SetNull(types.StringType),
+		PortState:  types.SetNull(types.StringType),
+		TapOwner:   types.StringValue(""),
+		TapGroup:   types.StringValue(""),
+		MultiQueue: types.BoolValue(false),
+		VnetHdr:    types.BoolValue(false),
+		Persist:    types.BoolValue(true),
+		TapCreated: types.BoolValue(true),
+
+		ForceDestroy: types.BoolValue(false),
+		STPStatus:    types.MapNull(types.StringType),
+		RSTPStatus:   types.MapNull(types.StringType),
+		Timeouts:     nullTimeouts(),
+	}
+	if upgraded.Action.ValueString() == "" {
+		upgraded.Action = types.StringValue("up")
+	}
+	if upgraded.OFVersion.ValueString() == "" {
+		upgraded.OFVersion = types.StringValue(defaultOFVersion)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &upgraded)...)
+}
+
+// tapIDValidator validates tap_owner and tap_group: a name or a
+// non-negative numeric ID.
+type tapIDValidator struct{}
+
+func (v tapIDValidator) Description(_ context.Context) string {
+	return "value must be a name or a non-negative numeric ID"
+}
+
+func (v tapIDValidator) MarkdownDescription(ctx context.Context) string {
+	return v.Description(ctx)
+}
+
+func (v tapIDValidator) ValidateString(ctx context.Context, req validator.StringRequest, resp *validator.StringResponse) {
+	if req.ConfigValue.IsNull() || req.ConfigValue.IsUnknown() {
+		return
+	}
+	if err := validateTapID(req.ConfigValue.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeError(req.Path, "Invalid tap ID", err.Error())
+	}
+}
+
+// validateTapID returns an error if value is a negative numeric ID.
+func validateTapID(value string) error {
+	if id, err := strconv.Atoi(value); err == nil && id < 0 {
+		return fmt.Errorf("must be a name or a non-negative ID, got %d", id)
+	}
+	return nil
+}
+
 func GetPortAction(action string) ovs.PortAction {
 	switch action {
 	case ("up"):
@@ -139,78 +434,189 @@ func GetPortAction(action string) ovs.PortAction {
 	return ovs.PortActionUp
 }
 
-func resourcePortCreate(d *schema.ResourceData, m interface{}) error {
-	port, ok := d.Get("name").(string)
-	if !ok {
-		return fmt.Errorf("name must be a string")
+func (r *portResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan portModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	bridge, ok := d.Get("bridge_id").(string)
-	if !ok {
-		return fmt.Errorf("bridge_id must be a string")
+	client := r.client
+	port := plan.Name.ValueString()
+	bridge := plan.BridgeID.ValueString()
+	ofversion := plan.OFVersion.ValueString()
+	createTimeout, diags := plan.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
 	}
 
-	action, ok := d.Get("action").(string)
-	if !ok {
-		return fmt.Errorf("action must be a string")
+	opCtx, cancel := client.context()
+	defer cancel()
+
+	// The plan cannot tell whether the bridge enables ofversion in the
+	// same apply, so this is where a mismatch fails
+	row, err := client.getBridge(opCtx, bridge)
+	if err != nil {
+		resp.Diagnostics.AddError("Error creating port", err.Error())
+		return
+	}
+	if err := checkPortOFVersion(row, bridge, ofversion); err != nil {
+		resp.Diagnostics.AddError("Error creating port", err.Error())
+		return
 	}
 
-	// Creates tap device for ovs port, this is not persistent
-	currentUser, err := user.Current()
+	// Every step taken is undone if a later one fails, so that no port
+	// is left behind outside the state
+	rb := newRollback("port " + port)
+
+	// Creates the tap device for the port unless a network device of that
+	// name already exists, in which case it is attached as is
+	created, err := ensureTap(client, &plan)
 	if err != nil {
-		return fmt.Errorf("error getting current user: %w", err)
+		resp.Diagnostics.AddError("Error creating tap device", err.Error())
+		return
+	}
+	plan.TapCreated = types.BoolValue(created)
+	if created {
+		rb.add("deleted tap device "+port, "Tap device "+port, func() error {
+			return client.taps().deleteTap(port)
+		})
 	}
 
-	cmd := exec.Command("sudo", "/sbin/ip", "tuntap", "add", "dev", port, "mode", "tap", "user", currentUser.Username)
-	if err := cmd.Run(); err != nil {
-		log.Printf("warning: error creating tap device (may already exist): %v", err)
-		// Continue even if there's an error, as the tap device might already exist
+	if err := client.addPort(opCtx, bridge, port); err != nil {
+		resp.Diagnostics.AddError("Error creating port", err.Error())
+		rb.run(&resp.Diagnostics)
+		return
 	}
+	rb.add("removed port "+port+" from bridge "+bridge, "Port "+port+" on bridge "+bridge, func() error {
+		ctx, cancel := client.context()
+		defer cancel()
+		if err := client.deletePort(ctx, bridge, port); err != nil && !isNotFound(err) {
+			return err
+		}
+		return nil
+	})
 
-	if err := c.VSwitch.AddPort(bridge, port); err != nil {
-		return fmt.Errorf("error adding port to bridge: %w", err)
+	// ovs-vswitchd accepts ports whose network device it cannot open, so
+	// wait until it has actually added the interface
+	waitCtx, waitCancel := context.WithTimeout(ctx, createTimeout)
+	defer waitCancel()
+	if err := waitForInterface(waitCtx, client, port); err != nil {
+		resp.Diagnostics.AddError("Error creating port", err.Error())
+		rb.run(&resp.Diagnostics)
+		return
 	}
 
-	if err := c.OpenFlow.ModPort(bridge, port, GetPortAction(action)); err != nil {
-		log.Printf("warning: error modifying port action: %v", err)
-		// Continue even if ModPort fails
+	if config := plan.otherConfig().config(); len(config) > 0 {
+		// The wait may have outlasted opCtx, so this gets a command
+		// timeout of its own
+		configCtx, configCancel := client.context()
+		defer configCancel()
+		if err := client.setPortOtherConfig(configCtx, bridge, port, config); err != nil {
+			resp.Diagnostics.AddError("Error configuring port", err.Error())
+			rb.run(&resp.Diagnostics)
+			return
+		}
 	}
 
-	// Set the ID using bridge:port format to ensure Terraform can track the resource
-	d.SetId(bridge + ":" + port)
-	return resourcePortRead(d, m)
+	plan.ID = types.StringValue(bridge + ":" + port)
+	resp.Diagnostics.Append(applyPortSettings(ctx, client, &plan)...)
+	if !resp.Diagnostics.HasError() {
+		found, diags := r.read(ctx, &plan)
+		resp.Diagnostics.Append(diags...)
+		if !found && !resp.Diagnostics.HasError() {
+			resp.Diagnostics.AddError("Error creating port", fmt.Sprintf("port %s disappeared after it was created", port))
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		rb.run(&resp.Diagnostics)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
 }
 
-func resourcePortRead(d *schema.ResourceData, m interface{}) error {
-	// Use Get directly for first read, or extract from ID for subsequent reads
-	var port, bridge string
-	if d.Id() == "" {
-		var ok bool
-		port, ok = d.Get("name").(string)
-		if !ok {
-			return fmt.Errorf("name must be a string")
-		}
-		bridge, ok = d.Get("bridge_id").(string)
-		if !ok {
-			return fmt.Errorf("bridge_id must be a string")
-		}
-	} else {
-		// ID format is bridge:port
-		parts := strings.Split(d.Id(), ":")
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid ID format: %s", d.Id())
-		}
-		bridge = parts[0]
-		port = parts[1]
+// applyPortSettings applies the port_config flags of a new port m or,
+// without them, its action.
+func applyPortSettings(ctx context.Context, client ovsClient, m *portModel) diag.Diagnostics {
+	bridge, port, ofversion := m.BridgeID.ValueString(), m.Name.ValueString(), m.OFVersion.ValueString()
+	if !m.PortConfig.IsNull() && !m.PortConfig.IsUnknown() {
+		return applyPortConfig(ctx, client, bridge, port, ofversion, m.PortConfig)
+	}
+
+	var diags diag.Diagnostics
+	if err := client.modPort(bridge, port, ofversion, GetPortAction(m.Action.ValueString())); err != nil {
+		diags.AddError("Error modifying port action", err.Error())
+	}
+	return diags
+}
+
+func (r *portResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state portModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	priorError := state.InterfaceError.ValueString()
+	found, diags := r.read(ctx, &state)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !found {
+		// Port doesn't exist, remove from state
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if msg := state.InterfaceError.ValueString(); msg != "" && msg != priorError {
+		resp.Diagnostics.AddWarning(
+			fmt.Sprintf("Interface error on port %s", state.Name.ValueString()),
+			fmt.Sprintf("ovs-vswitchd reports: %s", msg),
+		)
 	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+// readAndSet refreshes m after a create or update and stores it in state.
+func (r *portResource) readAndSet(ctx context.Context, m *portModel, state *tfsdk.State, diags *diag.Diagnostics) {
+	found, d := r.read(ctx, m)
+	diags.Append(d...)
+	if !found && !diags.HasError() {
+		diags.AddError("Error reading port", fmt.Sprintf("port %s no longer exists", m.ID.ValueString()))
+	}
+	if diags.HasError() {
+		return
+	}
+	diags.Append(state.Set(ctx, m)...)
+}
+
+// read refreshes m from the port named by its ID. It reports false when the
+// port or its bridge does not exist.
+func (r *portResource) read(ctx context.Context, m *portModel) (bool, diag.Diagnostics) {
+	var diags diag.Diagnostics
+	client := r.client
+
+	// ID format is bridge:port
+	parts := strings.Split(m.ID.ValueString(), ":")
+	if len(parts) != 2 {
+		diags.AddError("Error reading port", fmt.Sprintf("invalid ID format: %s", m.ID.ValueString()))
+		return false, diags
+	}
+	bridge, port := parts[0], parts[1]
+
+	opCtx, cancel := client.context()
+	defer cancel()
 
 	// Check if port exists by getting the bridge ports and checking if our port is in the list
-	ports, err := c.VSwitch.ListPorts(bridge)
+	ports, err := client.listPorts(opCtx, bridge)
 	if err != nil {
-		log.Printf("warning: error listing ports (bridge may not exist): %v", err)
-		// If we can't list ports, the bridge might not exist
-		d.SetId("")
-		return nil
+		if isNotFound(err) {
+			// The bridge is gone, and the port with it
+			return false, diags
+		}
+		diags.AddError("Error reading port", err.Error())
+		return false, diags
 	}
 
 	portExists := false
@@ -220,81 +626,526 @@ func resourcePortRead(d *schema.ResourceData, m interface{}) error {
 			break
 		}
 	}
-
 	if !portExists {
-		// Port doesn't exist, remove from state
-		d.SetId("")
-		return nil
+		return false, diags
+	}
+
+	// Port exists, set attributes. The action and ofversion attributes are
+	// kept as they are in the state.
+	m.Name = types.StringValue(port)
+	m.BridgeID = types.StringValue(bridge)
+	if m.Action.ValueString() == "" {
+		m.Action = types.StringValue("up")
+	}
+	if m.OFVersion.ValueString() == "" {
+		m.OFVersion = types.StringValue(defaultOFVersion)
+	}
+	if m.ForceDestroy.IsNull() {
+		// Created before force_destroy was added
+		m.ForceDestroy = types.BoolValue(false)
 	}
 
-	// Port exists, set attributes
-	if err := d.Set("name", port); err != nil {
-		return fmt.Errorf("error setting name: %w", err)
+	row, err := client.getPort(opCtx, port)
+	if err != nil {
+		if isNotFound(err) {
+			return false, diags
+		}
+		diags.AddError("Error reading port", err.Error())
+		return false, diags
 	}
-	if err := d.Set("bridge_id", bridge); err != nil {
-		return fmt.Errorf("error setting bridge_id: %w", err)
+	m.otherConfig().read(ovsdb.StringMap(row["other_config"]))
+	var d diag.Diagnostics
+	m.STPStatus, m.RSTPStatus, d = readSpanningTree(ctx, row)
+	diags.Append(d...)
+
+	diags.Append(readPortStatus(ctx, client, m, bridge, port)...)
+	diags.Append(readInterface(opCtx, client, m, port)...)
+
+	// Tap devices the provider did not create are managed elsewhere, so
+	// their settings are not tracked
+	if m.TapCreated.ValueBool() {
+		if err := readTap(ctx, client, m, port); err != nil {
+			diags.AddError("Error reading tap device", err.Error())
+		}
 	}
 
-	// Keep the action and ofversion attributes in the state if they're already there
-	// This ensures we don't lose attributes after apply
-	if action, ok := d.GetOk("action"); ok {
-		if err := d.Set("action", action); err != nil {
-			return fmt.Errorf("error setting action: %w", err)
+	m.setUnknownToNull()
+	return true, diags
+}
+
+// setUnknownToNull replaces the computed values left unknown, such as the
+// port status of ports missing from the datapath, with their empty values.
+func (m *portModel) setUnknownToNull() {
+	if m.PortConfig.IsUnknown() || m.PortConfig.IsNull() {
+		m.PortConfig = types.SetValueMust(types.StringType, nil)
+	}
+	if m.PortState.IsUnknown() || m.PortState.IsNull() {
+		m.PortState = types.SetValueMust(types.StringType, nil)
+	}
+	if m.TapOwner.IsUnknown() {
+		m.TapOwner = types.StringValue("")
+	}
+	if m.TapGroup.IsUnknown() {
+		m.TapGroup = types.StringValue("")
+	}
+	if m.TapCreated.IsUnknown() {
+		m.TapCreated = types.BoolValue(false)
+	}
+	if m.OFPort.IsUnknown() {
+		m.OFPort = types.Int64Null()
+	}
+	if m.LinkState.IsUnknown() {
+		m.LinkState = types.StringValue("")
+	}
+	if m.InterfaceError.IsUnknown() {
+		m.InterfaceError = types.StringValue("")
+	}
+}
+
+func (r *portResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state portModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.client
+	port := plan.Name.ValueString()
+	bridge := plan.BridgeID.ValueString()
+	ofversion := plan.OFVersion.ValueString()
+
+	if plan.otherConfig().changed(state.otherConfig()) {
+		opCtx, cancel := client.context()
+		defer cancel()
+
+		if err := client.setPortOtherConfig(opCtx, bridge, port, plan.otherConfig().config()); err != nil {
+			resp.Diagnostics.AddError("Error configuring port", err.Error())
+			return
 		}
 	}
-	if ofversion, ok := d.GetOk("ofversion"); ok {
-		if err := d.Set("ofversion", ofversion); err != nil {
-			return fmt.Errorf("error setting ofversion: %w", err)
+
+	setConfig := !plan.PortConfig.IsUnknown() && !plan.PortConfig.Equal(state.PortConfig)
+	if setConfig || !plan.Action.Equal(state.Action) {
+		// The plan only warns, as the bridge may enable ofversion in
+		// the same apply
+		opCtx, cancel := client.context()
+		defer cancel()
+
+		row, err := client.getBridge(opCtx, bridge)
+		if err == nil {
+			err = checkPortOFVersion(row, bridge, ofversion)
+		}
+		if err != nil {
+			resp.Diagnostics.AddError("Error modifying port", err.Error())
+			return
 		}
 	}
 
-	return nil
+	if setConfig {
+		resp.Diagnostics.Append(applyPortConfig(ctx, client, bridge, port, ofversion, plan.PortConfig)...)
+	} else if !plan.Action.Equal(state.Action) {
+		if err := client.modPort(bridge, port, ofversion, GetPortAction(plan.Action.ValueString())); err != nil {
+			resp.Diagnostics.AddError("Error modifying port action", err.Error())
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = state.ID
+	plan.TapCreated = state.TapCreated
+	r.readAndSet(ctx, &plan, &resp.State, &resp.Diagnostics)
 }
 
-func resourcePortUpdate(d *schema.ResourceData, m interface{}) error {
-	port, ok := d.Get("name").(string)
-	if !ok {
-		return fmt.Errorf("name must be a string")
+// applyPortConfig sets the port_config flags in set on port and clears the
+// others.
+func applyPortConfig(ctx context.Context, client ovsClient, bridge, port, ofversion string, set types.Set) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var want []string
+	diags.Append(set.ElementsAs(ctx, &want, false)...)
+	if diags.HasError() {
+		return diags
 	}
 
-	bridge, ok := d.Get("bridge_id").(string)
-	if !ok {
-		return fmt.Errorf("bridge_id must be a string")
+	current, err := client.showPort(bridge, port, ofversion)
+	if err != nil {
+		diags.AddError("Error reading port config", err.Error())
+		return diags
+	}
+	if err := setPortConfig(client, bridge, port, ofversion, want, current); err != nil {
+		diags.AddError("Error applying port config", err.Error())
 	}
+	return diags
+}
+
+// readInterface reads the OpenFlow port number, link state and error of the
+// interface of port into m.
+func readInterface(ctx context.Context, client ovsClient, m *portModel, port string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	row, err := client.getInterface(ctx, port)
+	if err != nil && !isNotFound(err) {
+		diags.AddError("Error reading port interface", err.Error())
+		return diags
+	}
+
+	m.OFPort = types.Int64Null()
+	if ofport, ok := interfaceOFPort(row); ok {
+		m.OFPort = types.Int64Value(int64(ofport))
+	}
+	m.LinkState = types.StringValue(interfaceLinkState(row))
+	m.InterfaceError = types.StringValue(interfaceError(row))
+	return diags
+}
+
+// readPortStatus reads the OpenFlow config and state flags of port back
+// into m.
+func readPortStatus(ctx context.Context, client ovsClient, m *portModel, bridge, port string) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	status, err := client.showPort(bridge, port, m.OFVersion.ValueString())
+	if err != nil {
+		if isNotFound(err) {
+			// ovs-vswitchd has not added the port to the datapath, for
+			// example because its network device is missing
+			tflog.Warn(ctx, "port is not in the datapath of its bridge", map[string]interface{}{
+				"port":   port,
+				"bridge": bridge,
+			})
+			return diags
+		}
+		diags.AddError("Error reading port status", err.Error())
+		return diags
+	}
+
+	var d diag.Diagnostics
+	m.PortConfig, d = types.SetValueFrom(ctx, types.StringType, status.Config)
+	diags.Append(d...)
+	m.PortState, d = types.SetValueFrom(ctx, types.StringType, status.State)
+	diags.Append(d...)
+	return diags
+}
+
+func (r *portResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state portModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	client := r.client
+	port := state.Name.ValueString()
+	bridge := state.BridgeID.ValueString()
 
-	action, ok := d.Get("action").(string)
-	if !ok {
-		return fmt.Errorf("action must be a string")
+	opCtx, cancel := client.context()
+	defer cancel()
+
+	if !state.ForceDestroy.ValueBool() {
+		refusal, err := portDeleteRefusal(opCtx, client, port)
+		if err != nil {
+			resp.Diagnostics.AddError("Error deleting port", err.Error())
+			return
+		}
+		if refusal != "" {
+			resp.Diagnostics.AddError("Refusing to delete port "+port, refusal)
+			return
+		}
+	}
+
+	if err := client.deletePort(opCtx, bridge, port); err != nil && !isNotFound(err) {
+		resp.Diagnostics.AddError("Error deleting port", err.Error())
+		return
 	}
 
-	err := c.OpenFlow.ModPort(bridge, port, GetPortAction(action))
+	// Only remove the tap device if the provider created it
+	if state.TapCreated.ValueBool() {
+		if err := client.taps().deleteTap(port); err != nil {
+			if _, getErr := client.taps().getTap(port); !isNotFound(getErr) {
+				resp.Diagnostics.AddError("Error deleting tap device", err.Error())
+			}
+		}
+	}
+}
+
+// ensureTap creates the tap device for the port as configured in m, unless
+// a network device of that name already exists. It reports whether the
+// device was created.
+func ensureTap(client ovsClient, m *portModel) (bool, error) {
+	taps := client.taps()
+	port := m.Name.ValueString()
+
+	_, err := taps.getTap(port)
+	switch {
+	case err == nil || errors.Is(err, errNotTap):
+		return false, nil
+	case !isNotFound(err):
+		return false, err
+	}
+
+	tap, err := tapFromModel(client, taps, m)
 	if err != nil {
-		return fmt.Errorf("error modifying port action: %w", err)
+		return false, err
+	}
+	if !tap.Persist {
+		return false, fmt.Errorf("tap device %s does not exist: non-persistent tap devices must be created by the process holding them open", port)
 	}
+	if err := taps.addTap(*tap); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// tapFromModel builds the tap device settings for the port from m. The
+// owner defaults to the user the provider runs commands as.
+func tapFromModel(client ovsClient, taps tapManager, m *portModel) (*tapDevice, error) {
+	ownerName := m.TapOwner.ValueString()
+	if ownerName == "" {
+		username, err := client.username()
+		if err != nil {
+			return nil, err
+		}
+		ownerName = username
+	}
+	owner, err := resolveID(ownerName, taps.lookupUser)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := resolveID(m.TapGroup.ValueString(), taps.lookupGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tapDevice{
+		Name:       m.Name.ValueString(),
+		Owner:      owner,
+		Group:      group,
+		MultiQueue: m.MultiQueue.ValueBool(),
+		VnetHdr:    m.VnetHdr.ValueBool(),
+		Persist:    m.Persist.ValueBool(),
+	}, nil
+}
+
+// readTap reads the settings of the tap device for port back into m so that
+// changes made outside Terraform show up in plans.
+func readTap(ctx context.Context, client ovsClient, m *portModel, port string) error {
+	taps := client.taps()
+
+	tap, err := taps.getTap(port)
+	if err != nil {
+		if isNotFound(err) || errors.Is(err, errNotTap) {
+			tflog.Warn(ctx, "tap device created for the port was replaced or removed", map[string]interface{}{
+				"port":  port,
+				"error": err.Error(),
+			})
+			return nil
+		}
+		return err
+	}
+
+	// Keep owner and group names in state as long as they still resolve to
+	// the IDs on the device
+	for _, attr := range []struct {
+		value  *types.String
+		id     int
+		lookup func(string) (int, error)
+	}{
+		{value: &m.TapOwner, id: tap.Owner, lookup: taps.lookupUser},
+		{value: &m.TapGroup, id: tap.Group, lookup: taps.lookupGroup},
+	} {
+		if id, err := resolveID(attr.value.ValueString(), attr.lookup); err != nil || id != attr.id {
+			*attr.value = types.StringValue(formatID(attr.id))
+		}
+	}
+
+	m.MultiQueue = types.BoolValue(tap.MultiQueue)
+	m.VnetHdr = types.BoolValue(tap.VnetHdr)
+	m.Persist = types.BoolValue(tap.Persist)
 	return nil
 }
 
-func resourcePortDelete(d *schema.ResourceData, m interface{}) error {
-	port, ok := d.Get("name").(string)
-	if !ok {
-		return fmt.Errorf("name must be a string")
+// ImportState imports the port identified by a bridge:port ID. The
+// OpenFlow version is taken from the bridge, and the tap settings from the
+// network device if it is a tap. The provider did not create the device, so
+// it is left in place when the port is destroyed.
+func (r *portResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	m, err := importPort(r.client, req.ID)
+	if err != nil {
+		resp.Diagnostics.AddError("Error importing port", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, m)...)
+}
+
+// importPort returns the resource data of the port identified by id.
+func importPort(client ovsClient, id string) (*portModel, error) {
+	parts := strings.Split(id, ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid ID %q: expected bridge:port", id)
+	}
+	bridge, port := parts[0], parts[1]
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	row, err := client.getBridge(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	ports, err := client.listPorts(ctx, bridge)
+	if err != nil {
+		return nil, err
+	}
+	found := false
+	for _, p := range ports {
+		if p == port {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("port %s on bridge %s: %w", port, bridge, errNotFound)
+	}
+
+	tap := &tapDevice{Owner: noID, Group: noID, Persist: true}
+	if t, err := client.taps().getTap(port); err == nil {
+		tap = t
+	} else if !errors.Is(err, errNotTap) && !isNotFound(err) {
+		return nil, err
+	}
+
+	return &portModel{
+		ID:         types.StringValue(id),
+		Name:       types.StringValue(port),
+		BridgeID:   types.StringValue(bridge),
+		Action:     types.StringValue("up"),
+		OFVersion:  types.StringValue(bridgeOFVersion(row)),
+		PortConfig: types.SetNull(types.StringType),
+		PortState:  types.SetNull(types.StringType),
+		TapOwner:   types.StringValue(formatID(tap.Owner)),
+		TapGroup:   types.StringValue(formatID(tap.Group)),
+		MultiQueue: types.BoolValue(tap.MultiQueue),
+		VnetHdr:    types.BoolValue(tap.VnetHdr),
+		Persist:    types.BoolValue(tap.Persist),
+		TapCreated: types.BoolValue(false),
+
+		ForceDestroy: types.BoolValue(false),
+		STPStatus:    types.MapNull(types.StringType),
+		RSTPStatus:   types.MapNull(types.StringType),
+		Timeouts:     nullTimeouts(),
+	}, nil
+}
+
+// ValidateConfig checks that the port's ofversion supports the requested
+// port_config flags.
+func (r *portResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config portModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if config.OFVersion.IsUnknown() || config.PortConfig.IsNull() || config.PortConfig.IsUnknown() {
+		return
+	}
+
+	ofversion := config.OFVersion.ValueString()
+	if ofversion == "" {
+		ofversion = defaultOFVersion
+	}
+	var flags []string
+	resp.Diagnostics.Append(config.PortConfig.ElementsAs(ctx, &flags, true)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := checkPortConfigVersion(ofversion, flags); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("port_config"), "Unsupported port_config flag", err.Error())
+	}
+}
+
+// ModifyPlan warns at plan time when the port's ofversion is not enabled on
+// its bridge. The bridge's own plan, which may enable it in the same apply,
+// is not visible here, so only Create and Update, which configure the port
+// with ofversion, fail on a mismatch.
+func (r *portResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var plan portModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !req.State.Raw.IsNull() {
+		var state portModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		// port_config is left unknown when it is not configured, unless the
+		// deprecated action is unchanged
+		if plan.PortConfig.IsUnknown() && plan.Action.Equal(state.Action) {
+			plan.PortConfig = state.PortConfig
+			resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("port_config"), state.PortConfig)...)
+		}
 	}
 
-	bridge, ok := d.Get("bridge_id").(string)
-	if !ok {
-		return fmt.Errorf("bridge_id must be a string")
+	if r.client == nil || plan.BridgeID.IsUnknown() || plan.OFVersion.IsUnknown() {
+		return
 	}
+	bridge := plan.BridgeID.ValueString()
 
-	// Deletes tap device for ovs port
-	cmd := exec.Command("sudo", "/sbin/ip", "tuntap", "del", "dev", port, "mode", "tap")
-	if err := cmd.Run(); err != nil {
-		log.Printf("warning: error deleting tap device: %v", err)
-		// Continue even if there's an error, as we still want to try to delete the port
+	opCtx, cancel := r.client.context()
+	defer cancel()
+
+	row, err := r.client.getBridge(opCtx, bridge)
+	if err != nil {
+		if !isNotFound(err) {
+			resp.Diagnostics.AddError("Error reading bridge", err.Error())
+		}
+		return
+	}
+	if err := checkPortOFVersion(row, bridge, plan.OFVersion.ValueString()); err != nil {
+		resp.Diagnostics.AddAttributeWarning(path.Root("ofversion"), "OpenFlow version not enabled on bridge",
+			err.Error()+". Applying the port fails unless the bridge enables it first")
 	}
+}
 
-	if err := c.VSwitch.DeletePort(bridge, port); err != nil {
-		return fmt.Errorf("error deleting port from bridge: %w", err)
+// checkPortOFVersion returns an error unless ofversion is enabled on the
+// Bridge row. Bridges with an empty protocols column enable the defaults of
+// the running OVS release, which are not checked.
+func checkPortOFVersion(row ovsdb.Row, bridge, ofversion string) error {
+	protocols := ovsdb.Strings(row["protocols"])
+	if len(protocols) == 0 {
+		return nil
 	}
+	for _, p := range protocols {
+		if p == ofversion {
+			return nil
+		}
+	}
+	sort.Strings(protocols)
+	return fmt.Errorf("ofversion %s is not enabled on bridge %s, which has protocols %s", ofversion, bridge, strings.Join(protocols, ", "))
+}
 
+// checkPortConfigVersion returns an error if flags holds port_config flags
+// that ofversion cannot express.
+func checkPortConfigVersion(ofversion string, flags []string) error {
+	if ofversion == "OpenFlow10" {
+		return nil
+	}
+	for _, f := range portConfigFlags {
+		if !f.openFlow10 {
+			continue
+		}
+		for _, flag := range flags {
+			if flag == f.name {
+				return fmt.Errorf("port_config %q requires ofversion OpenFlow10, got %s", f.name, ofversion)
+			}
+		}
+	}
 	return nil
 }
@@ -1,9 +1,16 @@
 package openvswitch
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/digitalocean/go-openvswitch/ovs"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
 )
 
 func TestGetPortAction(t *testing.T) {
@@ -121,3 +128,175 @@ func TestGetPortActionCaseSensitivity(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTapID(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: "qemu", valid: true},
+		{value: "0", valid: true},
+		{value: "1000", valid: true},
+		{value: "-1", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := validateTapID(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateTapID(%q) = %v, want valid %v", tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestResourcePortValidatesRSTPPortPriority(t *testing.T) {
+	for priority, valid := range map[int]bool{
+		0:   true,
+		128: true,
+		240: true,
+		100: false,
+		256: false,
+	} {
+		diags := testValidateResourceConfig(t, "openvswitch_port", map[string]tftypes.Value{
+			"name":               testString("tap0"),
+			"bridge_id":          testString("br0"),
+			"rstp_port_priority": testNumber(priority),
+		})
+		if testHasError(diags) == valid {
+			t.Errorf("rstp_port_priority = %d: diagnostics = %v, want valid %v", priority, diags, valid)
+		}
+	}
+}
+
+func TestResourcePortStateUpgradeV0(t *testing.T) {
+	state := testUpgradePortState(t, 0, `{
+		"id": "br0:tap0",
+		"name": "tap0",
+		"bridge_id": "br0",
+		"action": "up",
+		"ofversion": "OpenFlow13"
+	}`)
+
+	want := map[string]tftypes.Value{
+		"id":          tftypes.NewValue(tftypes.String, "br0:tap0"),
+		"name":        tftypes.NewValue(tftypes.String, "tap0"),
+		"tap_created": tftypes.NewValue(tftypes.Bool, true),
+		"persist":     tftypes.NewValue(tftypes.Bool, true),
+		"multi_queue": tftypes.NewValue(tftypes.Bool, false),
+	}
+	for key, value := range want {
+		if !state[key].Equal(value) {
+			t.Errorf("%s = %s, want %s", key, state[key], value)
+		}
+	}
+}
+
+func TestResourcePortStateFromSDKProvider(t *testing.T) {
+	// State written by releases built on the previous plugin SDK
+	state := testUpgradePortState(t, 1, `{
+		"id": "br0:tap0",
+		"name": "tap0",
+		"bridge_id": "br0",
+		"action": "up",
+		"ofversion": "OpenFlow13",
+		"port_config": [],
+		"port_state": ["live"],
+		"tap_owner": "qemu",
+		"tap_group": "",
+		"multi_queue": false,
+		"vnet_hdr": true,
+		"persist": true,
+		"tap_created": true
+	}`)
+
+	want := map[string]tftypes.Value{
+		"id":          tftypes.NewValue(tftypes.String, "br0:tap0"),
+		"tap_owner":   tftypes.NewValue(tftypes.String, "qemu"),
+		"vnet_hdr":    tftypes.NewValue(tftypes.Bool, true),
+		"tap_created": tftypes.NewValue(tftypes.Bool, true),
+		"port_state":  testStringSet("live"),
+	}
+	for key, value := range want {
+		if !state[key].Equal(value) {
+			t.Errorf("%s = %s, want %s", key, state[key], value)
+		}
+	}
+}
+
+// testUpgradePortState upgrades a raw openvswitch_port state of the given
+// schema version and returns its attributes.
+func testUpgradePortState(t *testing.T, version int64, raw string) map[string]tftypes.Value {
+	t.Helper()
+
+	server := testProviderServer(t)
+	objectType := testResourceType(t, server, "openvswitch_port")
+
+	resp, err := server.UpgradeResourceState(context.Background(), &tfprotov6.UpgradeResourceStateRequest{
+		TypeName: "openvswitch_port",
+		Version:  version,
+		RawState: &tfprotov6.RawState{JSON: []byte(raw)},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, d := range resp.Diagnostics {
+		t.Fatalf("%s: %s: %s", d.Severity, d.Summary, d.Detail)
+	}
+
+	value, err := resp.UpgradedState.Unmarshal(objectType)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var attrs map[string]tftypes.Value
+	if err := value.As(&attrs); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return attrs
+}
+
+func TestCheckPortOFVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		protocols interface{}
+		ofversion string
+		valid     bool
+	}{
+		{name: "enabled", protocols: ovsdb.Set{"OpenFlow10", "OpenFlow13"}, ofversion: "OpenFlow13", valid: true},
+		{name: "single atom", protocols: "OpenFlow15", ofversion: "OpenFlow15", valid: true},
+		{name: "not enabled", protocols: ovsdb.Set{"OpenFlow10", "OpenFlow13"}, ofversion: "OpenFlow14", valid: false},
+		{name: "ovs defaults", protocols: ovsdb.Set{}, ofversion: "OpenFlow14", valid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPortOFVersion(ovsdb.Row{"protocols": tt.protocols}, "br0", tt.ofversion)
+			if (err == nil) != tt.valid {
+				t.Errorf("checkPortOFVersion(%s) = %v, want valid %v", tt.ofversion, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestClientOpenFlowUsesPortVersion(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := filepath.Join(dir, "ovs-ofctl")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > "+argsFile+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := (&Config{PrivilegeEscalation: "none", OVSOfctlPath: script}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := client.openFlow("OpenFlow10").ModPort("br0", "tap0", ovs.PortActionNoFlood); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "--protocols=OpenFlow10 ") {
+		t.Errorf("ovs-ofctl args = %q, want only OpenFlow10 offered", args)
+	}
+}
@@ -5,63 +5,110 @@ import (
 	"os/exec"
 	"testing"
 
-	"github.com/hashicorp/terraform/helper/resource"
-	"github.com/hashicorp/terraform/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
 )
 
 func TestAccPort_basic(t *testing.T) {
-	skipIfOvsNotInstalled(t)
-	skipIfNoSudo(t)
+	client := testAccSandbox(t)
+	skipIfNotRoot(t)
 
-	var bridgeName = "testbridge"
-	var portName = "testport"
+	bridgeName := testAccName("br")
+	portName := testAccName("tap")
 
-	resource.Test(t, resource.TestCase{
-		PreCheck:     func() { testAccPreCheck(t) },
-		Providers:    testAccProviders,
-		CheckDestroy: testAccCheckPortDestroy,
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccSandboxProviderFactories(client),
+		CheckDestroy:             testAccCheckPortDestroy(client),
 		Steps: []resource.TestStep{
 			{
 				Config: testAccPortConfig(bridgeName, portName),
 				Check: resource.ComposeTestCheckFunc(
-					testAccCheckPortExists("openvswitch_port.test"),
+					testAccCheckPortExists(client, "openvswitch_port.test"),
 					resource.TestCheckResourceAttr("openvswitch_port.test", "name", portName),
 					resource.TestCheckResourceAttr("openvswitch_port.test", "ofversion", "OpenFlow13"),
 					resource.TestCheckResourceAttr("openvswitch_port.test", "action", "up"),
 				),
 			},
+			{
+				ResourceName:      "openvswitch_port.test",
+				ImportState:       true,
+				ImportStateVerify: true,
+				// Imported tap devices are never owned by the provider, and
+				// their owner is read back as a numeric ID.
+				ImportStateVerifyIgnore: []string{"tap_created", "tap_owner"},
+			},
 		},
 	})
 }
 
-func testAccCheckPortDestroy(s *terraform.State) error {
-	for _, rs := range s.RootModule().Resources {
-		if rs.Type != "openvswitch_port" {
-			continue
-		}
+func TestAccPort_portConfig(t *testing.T) {
+	client := testAccSandbox(t)
+	skipIfNotRoot(t)
 
-		portName := rs.Primary.Attributes["name"]
-		bridgeName := rs.Primary.Attributes["bridge_id"]
+	bridgeName := testAccName("br")
+	portName := testAccName("tap")
 
-		// Check if port still exists on the bridge
-		cmd := exec.Command("ovs-vsctl", "list-ports", bridgeName)
-		output, err := cmd.Output()
-		if err == nil && string(output) != "" {
-			return fmt.Errorf("Port %s still exists on bridge %s", portName, bridgeName)
-		}
+	resource.ParallelTest(t, resource.TestCase{
+		PreCheck:                 func() { testAccPreCheck(t) },
+		ProtoV6ProviderFactories: testAccSandboxProviderFactories(client),
+		CheckDestroy:             testAccCheckPortDestroy(client),
+		Steps: []resource.TestStep{
+			{
+				Config: testAccPortConfigFlags(bridgeName, portName, `["no-forward", "no-packet-in"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("openvswitch_port.test", "port_config.#", "2"),
+				),
+			},
+			{
+				Config: testAccPortConfigFlags(bridgeName, portName, `["down"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("openvswitch_port.test", "port_config.#", "1"),
+				),
+			},
+			{
+				// A flag set outside Terraform is detected and cleared
+				PreConfig: func() {
+					if out, err := client.run("ovs-ofctl", "-O", "OpenFlow13", "mod-port", bridgeName, portName, "no-receive"); err != nil {
+						t.Fatalf("err: %s: %s", err, out)
+					}
+				},
+				Config: testAccPortConfigFlags(bridgeName, portName, `["down"]`),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("openvswitch_port.test", "port_config.#", "1"),
+				),
+			},
+		},
+	})
+}
 
-		// Check if tap device still exists
-		cmd = exec.Command("ip", "link", "show", portName)
-		err = cmd.Run()
-		if err == nil {
-			return fmt.Errorf("Tap device %s still exists", portName)
+func testAccCheckPortDestroy(client *Client) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		ctx, cancel := client.context()
+		defer cancel()
+		for _, rs := range s.RootModule().Resources {
+			if rs.Type != "openvswitch_port" {
+				continue
+			}
+
+			portName := rs.Primary.Attributes["name"]
+
+			// The port may have gone with its bridge, but must be gone
+			if _, err := client.getInterface(ctx, portName); !isNotFound(err) {
+				return fmt.Errorf("Port %s still exists", portName)
+			}
+
+			// Check if tap device still exists
+			if err := exec.Command("ip", "link", "show", portName).Run(); err == nil {
+				return fmt.Errorf("Tap device %s still exists", portName)
+			}
 		}
-	}
 
-	return nil
+		return nil
+	}
 }
 
-func testAccCheckPortExists(n string) resource.TestCheckFunc {
+func testAccCheckPortExists(client *Client, n string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
 		if !ok {
@@ -76,13 +123,18 @@ func testAccCheckPortExists(n string) resource.TestCheckFunc {
 		bridgeName := rs.Primary.Attributes["bridge_id"]
 
 		// Check if port exists on the bridge
-		cmd := exec.Command("ovs-vsctl", "port-to-br", portName)
-		output, err := cmd.Output()
-		if err != nil || string(output) != bridgeName+"\n" {
-			return fmt.Errorf("Port %s doesn't exist on bridge %s", portName, bridgeName)
+		ctx, cancel := client.context()
+		defer cancel()
+		ports, err := client.listPorts(ctx, bridgeName)
+		if err != nil {
+			return fmt.Errorf("Error listing ports of bridge %s: %w", bridgeName, err)
 		}
-
-		return nil
+		for _, p := range ports {
+			if p == portName {
+				return nil
+			}
+		}
+		return fmt.Errorf("Port %s doesn't exist on bridge %s", portName, bridgeName)
 	}
 }
 
@@ -101,3 +153,19 @@ resource "openvswitch_port" "test" {
 }
 `, bridgeName, portName)
 }
+
+func testAccPortConfigFlags(bridgeName, portName, flags string) string {
+	return fmt.Sprintf(`
+resource "openvswitch_bridge" "test" {
+  name = "%s"
+  ofversion = "OpenFlow13"
+}
+
+resource "openvswitch_port" "test" {
+  name = "%s"
+  bridge_id = openvswitch_bridge.test.name
+  ofversion = "OpenFlow13"
+  port_config = %s
+}
+`, bridgeName, portName, flags)
+}
@@ -0,0 +1,70 @@
+package openvswitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultMaxRetries is how often transient failures are retried unless the
+// provider configuration says otherwise.
+const defaultMaxRetries = 5
+
+// Backoff between attempts doubles from retryBaseDelay up to retryMaxDelay.
+// They are variables so that tests can shorten them.
+var (
+	retryBaseDelay = 100 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+)
+
+// retryable reports whether err is a transient failure: a transaction
+// whose verify operation found the rows it was built from changed, or an
+// OVS command killed by the alarm its --timeout option sets.
+func retryable(err error) bool {
+	if errors.Is(err, errConflict) {
+		return true
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status, ok := exitErr.Sys().(syscall.WaitStatus)
+		return ok && status.Signaled() && status.Signal() == syscall.SIGALRM
+	}
+	var sshErr *ssh.ExitError
+	if errors.As(err, &sshErr) {
+		return sshErr.Signal() == "ALRM"
+	}
+	return false
+}
+
+// retry runs fn until it succeeds or fails for good, retrying transient
+// failures up to the configured number of times with exponential backoff.
+// When retries run out, the last error is returned labeled with what and
+// the number of attempts.
+func (c *Client) retry(ctx context.Context, what string, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !retryable(err) {
+			return err
+		}
+
+		if attempt <= c.config.MaxRetries {
+			select {
+			case <-ctx.Done():
+			case <-time.After(delay):
+				delay = min(2*delay, retryMaxDelay)
+				continue
+			}
+		}
+		if attempt == 1 {
+			return err
+		}
+		return fmt.Errorf("%s: giving up after %d attempts: %w", what, attempt, err)
+	}
+}
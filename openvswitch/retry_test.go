@@ -0,0 +1,239 @@
+package openvswitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// fastRetries shortens the backoff between retries for the test.
+func fastRetries(t *testing.T) {
+	t.Helper()
+	base, maxDelay := retryBaseDelay, retryMaxDelay
+	retryBaseDelay, retryMaxDelay = time.Millisecond, time.Millisecond
+	t.Cleanup(func() { retryBaseDelay, retryMaxDelay = base, maxDelay })
+}
+
+// conflicted fails the verify operation leading a transaction with the
+// error ovsdb-server reports when the rows it verifies changed.
+func conflicted(ops []ovsdb.Operation) []ovsdb.OperationResult {
+	results := emptyResults(ops)
+	results[0] = ovsdb.OperationResult{Error: ovsdbWaitTimedOut, Details: `"wait" timed out after 0 ms`}
+	return results
+}
+
+func TestClientRetriesConflicts(t *testing.T) {
+	fastRetries(t)
+
+	attempts := 0
+	client := newScriptedClient(t, func(ops []ovsdb.Operation) []ovsdb.OperationResult {
+		attempts++
+		if attempts < 3 {
+			return conflicted(ops)
+		}
+		results := reconfigured(ops)
+		results[1].Count = 1
+		return results
+	})
+	client.config.MaxRetries = defaultMaxRetries
+	client.snap.update(bridgeWithPort)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.setBridgeSettings(ctx, "br0", bridgeSettings{STPEnable: true}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if attempts != 4 {
+		t.Errorf("expected two retries and a cur_cfg poll, got %d transactions", attempts)
+	}
+}
+
+func TestClientRetriesGiveUp(t *testing.T) {
+	fastRetries(t)
+
+	attempts := 0
+	client := newScriptedClient(t, func(ops []ovsdb.Operation) []ovsdb.OperationResult {
+		attempts++
+		return conflicted(ops)
+	})
+	client.config.MaxRetries = 2
+	client.snap.update(bridgeWithPort)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	err := client.setBridgeSettings(ctx, "br0", bridgeSettings{STPEnable: true})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if !strings.Contains(err.Error(), "set bridge br0: giving up after 3 attempts: error configuring bridge br0: changed by another client") {
+		t.Errorf("unexpected error %q", err)
+	}
+	var opErr *ovsdb.OperationError
+	if !errors.As(err, &opErr) || opErr.Err != ovsdbWaitTimedOut {
+		t.Errorf("expected the error to wrap the OVSDB error, got %v", err)
+	}
+}
+
+func TestClientVerifyCatchesConcurrentChange(t *testing.T) {
+	client := newServedClient(t)
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.addBridge(ctx, "br0", nil, bridgeSettings{Controllers: []string{"tcp:127.0.0.1:6653"}}); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	snap, err := client.snapshot(ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	bridge, _ := snap.get("Bridge", "br0")
+	stale := ovsdb.TableUpdates{
+		"Bridge":     {bridge["_uuid"].(ovsdb.UUID): {New: bridge}},
+		"Controller": {},
+	}
+	for _, row := range snap.lookup("Controller", ovsdb.UUIDs(bridge["controller"])) {
+		stale["Controller"][row["_uuid"].(ovsdb.UUID)] = ovsdb.RowUpdate{New: row}
+	}
+
+	// Another client moves the bridge to a new controller
+	other, err := (&Config{OVSDBEndpoint: client.config.OVSDBEndpoint}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := other.setBridgeSettings(ctx, "br0", bridgeSettings{Controllers: []string{"tcp:127.0.0.2:6653"}}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for {
+		if controllers, err := client.getControllers(ctx, "br0"); err == nil && controllerTargets(controllers)[0] == "tcp:127.0.0.2:6653" {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatal("the change of the other client never reached the snapshot")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// Had the snapshot not seen it yet, the transaction built from the old
+	// controller would leave the new one in place, and ovsdb-server must
+	// refuse it
+	client.snap.update(stale)
+	err = client.setBridgeSettings(ctx, "br0", bridgeSettings{Controllers: []string{"tcp:127.0.0.1:6653"}})
+	if !errors.Is(err, errConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	var opErr *ovsdb.OperationError
+	if !errors.As(err, &opErr) || opErr.Op != "wait" || opErr.Err != ovsdbWaitTimedOut {
+		t.Errorf("expected the error ovsdb-server reports for a failed wait, got %v", err)
+	}
+}
+
+func TestClientRetriesOnlyTransientErrors(t *testing.T) {
+	fastRetries(t)
+
+	attempts := 0
+	client := newScriptedClient(t, func(ops []ovsdb.Operation) []ovsdb.OperationResult {
+		attempts++
+		results := emptyResults(ops)
+		results[0] = ovsdb.OperationResult{Error: "constraint violation"}
+		return results
+	})
+	client.config.MaxRetries = defaultMaxRetries
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	err := client.setBridgeProtocols(ctx, "br0", []string{"OpenFlow13"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("expected no retries, got %d attempts", attempts)
+	}
+	if strings.Contains(err.Error(), "giving up") {
+		t.Errorf("unexpected error %q", err)
+	}
+}
+
+func TestClientRunRetriesTimeouts(t *testing.T) {
+	fastRetries(t)
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found, skipping test")
+	}
+
+	// The command times out, like an OVS utility run with --timeout, until
+	// its third run.
+	count := filepath.Join(t.TempDir(), "count")
+	script := `echo run >> "$0"; [ "$(wc -l < "$0")" -ge 3 ] || kill -ALRM $$`
+
+	for _, tt := range []struct {
+		maxRetries int
+		runs       int
+		err        string
+	}{
+		{maxRetries: defaultMaxRetries, runs: 3},
+		{maxRetries: 1, runs: 2, err: "ovs-appctl: giving up after 2 attempts: signal: alarm clock"},
+		{maxRetries: 0, runs: 1, err: "signal: alarm clock"},
+	} {
+		if err := os.Remove(count); err != nil && !errors.Is(err, os.ErrNotExist) {
+			t.Fatal(err)
+		}
+		client, err := (&Config{
+			PrivilegeEscalation: privilegeEscalationNone,
+			OVSAppctlPath:       sh,
+			MaxRetries:          tt.maxRetries,
+		}).Client()
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		_, err = client.run("ovs-appctl", "-c", script, count)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("max_retries %d: err: %s", tt.maxRetries, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("max_retries %d: expected error %q, got %v", tt.maxRetries, tt.err, err)
+		}
+
+		out, err := os.ReadFile(count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if runs := strings.Count(string(out), "run"); runs != tt.runs {
+			t.Errorf("max_retries %d: expected %d runs, got %d", tt.maxRetries, tt.runs, runs)
+		}
+	}
+}
+
+func TestRetryStopsWithContext(t *testing.T) {
+	client := &Client{config: &Config{MaxRetries: defaultMaxRetries}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := client.retry(ctx, "transaction", func() error {
+		attempts++
+		return fmt.Errorf("%w: %w", errConflict, &ovsdb.OperationError{Op: "wait", Err: ovsdbWaitTimedOut})
+	})
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+	var opErr *ovsdb.OperationError
+	if !errors.As(err, &opErr) {
+		t.Errorf("unexpected error %v", err)
+	}
+}
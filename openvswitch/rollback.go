@@ -0,0 +1,60 @@
+package openvswitch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// rollback records the steps a Create has completed, so that they can be
+// undone when a later step fails and no half-created object is left behind
+// outside the state.
+type rollback struct {
+	resource string
+	steps    []rollbackStep
+}
+
+type rollbackStep struct {
+	// done and left describe the step once undone, and what is left
+	// behind if undoing it fails.
+	done, left string
+	undo       func() error
+}
+
+// newRollback returns an empty rollback for the Create of resource, for
+// example "port tap0".
+func newRollback(resource string) *rollback {
+	return &rollback{resource: resource}
+}
+
+// add records a completed step.
+func (r *rollback) add(done, left string, undo func() error) {
+	r.steps = append(r.steps, rollbackStep{done: done, left: left, undo: undo})
+}
+
+// run undoes the recorded steps, latest first, and reports the outcome in
+// diags: a warning listing what was undone, and an error for each step that
+// could not be undone.
+func (r *rollback) run(diags *diag.Diagnostics) {
+	var undone []string
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+		if err := step.undo(); err != nil {
+			diags.AddError(
+				fmt.Sprintf("Error rolling back the creation of %s", r.resource),
+				fmt.Sprintf("%s is left behind and must be removed by hand: %s", step.left, err),
+			)
+			continue
+		}
+		undone = append(undone, step.done)
+	}
+	r.steps = nil
+
+	if len(undone) > 0 {
+		diags.AddWarning(
+			fmt.Sprintf("Rolled back the creation of %s", r.resource),
+			fmt.Sprintf("Creating %s failed, so the steps already taken were undone: %s.", r.resource, strings.Join(undone, ", ")),
+		)
+	}
+}
@@ -0,0 +1,46 @@
+package openvswitch
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+func TestRollback(t *testing.T) {
+	var undone []string
+	rb := newRollback("port tap0")
+	rb.add("deleted tap device tap0", "Tap device tap0", func() error {
+		undone = append(undone, "tap")
+		return nil
+	})
+	rb.add("removed port tap0 from bridge br0", "Port tap0 on bridge br0", func() error {
+		undone = append(undone, "port")
+		return errors.New("connection refused")
+	})
+
+	var diags diag.Diagnostics
+	rb.run(&diags)
+
+	if !reflect.DeepEqual(undone, []string{"port", "tap"}) {
+		t.Errorf("undone = %v, want the steps in reverse order", undone)
+	}
+	if len(diags) != 2 {
+		t.Fatalf("expected an error and a warning, got %v", diags)
+	}
+	if d := diags.Errors(); len(d) != 1 || !strings.Contains(d[0].Detail(), "Port tap0 on bridge br0 is left behind and must be removed by hand: connection refused") {
+		t.Errorf("unexpected error diagnostics %v", d)
+	}
+	if d := diags.Warnings(); len(d) != 1 || !strings.HasSuffix(d[0].Detail(), "undone: deleted tap device tap0.") {
+		t.Errorf("unexpected warning diagnostics %v", d)
+	}
+
+	// Steps are undone once
+	diags = nil
+	rb.run(&diags)
+	if len(diags) != 0 || len(undone) != 2 {
+		t.Errorf("expected nothing left to undo, got %v", diags)
+	}
+}
@@ -0,0 +1,86 @@
+package openvswitch
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/helper/acctest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/trvon/terraform-provider-openvswitch/internal/sandbox"
+)
+
+// testAccSandbox starts a private ovsdb-server and ovs-vswitchd for an
+// acceptance test and returns a Client managing it. Bridges use the netdev
+// datapath, so the test needs neither root nor the OVS kernel module, and
+// tests with sandboxes of their own can run in parallel. The sandbox is
+// torn down when the test ends.
+func testAccSandbox(t *testing.T) *Client {
+	t.Helper()
+
+	if os.Getenv(resource.EnvTfAcc) == "" {
+		t.Skipf("acceptance tests skipped unless env %q set", resource.EnvTfAcc)
+	}
+	for _, bin := range []string{"ovsdb-tool", "ovsdb-server", "ovs-vswitchd", "ovs-ofctl"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not found, skipping test", bin)
+		}
+	}
+
+	sb, err := sandbox.Start(context.Background(), sandbox.Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := sb.Close(); err != nil {
+			t.Errorf("err: %s", err)
+		}
+	})
+
+	client, err := (&Config{
+		OVSDBEndpoint:       sb.Endpoint(),
+		OVSRunDir:           sb.Dir(),
+		PrivilegeEscalation: privilegeEscalationNone,
+		DatapathType:        sandbox.DatapathType,
+	}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return client
+}
+
+// testAccRootEnv, when set, makes tests that need root fail rather than
+// skip without it. The CI acceptance job, which runs as root, sets it so
+// that the port tests cannot silently stop running.
+const testAccRootEnv = "TF_ACC_ROOT"
+
+// skipIfNotRoot skips tests that create tap devices, which a sandbox cannot
+// contain, unless testAccRootEnv is set.
+func skipIfNotRoot(t *testing.T) {
+	t.Helper()
+	if os.Getuid() == 0 {
+		return
+	}
+	if os.Getenv(testAccRootEnv) != "" {
+		t.Fatalf("tap devices require root, which env %q says this run has", testAccRootEnv)
+	}
+	t.Skip("tap devices require root, skipping test")
+}
+
+// testAccSandboxProviderFactories serves the provider managing the sandbox
+// of client.
+func testAccSandboxProviderFactories(client *Client) map[string]func() (tfprotov6.ProviderServer, error) {
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"openvswitch": providerserver.NewProtocol6WithError(&openvswitchProvider{version: "test", client: client}),
+	}
+}
+
+// testAccName returns a random name for a bridge or port. Network devices
+// live outside the sandbox, so names must not collide between tests
+// running in parallel, and stay within the 15 characters Linux allows.
+func testAccName(prefix string) string {
+	return prefix + acctest.RandString(8)
+}
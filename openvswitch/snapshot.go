@@ -0,0 +1,144 @@
+package openvswitch
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// snapshotTables lists the tables held by a snapshot.
+var snapshotTables = []string{"Bridge", "Port", "Interface", "Controller"}
+
+// snapshot is an in-memory copy of the Bridge, Port, Interface and
+// Controller tables, kept current by a monitor on the ovsdb-server
+// connection. Reads are answered from it, so refreshing hundreds of ports
+// costs no transaction per port. Rows handed out are replaced, never
+// modified, by later updates and must not be modified by their readers
+// either.
+type snapshot struct {
+	mu     sync.RWMutex
+	rows   map[string]map[ovsdb.UUID]ovsdb.Row
+	byName map[string]map[string]ovsdb.UUID
+}
+
+func newSnapshot() *snapshot {
+	s := &snapshot{
+		rows:   make(map[string]map[ovsdb.UUID]ovsdb.Row, len(snapshotTables)),
+		byName: make(map[string]map[string]ovsdb.UUID, len(snapshotTables)),
+	}
+	for _, table := range snapshotTables {
+		s.rows[table] = make(map[ovsdb.UUID]ovsdb.Row)
+		s.byName[table] = make(map[string]ovsdb.UUID)
+	}
+	return s
+}
+
+// requests returns the monitor requests for every column of the tables.
+func (s *snapshot) requests() map[string]ovsdb.MonitorRequest {
+	requests := make(map[string]ovsdb.MonitorRequest, len(snapshotTables))
+	for _, table := range snapshotTables {
+		requests[table] = ovsdb.MonitorRequest{}
+	}
+	return requests
+}
+
+// update applies the changes reported by the monitor. Rows get an _uuid
+// column, as in the result of a select.
+func (s *snapshot) update(updates ovsdb.TableUpdates) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for table, rows := range updates {
+		tableRows, ok := s.rows[table]
+		if !ok {
+			continue
+		}
+		names := s.byName[table]
+		for uuid, u := range rows {
+			if old, ok := tableRows[uuid]; ok {
+				if name, _ := old["name"].(string); names[name] == uuid {
+					delete(names, name)
+				}
+			}
+			if u.New == nil {
+				delete(tableRows, uuid)
+				continue
+			}
+
+			row := make(ovsdb.Row, len(u.New)+1)
+			for column, value := range u.New {
+				row[column] = value
+			}
+			row["_uuid"] = uuid
+			tableRows[uuid] = row
+			if name, ok := row["name"].(string); ok {
+				names[name] = uuid
+			}
+		}
+	}
+}
+
+// get returns the row of table named name.
+func (s *snapshot) get(table, name string) (ovsdb.Row, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	uuid, ok := s.byName[table][name]
+	if !ok {
+		return nil, false
+	}
+	return s.rows[table][uuid], true
+}
+
+// lookup returns the rows of table with the given UUIDs, skipping those
+// that do not exist.
+func (s *snapshot) lookup(table string, uuids []ovsdb.UUID) []ovsdb.Row {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rows := make([]ovsdb.Row, 0, len(uuids))
+	for _, uuid := range uuids {
+		if row, ok := s.rows[table][uuid]; ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// names returns the names of the rows of table, sorted.
+func (s *snapshot) names(table string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.byName[table]))
+	for name := range s.byName[table] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ports returns the rows of the ports on bridge, or false if there is no
+// such bridge.
+func (s *snapshot) ports(bridge string) ([]ovsdb.Row, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	uuid, ok := s.byName["Bridge"][bridge]
+	if !ok {
+		return nil, false
+	}
+	var ports []ovsdb.Row
+	for _, p := range ovsdb.UUIDs(s.rows["Bridge"][uuid]["ports"]) {
+		if row, ok := s.rows["Port"][p]; ok {
+			ports = append(ports, row)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		a, _ := ports[i]["name"].(string)
+		b, _ := ports[j]["name"].(string)
+		return a < b
+	})
+	return ports, true
+}
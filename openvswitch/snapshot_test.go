@@ -0,0 +1,92 @@
+package openvswitch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+func TestSnapshotUpdate(t *testing.T) {
+	s := newSnapshot()
+	s.update(ovsdb.TableUpdates{
+		"Bridge": {"b": {New: ovsdb.Row{"name": "br0", "ports": ovsdb.UUID("p")}}},
+		"Port":   {"p": {New: ovsdb.Row{"name": "tap0"}}},
+		"Mirror": {"m": {New: ovsdb.Row{"name": "m0"}}},
+	})
+
+	row, ok := s.get("Bridge", "br0")
+	if !ok {
+		t.Fatal("bridge br0 not found")
+	}
+	if row["_uuid"] != ovsdb.UUID("b") {
+		t.Errorf("_uuid = %v, want b", row["_uuid"])
+	}
+	if ports, ok := s.ports("br0"); !ok || len(ports) != 1 || ports[0]["name"] != "tap0" {
+		t.Errorf("ports = %v, %v", ports, ok)
+	}
+	if _, ok := s.get("Mirror", "m0"); ok {
+		t.Error("expected tables outside the snapshot to be ignored")
+	}
+
+	// A renamed row is found under its new name only
+	s.update(ovsdb.TableUpdates{
+		"Port": {"p": {Old: ovsdb.Row{"name": "tap0"}, New: ovsdb.Row{"name": "tap1"}}},
+	})
+	if _, ok := s.get("Port", "tap0"); ok {
+		t.Error("expected tap0 to be gone after the rename")
+	}
+	if _, ok := s.get("Port", "tap1"); !ok {
+		t.Error("port tap1 not found")
+	}
+
+	s.update(ovsdb.TableUpdates{
+		"Bridge": {"b": {Old: ovsdb.Row{"name": "br0"}}},
+	})
+	if _, ok := s.get("Bridge", "br0"); ok {
+		t.Error("expected br0 to be deleted")
+	}
+	if _, ok := s.ports("br0"); ok {
+		t.Error("expected no ports for a deleted bridge")
+	}
+}
+
+func TestClientSnapshotFollowsOtherClients(t *testing.T) {
+	client := newServedClient(t)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.addBridge(ctx, "br0", nil, bridgeSettings{}); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	if _, err := client.getBridge(ctx, "br0"); err != nil {
+		t.Fatalf("expected the snapshot to reflect the new bridge: %s", err)
+	}
+
+	// Another client, like ovs-vsctl, removes the bridge
+	other, err := ovsdb.Dial(ctx, client.config.OVSDBEndpoint, ovsdb.DialOptions{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer other.Close()
+	if _, err := other.Transact(ctx, vswitchDatabase, ovsdb.Operation{
+		Op:    "update",
+		Table: "Open_vSwitch",
+		Row:   ovsdb.Row{"bridges": ovsdb.Set{}},
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Once a later request on the connection is answered, the update has
+	// been applied
+	if err := client.db.Echo(context.Background()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := client.getBridge(ctx, "br0"); !isNotFound(err) {
+		t.Errorf("expected the snapshot to drop the deleted bridge, got %v", err)
+	}
+	if _, err := client.getInterface(ctx, "br0"); !isNotFound(err) {
+		t.Errorf("expected the snapshot to drop the collected interface, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package openvswitch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSHConfig describes the remote host on which every command and OVSDB
+// connection of the provider is run.
+type SSHConfig struct {
+	// Host is the remote host name or address, optionally with a :port
+	// suffix. The port defaults to 22.
+	Host string
+
+	// User is the login user on the remote host.
+	User string
+
+	// PrivateKey is the PEM encoded private key used to authenticate.
+	PrivateKey string
+
+	// KnownHosts holds known_hosts lines used to verify the host key. When
+	// empty, ~/.ssh/known_hosts of the user running Terraform is used.
+	KnownHosts string
+}
+
+// address returns Host with the default SSH port applied.
+func (s *SSHConfig) address() string {
+	if _, _, err := net.SplitHostPort(s.Host); err == nil {
+		return s.Host
+	}
+	return net.JoinHostPort(strings.Trim(s.Host, "[]"), "22")
+}
+
+// clientConfig builds the ssh.ClientConfig for s.
+func (s *SSHConfig) clientConfig() (*ssh.ClientConfig, error) {
+	if s.Host == "" || s.User == "" {
+		return nil, errors.New("ssh host and user are required")
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(s.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ssh private_key: %w", err)
+	}
+
+	hostKeyCallback, err := s.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            s.User,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+	}, nil
+}
+
+// hostKeyCallback verifies host keys against KnownHosts, or against the
+// user's known_hosts file when KnownHosts is empty.
+func (s *SSHConfig) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if s.KnownHosts == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("ssh known_hosts not set and home directory unknown: %w", err)
+		}
+		callback, err := knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+		if err != nil {
+			return nil, fmt.Errorf("ssh known_hosts not set and ~/.ssh/known_hosts unusable: %w", err)
+		}
+		return callback, nil
+	}
+
+	// knownhosts only reads files, so stage the configured lines in one.
+	f, err := os.CreateTemp("", "openvswitch-known-hosts")
+	if err != nil {
+		return nil, fmt.Errorf("error staging ssh known_hosts: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(s.KnownHosts + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error staging ssh known_hosts: %w", err)
+	}
+
+	callback, err := knownhosts.New(f.Name())
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ssh known_hosts: %w", err)
+	}
+	return callback, nil
+}
+
+// sshConn holds the lazily established SSH connection of a Client.
+type sshConn struct {
+	config *SSHConfig
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+// get returns the SSH client, connecting on first use or after the previous
+// connection was lost.
+func (s *sshConn) get(ctx context.Context) (*ssh.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		// A failed keepalive means the connection is gone.
+		if _, _, err := s.client.SendRequest("keepalive@openssh.com", true, nil); err == nil {
+			return s.client, nil
+		}
+		s.client.Close()
+		s.client = nil
+	}
+
+	config, err := s.config.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.config.address())
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to ssh host %s: %w", s.config.Host, err)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, s.config.address(), config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error connecting to ssh host %s: %w", s.config.Host, err)
+	}
+	s.client = ssh.NewClient(c, chans, reqs)
+	return s.client, nil
+}
+
+// run executes argv on the remote host and returns its combined output.
+func (s *sshConn) run(ctx context.Context, argv []string, stdin []byte) ([]byte, error) {
+	client, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error opening ssh session: %w", err)
+	}
+	defer session.Close()
+
+	// Sessions have no context support; closing the session aborts the
+	// command when ctx expires.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			session.Close()
+		case <-done:
+		}
+	}()
+
+	if stdin != nil {
+		session.Stdin = bytes.NewReader(stdin)
+	}
+	out, err := session.CombinedOutput(shellJoin(argv))
+	if ctx.Err() != nil {
+		return out, fmt.Errorf("running %s on %s: %w", argv[0], s.config.Host, ctx.Err())
+	}
+	return out, err
+}
+
+// dial opens a connection from the remote host to address. Unix sockets are
+// forwarded with the direct-streamlocal OpenSSH extension.
+func (s *sshConn) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	client, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.DialContext(ctx, network, address)
+}
+
+// shellJoin quotes argv for the remote user's POSIX shell.
+func shellJoin(argv []string) string {
+	quoted := make([]string, 0, len(argv))
+	for _, arg := range argv {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// shellQuote quotes s unless it consists only of characters that are safe
+// in a POSIX shell word.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=+.,:/@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,327 @@
+package openvswitch
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is an in-process SSH server standing in for a remote
+// hypervisor. It runs exec requests with the local shell and forwards
+// direct-streamlocal channels to local unix sockets.
+type testSSHServer struct {
+	addr       string
+	privateKey string
+	knownHosts string
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+
+	_, hostKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientPub, clientKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized key")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &testSSHServer{
+		addr:       l.Addr().String(),
+		privateKey: string(pem.EncodeToMemory(block)),
+		knownHosts: knownhosts.Line([]string{knownhosts.Normalize(l.Addr().String())}, hostSigner.PublicKey()),
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serveConn(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testSSHServer) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			go s.serveSession(newChannel)
+		case "direct-streamlocal@openssh.com":
+			go serveStreamLocal(newChannel)
+		default:
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+func (s *testSSHServer) serveSession(newChannel ssh.NewChannel) {
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	for req := range reqs {
+		if req.Type != "exec" {
+			_ = req.Reply(false, nil)
+			continue
+		}
+
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			_ = req.Reply(false, nil)
+			return
+		}
+		_ = req.Reply(true, nil)
+
+		s.mu.Lock()
+		s.commands = append(s.commands, payload.Command)
+		s.mu.Unlock()
+
+		cmd := exec.Command("sh", "-c", payload.Command)
+		cmd.Stdin = channel
+		cmd.Stdout = channel
+		cmd.Stderr = channel.Stderr()
+
+		status := uint32(0)
+		if err := cmd.Run(); err != nil {
+			status = 1
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				status = uint32(exitErr.ExitCode())
+			}
+		}
+		_, _ = channel.SendRequest("exit-status", false, binary.BigEndian.AppendUint32(nil, status))
+		return
+	}
+}
+
+func serveStreamLocal(newChannel ssh.NewChannel) {
+	var payload struct {
+		SocketPath string
+		Reserved0  string
+		Reserved1  uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	conn, err := net.Dial("unix", payload.SocketPath)
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, reqs, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	go func() {
+		_, _ = io.Copy(channel, conn)
+		channel.Close()
+	}()
+	_, _ = io.Copy(conn, channel)
+	conn.Close()
+}
+
+func (s *testSSHServer) sshConfig() *SSHConfig {
+	return &SSHConfig{
+		Host:       s.addr,
+		User:       "ovs",
+		PrivateKey: s.privateKey,
+		KnownHosts: s.knownHosts,
+	}
+}
+
+func TestSSHRun(t *testing.T) {
+	server := newTestSSHServer(t)
+
+	config := &Config{
+		PrivilegeEscalation: "none",
+		IPPath:              "echo",
+		SSH:                 server.sshConfig(),
+	}
+	client, err := config.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	out, err := client.run("ip", "tuntap", "add", "dev", "tap 0", "it's")
+	if err != nil {
+		t.Fatalf("err: %s: %s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "tuntap add dev tap 0 it's" {
+		t.Errorf("output = %q", got)
+	}
+
+	owner, err := client.username()
+	if err != nil || owner != "ovs" {
+		t.Errorf("username = %q, %v, want the ssh user", owner, err)
+	}
+
+	// The connection is reused across commands.
+	if _, err := client.run("ip", "link"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.commands) != 2 {
+		t.Errorf("server ran %d commands, want 2", len(server.commands))
+	}
+}
+
+func TestSSHRunFailure(t *testing.T) {
+	server := newTestSSHServer(t)
+
+	config := &Config{
+		PrivilegeEscalation: "none",
+		IPPath:              "false",
+		SSH:                 server.sshConfig(),
+	}
+	client, err := config.Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var exitErr *ssh.ExitError
+	if _, err := client.run("ip"); !errors.As(err, &exitErr) || exitErr.ExitStatus() != 1 {
+		t.Errorf("expected exit status 1, got %v", err)
+	}
+}
+
+func TestSSHDialUnixSocket(t *testing.T) {
+	server := newTestSSHServer(t)
+
+	socket := filepath.Join(t.TempDir(), "db.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	client, err := (&Config{SSH: server.sshConfig()}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	conn, err := client.dial(ctx, "unix", socket)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != "hello\n" {
+		t.Errorf("read %q, %v through the tunnel", line, err)
+	}
+}
+
+func TestSSHRejectsUnknownHostKey(t *testing.T) {
+	server := newTestSSHServer(t)
+	other := newTestSSHServer(t)
+
+	sshConfig := server.sshConfig()
+	sshConfig.KnownHosts = strings.Replace(other.knownHosts, other.addr, server.addr, 1)
+
+	client, err := (&Config{PrivilegeEscalation: "none", SSH: sshConfig}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var keyErr *knownhosts.KeyError
+	if _, err := client.run("ip"); !errors.As(err, &keyErr) {
+		t.Errorf("expected a host key error, got %v", err)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "ovs-vsctl", want: "ovs-vsctl"},
+		{in: "OVS_RUNDIR=/var/run/openvswitch", want: "OVS_RUNDIR=/var/run/openvswitch"},
+		{in: "", want: "''"},
+		{in: "a b", want: "'a b'"},
+		{in: "it's", want: `'it'\''s'`},
+		{in: "$(reboot)", want: "'$(reboot)'"},
+	}
+
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
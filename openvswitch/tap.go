@@ -0,0 +1,204 @@
+package openvswitch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// noID marks an unset tap owner or group, as reported by the kernel.
+const noID = -1
+
+// Flags of a tun device as reported in /sys/class/net/<dev>/tun_flags.
+const (
+	iffTap        = 0x0002
+	iffMultiQueue = 0x0100
+	iffPersist    = 0x0800
+	iffVnetHdr    = 0x4000
+)
+
+// errNotTap is returned when a network device exists but is not a tap device.
+var errNotTap = errors.New("not a tap device")
+
+// tapDevice describes a tap device. Owner and Group are numeric IDs, or noID
+// when unset.
+type tapDevice struct {
+	Name       string
+	Owner      int
+	Group      int
+	MultiQueue bool
+	VnetHdr    bool
+	Persist    bool
+}
+
+// tapManager creates, inspects and removes the tap devices backing ports.
+type tapManager interface {
+	// addTap creates the tap device described by tap.
+	addTap(tap tapDevice) error
+
+	// getTap returns the tap device called name. It returns an error
+	// wrapping errNotFound when there is no such network device, and one
+	// wrapping errNotTap when the device is not a tap device.
+	getTap(name string) (*tapDevice, error)
+
+	// deleteTap removes the tap device called name.
+	deleteTap(name string) error
+
+	// lookupUser and lookupGroup resolve a user or group name to its
+	// numeric ID on the host the devices live on.
+	lookupUser(name string) (int, error)
+	lookupGroup(name string) (int, error)
+}
+
+// taps returns the tap manager of the client. Devices are managed over
+// netlink when the provider runs locally with enough privileges to do so,
+// and through the ip command otherwise, so that privilege_escalation and ssh
+// apply to them like to every other command.
+func (c *Client) taps() tapManager {
+	if c.ssh == nil && (c.config.PrivilegeEscalation == privilegeEscalationNone || os.Geteuid() == 0) {
+		if m := netlinkTaps(); m != nil {
+			return m
+		}
+	}
+	return &ipTaps{run: c.run, query: c.query}
+}
+
+// resolveID returns the numeric ID for a tap_owner or tap_group value, which
+// may be empty, numeric or a name resolved with lookup.
+func resolveID(value string, lookup func(string) (int, error)) (int, error) {
+	if value == "" {
+		return noID, nil
+	}
+	if id, err := strconv.Atoi(value); err == nil {
+		return id, nil
+	}
+	return lookup(value)
+}
+
+// formatID is the inverse of resolveID for values read back from a device.
+func formatID(id int) string {
+	if id == noID {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
+
+// ipTaps manages tap devices with the ip command, reading their settings
+// back from sysfs.
+type ipTaps struct {
+	// run executes the ip command with the privileges of the provider.
+	run func(cmd string, args ...string) ([]byte, error)
+
+	// query executes the commands that read the host, cat, id and getent,
+	// which need no privileges.
+	query func(cmd string, args ...string) ([]byte, error)
+}
+
+func (t *ipTaps) addTap(tap tapDevice) error {
+	args := []string{"tuntap", "add", "dev", tap.Name, "mode", "tap"}
+	if tap.Owner != noID {
+		args = append(args, "user", strconv.Itoa(tap.Owner))
+	}
+	if tap.Group != noID {
+		args = append(args, "group", strconv.Itoa(tap.Group))
+	}
+	if tap.MultiQueue {
+		args = append(args, "multi_queue")
+	}
+	if tap.VnetHdr {
+		args = append(args, "vnet_hdr")
+	}
+
+	if out, err := t.run("ip", args...); err != nil {
+		return fmt.Errorf("error creating tap device %s: %w: %s", tap.Name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (t *ipTaps) getTap(name string) (*tapDevice, error) {
+	// Every network device has an ifindex and only tun devices have the
+	// other attributes, so one read tells a missing device, a device that
+	// is not a tap and a tap apart.
+	dir := "/sys/class/net/" + name + "/"
+	out, err := t.query("cat", dir+"ifindex", dir+"tun_flags", dir+"owner", dir+"group")
+	if err != nil {
+		if strings.Contains(string(out), dir+"ifindex") {
+			return nil, fmt.Errorf("network device %s: %w", name, errNotFound)
+		}
+		return nil, fmt.Errorf("network device %s: %w", name, errNotTap)
+	}
+	_, attrs, _ := strings.Cut(string(out), "\n")
+	return parseTunSysfs(name, attrs)
+}
+
+func (t *ipTaps) deleteTap(name string) error {
+	if out, err := t.run("ip", "link", "delete", "dev", name); err != nil {
+		return fmt.Errorf("error deleting tap device %s: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (t *ipTaps) lookupUser(name string) (int, error) {
+	out, err := t.query("id", "-u", name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown user %q: %w", name, err)
+	}
+	id, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected id output for user %q: %q", name, out)
+	}
+	return id, nil
+}
+
+func (t *ipTaps) lookupGroup(name string) (int, error) {
+	// getent prints group:password:gid:members.
+	out, err := t.query("getent", "group", name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %w", name, err)
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), ":")
+	if len(fields) < 3 {
+		return 0, fmt.Errorf("unexpected getent output for group %q: %q", name, out)
+	}
+	id, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, fmt.Errorf("unexpected getent output for group %q: %q", name, out)
+	}
+	return id, nil
+}
+
+// parseTunSysfs parses the tun_flags, owner and group sysfs attributes of a
+// tun device, one per line.
+func parseTunSysfs(name, out string) (*tapDevice, error) {
+	fields := strings.Fields(out)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("unexpected tun attributes for %s: %q", name, out)
+	}
+
+	flags, err := strconv.ParseUint(strings.TrimPrefix(fields[0], "0x"), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected tun_flags for %s: %q", name, fields[0])
+	}
+	if flags&iffTap == 0 {
+		return nil, fmt.Errorf("network device %s is a tun device: %w", name, errNotTap)
+	}
+	owner, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected owner for %s: %q", name, fields[1])
+	}
+	group, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected group for %s: %q", name, fields[2])
+	}
+
+	return &tapDevice{
+		Name:       name,
+		Owner:      owner,
+		Group:      group,
+		MultiQueue: flags&iffMultiQueue != 0,
+		VnetHdr:    flags&iffVnetHdr != 0,
+		Persist:    flags&iffPersist != 0,
+	}, nil
+}
@@ -0,0 +1,120 @@
+package openvswitch
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// netlinkTaps returns the tap manager for the local host.
+func netlinkTaps() tapManager {
+	return localTaps{}
+}
+
+// localTaps manages tap devices of the local host without running commands.
+type localTaps struct{}
+
+func (localTaps) addTap(tap tapDevice) error {
+	// Tun devices are created through /dev/net/tun rather than netlink.
+	// The netlink package always sets an owner and group, which cannot be
+	// left unset that way, so the ioctls are issued here directly.
+	fd, err := unix.Open("/dev/net/tun", unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("error creating tap device %s: %w", tap.Name, err)
+	}
+	defer unix.Close(fd)
+
+	req, err := unix.NewIfreq(tap.Name)
+	if err != nil {
+		return fmt.Errorf("error creating tap device %s: %w", tap.Name, err)
+	}
+	flags := uint16(unix.IFF_TAP | unix.IFF_NO_PI)
+	if tap.MultiQueue {
+		flags |= unix.IFF_MULTI_QUEUE
+	}
+	if tap.VnetHdr {
+		flags |= unix.IFF_VNET_HDR
+	}
+	req.SetUint16(flags)
+	if err := unix.IoctlIfreq(fd, unix.TUNSETIFF, req); err != nil {
+		return fmt.Errorf("error creating tap device %s: %w", tap.Name, err)
+	}
+
+	ioctls := []struct {
+		name  string
+		req   uint
+		value int
+	}{
+		{name: "owner", req: unix.TUNSETOWNER, value: tap.Owner},
+		{name: "group", req: unix.TUNSETGROUP, value: tap.Group},
+		{name: "persist flag", req: unix.TUNSETPERSIST, value: 1},
+	}
+	for _, ioctl := range ioctls {
+		if ioctl.value == noID {
+			continue
+		}
+		if err := unix.IoctlSetInt(fd, ioctl.req, ioctl.value); err != nil {
+			// The device goes away with fd as it is not persistent yet.
+			return fmt.Errorf("error setting %s of tap device %s: %w", ioctl.name, tap.Name, err)
+		}
+	}
+	return nil
+}
+
+func (localTaps) getTap(name string) (*tapDevice, error) {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		var notFound netlink.LinkNotFoundError
+		if errors.As(err, &notFound) {
+			return nil, fmt.Errorf("network device %s: %w", name, errNotFound)
+		}
+		return nil, fmt.Errorf("error reading network device %s: %w", name, err)
+	}
+	if _, ok := link.(*netlink.Tuntap); !ok {
+		return nil, fmt.Errorf("network device %s: %w", name, errNotTap)
+	}
+
+	// Netlink omits the owner and group when they are unset, which reads
+	// the same as root, so take them from sysfs like ipTaps does.
+	var out []byte
+	for _, attr := range []string{"tun_flags", "owner", "group"} {
+		value, err := os.ReadFile("/sys/class/net/" + name + "/" + attr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading network device %s: %w", name, err)
+		}
+		out = append(out, value...)
+	}
+	return parseTunSysfs(name, string(out))
+}
+
+func (localTaps) deleteTap(name string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		return fmt.Errorf("error deleting tap device %s: %w", name, err)
+	}
+	if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf("error deleting tap device %s: %w", name, err)
+	}
+	return nil
+}
+
+func (localTaps) lookupUser(name string) (int, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown user %q: %w", name, err)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+func (localTaps) lookupGroup(name string) (int, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %w", name, err)
+	}
+	return strconv.Atoi(g.Gid)
+}
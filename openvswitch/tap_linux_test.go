@@ -0,0 +1,38 @@
+package openvswitch
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLocalTaps(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating tap devices requires root")
+	}
+
+	taps := localTaps{}
+	want := tapDevice{Name: "tftest0", Owner: 0, Group: noID, MultiQueue: true, VnetHdr: true, Persist: true}
+	if err := taps.addTap(want); err != nil {
+		t.Skipf("cannot create tap devices here: %s", err)
+	}
+	defer taps.deleteTap(want.Name)
+
+	got, err := taps.getTap(want.Name)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("tap = %+v, want %+v", *got, want)
+	}
+
+	if err := taps.deleteTap(want.Name); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := taps.getTap(want.Name); !isNotFound(err) {
+		t.Errorf("expected a not found error after delete, got %v", err)
+	}
+	if _, err := taps.getTap("lo"); err == nil {
+		t.Error("expected an error for the loopback device")
+	}
+}
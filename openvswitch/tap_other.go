@@ -0,0 +1,9 @@
+//go:build !linux
+
+package openvswitch
+
+// netlinkTaps returns nil: netlink is only available on Linux, so tap devices
+// are always managed with the ip command elsewhere.
+func netlinkTaps() tapManager {
+	return nil
+}
@@ -0,0 +1,192 @@
+package openvswitch
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scriptedRunner answers commands by their command line and records them.
+type scriptedRunner struct {
+	outputs  map[string]string
+	failures map[string]string
+	commands []string
+}
+
+func (r *scriptedRunner) run(cmd string, args ...string) ([]byte, error) {
+	line := strings.Join(append([]string{cmd}, args...), " ")
+	r.commands = append(r.commands, line)
+	if out, ok := r.failures[line]; ok {
+		return []byte(out), errors.New("exit status 1")
+	}
+	return []byte(r.outputs[line]), nil
+}
+
+func TestIPTapsAddTap(t *testing.T) {
+	tests := []struct {
+		name string
+		tap  tapDevice
+		want string
+	}{
+		{
+			name: "owner only",
+			tap:  tapDevice{Name: "tap0", Owner: 1000, Group: noID, Persist: true},
+			want: "ip tuntap add dev tap0 mode tap user 1000",
+		},
+		{
+			name: "all options",
+			tap:  tapDevice{Name: "tap0", Owner: 0, Group: 36, MultiQueue: true, VnetHdr: true, Persist: true},
+			want: "ip tuntap add dev tap0 mode tap user 0 group 36 multi_queue vnet_hdr",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &scriptedRunner{}
+			if err := (&ipTaps{run: r.run}).addTap(tt.tap); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(r.commands, []string{tt.want}) {
+				t.Errorf("commands = %q, want %q", r.commands, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPTapsGetTap(t *testing.T) {
+	r := &scriptedRunner{
+		outputs: map[string]string{
+			"cat /sys/class/net/tap0/ifindex /sys/class/net/tap0/tun_flags /sys/class/net/tap0/owner /sys/class/net/tap0/group": "7\n0x5802\n1000\n-1\n",
+		},
+		failures: map[string]string{
+			"cat /sys/class/net/tap9/ifindex /sys/class/net/tap9/tun_flags /sys/class/net/tap9/owner /sys/class/net/tap9/group": "cat: /sys/class/net/tap9/ifindex: No such file or directory\n",
+			"cat /sys/class/net/eth0/ifindex /sys/class/net/eth0/tun_flags /sys/class/net/eth0/owner /sys/class/net/eth0/group": "2\ncat: /sys/class/net/eth0/tun_flags: No such file or directory\n",
+		},
+	}
+	// reading a device needs no privileges, so it never goes through run
+	taps := &ipTaps{query: r.run}
+
+	tap, err := taps.getTap("tap0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	want := &tapDevice{Name: "tap0", Owner: 1000, Group: noID, VnetHdr: true, Persist: true}
+	if !reflect.DeepEqual(tap, want) {
+		t.Errorf("tap = %+v, want %+v", tap, want)
+	}
+
+	if _, err := taps.getTap("tap9"); !isNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	if _, err := taps.getTap("eth0"); !errors.Is(err, errNotTap) {
+		t.Errorf("expected a not a tap error, got %v", err)
+	}
+}
+
+func TestIPTapsLookup(t *testing.T) {
+	r := &scriptedRunner{
+		outputs: map[string]string{
+			"id -u qemu":       "107\n",
+			"getent group kvm": "kvm:x:36:qemu\n",
+			"getent group bad": "bad\n",
+		},
+		failures: map[string]string{
+			"id -u ghost": "id: 'ghost': no such user",
+		},
+	}
+	// lookups need no privileges, so they never go through run
+	taps := &ipTaps{query: r.run}
+
+	if id, err := taps.lookupUser("qemu"); err != nil || id != 107 {
+		t.Errorf("lookupUser(qemu) = %d, %v", id, err)
+	}
+	if id, err := taps.lookupGroup("kvm"); err != nil || id != 36 {
+		t.Errorf("lookupGroup(kvm) = %d, %v", id, err)
+	}
+	if _, err := taps.lookupUser("ghost"); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+	if _, err := taps.lookupGroup("bad"); err == nil {
+		t.Error("expected an error for malformed getent output")
+	}
+}
+
+func TestParseTunSysfs(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    *tapDevice
+		wantErr error
+	}{
+		{
+			name: "multi queue",
+			out:  "0x1902\n0\n36\n",
+			want: &tapDevice{Name: "tap0", Owner: 0, Group: 36, MultiQueue: true, Persist: true},
+		},
+		{
+			name: "non-persistent",
+			out:  "0x1002\n-1\n-1\n",
+			want: &tapDevice{Name: "tap0", Owner: noID, Group: noID},
+		},
+		{
+			name:    "tun device",
+			out:     "0x1801\n-1\n-1\n",
+			wantErr: errNotTap,
+		},
+		{
+			name:    "truncated",
+			out:     "0x1802\n",
+			wantErr: errors.New("unexpected tun attributes"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTunSysfs("tap0", tt.out)
+			if tt.wantErr != nil {
+				if err == nil || (!errors.Is(err, tt.wantErr) && !strings.Contains(err.Error(), tt.wantErr.Error())) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tap = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveID(t *testing.T) {
+	lookup := func(name string) (int, error) {
+		if name == "qemu" {
+			return 107, nil
+		}
+		return 0, errors.New("unknown")
+	}
+
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "", want: noID},
+		{value: "0", want: 0},
+		{value: "1000", want: 1000},
+		{value: "qemu", want: 107},
+		{value: "ghost", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveID(tt.value, lookup)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveID(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+		if err == nil && tt.value != "qemu" && formatID(got) != tt.value {
+			t.Errorf("formatID(%d) = %q, want %q", got, formatID(got), tt.value)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package openvswitch
+
+import (
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// defaultCreateTimeout bounds how long Create waits for ovs-vswitchd when
+// the timeouts block does not say otherwise.
+const defaultCreateTimeout = time.Minute
+
+// nullTimeouts returns the timeouts block of states built without a
+// configuration, such as imported or upgraded ones.
+func nullTimeouts() timeouts.Value {
+	return timeouts.Value{Object: types.ObjectNull(map[string]attr.Type{"create": types.StringType})}
+}
@@ -0,0 +1,514 @@
+package openvswitch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/digitalocean/go-openvswitch/ovs"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// ovsClient is the set of Open vSwitch operations the resources are built
+// on. Client implements it against a running OVS, and the tests substitute
+// an in-memory fake.
+type ovsClient interface {
+	// context returns a context bounded by the configured command timeout.
+	context() (context.Context, context.CancelFunc)
+
+	// Bridges. Bridge rows hold the columns of the Bridge table, and
+	// Controller rows those of the Controller table.
+	addBridge(ctx context.Context, bridge string, protocols []string, settings bridgeSettings) error
+	setBridgeProtocols(ctx context.Context, bridge string, protocols []string) error
+	setBridgeSettings(ctx context.Context, bridge string, settings bridgeSettings) error
+	getControllers(ctx context.Context, bridge string) ([]ovsdb.Row, error)
+	deleteBridge(ctx context.Context, bridge string) error
+	getBridge(ctx context.Context, bridge string) (ovsdb.Row, error)
+	listBridges(ctx context.Context) ([]string, error)
+
+	// Ports and their interfaces. Port rows hold the columns of the Port
+	// table.
+	listPorts(ctx context.Context, bridge string) ([]string, error)
+	getPort(ctx context.Context, port string) (ovsdb.Row, error)
+	addPort(ctx context.Context, bridge, port string) error
+	setPortOtherConfig(ctx context.Context, bridge, port string, config map[string]string) error
+	deletePort(ctx context.Context, bridge, port string) error
+	getInterface(ctx context.Context, iface string) (ovsdb.Row, error)
+
+	// OpenFlow port config and state, spoken with version ofversion.
+	showPort(bridge, port, ofversion string) (*portStatus, error)
+	modPort(bridge, port, ofversion string, action ovs.PortAction) error
+
+	// What bridges learned, read with ovs-appctl.
+	fdbShow(ctx context.Context, bridge string) ([]fdbEntry, error)
+	mdbShow(ctx context.Context, bridge string) ([]mdbEntry, error)
+
+	// taps returns the manager of the tap devices backing ports, and
+	// username the user owning the taps the provider creates by default.
+	taps() tapManager
+	username() (string, error)
+
+	// owner returns the owner recorded in the external_ids of the bridges
+	// and ports the provider creates.
+	owner() string
+}
+
+var _ ovsClient = (*Client)(nil)
+
+// vswitchDatabase is the name of the database served by ovsdb-server for
+// ovs-vswitchd.
+const vswitchDatabase = "Open_vSwitch"
+
+// errNotFound is returned when a bridge or port does not exist.
+var errNotFound = errors.New("not found")
+
+// errConflict is returned for a transaction built from rows another client
+// changed before it committed. Building it again from the new rows may
+// succeed.
+var errConflict = errors.New("changed by another client")
+
+// ovsdbWaitTimedOut is the error ovsdb-server reports for a wait operation
+// whose condition does not hold, such as a verify with a timeout of 0.
+const ovsdbWaitTimedOut = "timed out"
+
+// transact runs ops against the Open_vSwitch database as one transaction,
+// followed by a comment identifying the provider in the ovsdb-server log.
+func (c *Client) transact(ctx context.Context, comment string, ops ...ovsdb.Operation) ([]ovsdb.OperationResult, error) {
+	db, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ops = append(ops, ovsdb.Operation{
+		Op:      "comment",
+		Comment: "terraform-provider-openvswitch: " + comment,
+	})
+	return db.Transact(ctx, vswitchDatabase, ops...)
+}
+
+// verify returns a wait operation that fails the transaction unless the row
+// of table called name still holds columns as read from the snapshot, the
+// way ovs-vsctl verifies what it read. ovsdb-server fails it at once when
+// another client changed them, or removed the row, in the meantime.
+func verify(table, name string, columns ovsdb.Row) ovsdb.Operation {
+	names := make([]string, 0, len(columns))
+	for column := range columns {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+	return ovsdb.Operation{
+		Op:      "wait",
+		Table:   table,
+		Where:   whereName(name),
+		Columns: names,
+		Until:   "==",
+		Rows:    []ovsdb.Row{columns},
+		Timeout: new(int),
+	}
+}
+
+// conflict returns err wrapping errConflict as well if it is the failure of
+// the verify operation at index i of a transaction.
+func conflict(err error, i int) error {
+	var opErr *ovsdb.OperationError
+	if errors.As(err, &opErr) && opErr.Index == i && opErr.Op == "wait" && opErr.Err == ovsdbWaitTimedOut {
+		return fmt.Errorf("%w: %w", errConflict, err)
+	}
+	return err
+}
+
+// reconfigure returns the operations that ask ovs-vswitchd to apply the
+// changes made by the rest of the transaction. The result of the trailing
+// select carries the next_cfg value to pass to waitReconfigured.
+func reconfigure() []ovsdb.Operation {
+	return []ovsdb.Operation{
+		{
+			Op:        "mutate",
+			Table:     "Open_vSwitch",
+			Mutations: []ovsdb.Mutation{{Column: "next_cfg", Mutator: "+=", Value: 1}},
+		},
+		{
+			Op:      "select",
+			Table:   "Open_vSwitch",
+			Columns: []string{"next_cfg"},
+		},
+	}
+}
+
+// commit runs ops followed by a reconfiguration request, then waits until
+// ovs-vswitchd has caught up, as ovs-vsctl does without --no-wait. Polling
+// cur_cfg also guarantees that the snapshot has seen the changes, so reads
+// that follow a commit reflect it.
+func (c *Client) commit(ctx context.Context, comment string, ops ...ovsdb.Operation) ([]ovsdb.OperationResult, error) {
+	results, err := c.transact(ctx, comment, append(ops, reconfigure()...)...)
+	if err != nil {
+		return results, err
+	}
+
+	sel := results[len(ops)+1]
+	if len(sel.Rows) == 0 {
+		return results, fmt.Errorf("ovsdb: Open_vSwitch table is empty")
+	}
+	nextCfg, ok := sel.Rows[0]["next_cfg"].(int)
+	if !ok {
+		return results, fmt.Errorf("ovsdb: unexpected next_cfg value %v", sel.Rows[0]["next_cfg"])
+	}
+
+	return results, c.waitReconfigured(ctx, nextCfg)
+}
+
+// waitReconfigured polls Open_vSwitch.cur_cfg until ovs-vswitchd reports
+// that it has applied configuration nextCfg.
+func (c *Client) waitReconfigured(ctx context.Context, nextCfg int) error {
+	delay := 10 * time.Millisecond
+	for {
+		results, err := c.transact(ctx, "wait for reconfiguration", ovsdb.Operation{
+			Op:      "select",
+			Table:   "Open_vSwitch",
+			Columns: []string{"cur_cfg"},
+		})
+		if err != nil {
+			return err
+		}
+		if rows := results[0].Rows; len(rows) > 0 {
+			if cur, ok := rows[0]["cur_cfg"].(int); ok && cur >= nextCfg {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for ovs-vswitchd to reconfigure: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+		if delay < 500*time.Millisecond {
+			delay *= 2
+		}
+	}
+}
+
+// whereName matches rows by their name column.
+func whereName(name string) []ovsdb.Condition {
+	return []ovsdb.Condition{{Column: "name", Function: "==", Value: name}}
+}
+
+// addBridge creates bridge together with its local port and internal
+// interface, mirroring ovs-vsctl add-br, configured with settings from the
+// start.
+func (c *Client) addBridge(ctx context.Context, bridge string, protocols []string, settings bridgeSettings) error {
+	unlock, err := c.bridges.lock(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	row, inserts := settings.columns()
+	row["name"] = bridge
+	row["ports"] = ovsdb.NamedUUID("port")
+	row["other_config"] = settings.otherConfig()
+	row["external_ids"] = ownershipIDs(c.owner(), "openvswitch_bridge", bridge)
+	if len(protocols) > 0 {
+		set := make(ovsdb.Set, 0, len(protocols))
+		for _, p := range protocols {
+			set = append(set, p)
+		}
+		row["protocols"] = set
+	}
+	if settings.DatapathType == "" && c.config.DatapathType != "" {
+		row["datapath_type"] = c.config.DatapathType
+	}
+
+	_, err = c.commit(ctx, "add-br "+bridge, append(inserts,
+		ovsdb.Operation{
+			Op:       "insert",
+			Table:    "Interface",
+			UUIDName: "iface",
+			Row:      ovsdb.Row{"name": bridge, "type": "internal"},
+		},
+		ovsdb.Operation{
+			Op:       "insert",
+			Table:    "Port",
+			UUIDName: "port",
+			Row:      ovsdb.Row{"name": bridge, "interfaces": ovsdb.NamedUUID("iface")},
+		},
+		ovsdb.Operation{
+			Op:       "insert",
+			Table:    "Bridge",
+			UUIDName: "bridge",
+			Row:      row,
+		},
+		ovsdb.Operation{
+			Op:        "mutate",
+			Table:     "Open_vSwitch",
+			Mutations: []ovsdb.Mutation{{Column: "bridges", Mutator: "insert", Value: ovsdb.Set{ovsdb.NamedUUID("bridge")}}},
+		},
+	)...)
+	if err != nil {
+		return fmt.Errorf("error creating bridge %s: %w", bridge, err)
+	}
+	return nil
+}
+
+// setBridgeProtocols replaces the OpenFlow versions enabled on bridge.
+func (c *Client) setBridgeProtocols(ctx context.Context, bridge string, protocols []string) error {
+	unlock, err := c.bridges.lock(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	set := make(ovsdb.Set, 0, len(protocols))
+	for _, p := range protocols {
+		set = append(set, p)
+	}
+
+	results, err := c.commit(ctx, "set bridge "+bridge+" protocols", ovsdb.Operation{
+		Op:    "update",
+		Table: "Bridge",
+		Where: whereName(bridge),
+		Row:   ovsdb.Row{"protocols": set},
+	})
+	if err != nil {
+		return fmt.Errorf("error setting protocols of bridge %s: %w", bridge, err)
+	}
+	if results[0].Count == 0 {
+		return fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	return nil
+}
+
+// deleteBridge removes bridge from the Open_vSwitch table. ovsdb-server
+// garbage collects the bridge row along with its ports and interfaces.
+func (c *Client) deleteBridge(ctx context.Context, bridge string) error {
+	unlock, err := c.bridges.lock(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	row, err := c.getBridge(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	uuid, ok := row["_uuid"].(ovsdb.UUID)
+	if !ok {
+		return fmt.Errorf("error deleting bridge %s: missing _uuid", bridge)
+	}
+
+	_, err = c.commit(ctx, "del-br "+bridge, ovsdb.Operation{
+		Op:        "mutate",
+		Table:     "Open_vSwitch",
+		Mutations: []ovsdb.Mutation{{Column: "bridges", Mutator: "delete", Value: ovsdb.Set{uuid}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting bridge %s: %w", bridge, err)
+	}
+	return nil
+}
+
+// getBridge returns the Bridge row named bridge, or an error wrapping
+// errNotFound if there is none.
+func (c *Client) getBridge(ctx context.Context, bridge string) (ovsdb.Row, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading bridge %s: %w", bridge, err)
+	}
+	row, ok := snap.get("Bridge", bridge)
+	if !ok {
+		return nil, fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	return row, nil
+}
+
+// listBridges returns the names of all bridges, sorted.
+func (c *Client) listBridges(ctx context.Context) ([]string, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing bridges: %w", err)
+	}
+	return snap.names("Bridge"), nil
+}
+
+// listPorts returns the names of the ports on bridge, excluding the bridge's
+// own local port, as ovs-vsctl list-ports does.
+func (c *Client) listPorts(ctx context.Context, bridge string) ([]string, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing ports on bridge %s: %w", bridge, err)
+	}
+	rows, ok := snap.ports(bridge)
+	if !ok {
+		return nil, fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+
+	var ports []string
+	for _, row := range rows {
+		if name, _ := row["name"].(string); name != bridge {
+			ports = append(ports, name)
+		}
+	}
+	return ports, nil
+}
+
+// addPort attaches a new port, backed by the network device of the same
+// name, to bridge.
+func (c *Client) addPort(ctx context.Context, bridge, port string) error {
+	unlock, err := c.bridges.lock(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, err = c.commit(ctx, "add-port "+bridge+" "+port,
+		// Fail the transaction if the bridge does not exist.
+		ovsdb.Operation{
+			Op:      "wait",
+			Table:   "Bridge",
+			Where:   whereName(bridge),
+			Columns: []string{"name"},
+			Until:   "==",
+			Rows:    []ovsdb.Row{{"name": bridge}},
+			Timeout: new(int),
+		},
+		ovsdb.Operation{
+			Op:       "insert",
+			Table:    "Interface",
+			UUIDName: "iface",
+			Row:      ovsdb.Row{"name": port},
+		},
+		ovsdb.Operation{
+			Op:       "insert",
+			Table:    "Port",
+			UUIDName: "port",
+			Row: ovsdb.Row{
+				"name":         port,
+				"interfaces":   ovsdb.NamedUUID("iface"),
+				"external_ids": ownershipIDs(c.owner(), "openvswitch_port", bridge+":"+port),
+			},
+		},
+		ovsdb.Operation{
+			Op:        "mutate",
+			Table:     "Bridge",
+			Where:     whereName(bridge),
+			Mutations: []ovsdb.Mutation{{Column: "ports", Mutator: "insert", Value: ovsdb.Set{ovsdb.NamedUUID("port")}}},
+		},
+	)
+	if err != nil {
+		var opErr *ovsdb.OperationError
+		if errors.As(err, &opErr) && opErr.Op == "wait" {
+			return fmt.Errorf("error adding port %s: bridge %s: %w", port, bridge, errNotFound)
+		}
+		return fmt.Errorf("error adding port %s to bridge %s: %w", port, bridge, err)
+	}
+	return nil
+}
+
+// deletePort detaches port from bridge. ovsdb-server garbage collects the
+// port and its interfaces once they are no longer referenced.
+func (c *Client) deletePort(ctx context.Context, bridge, port string) error {
+	unlock, err := c.bridges.lock(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("error deleting port %s: %w", port, err)
+	}
+	ports, ok := snap.ports(bridge)
+	if !ok {
+		return fmt.Errorf("error deleting port %s: bridge %s: %w", port, bridge, errNotFound)
+	}
+	var uuid ovsdb.UUID
+	for _, row := range ports {
+		if row["name"] == port {
+			uuid, _ = row["_uuid"].(ovsdb.UUID)
+		}
+	}
+	if uuid == "" {
+		if _, ok := snap.get("Port", port); !ok {
+			return fmt.Errorf("error deleting port %s: %w", port, errNotFound)
+		}
+		return fmt.Errorf("error deleting port %s: bridge %s does not have a port %s", port, bridge, port)
+	}
+
+	_, err = c.commit(ctx, "del-port "+bridge+" "+port, ovsdb.Operation{
+		Op:        "mutate",
+		Table:     "Bridge",
+		Where:     whereName(bridge),
+		Mutations: []ovsdb.Mutation{{Column: "ports", Mutator: "delete", Value: ovsdb.Set{uuid}}},
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting port %s from bridge %s: %w", port, bridge, err)
+	}
+	return nil
+}
+
+// getPort returns the Port row named port, or an error wrapping errNotFound
+// if there is none.
+func (c *Client) getPort(ctx context.Context, port string) (ovsdb.Row, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading port %s: %w", port, err)
+	}
+	row, ok := snap.get("Port", port)
+	if !ok {
+		return nil, fmt.Errorf("port %s: %w", port, errNotFound)
+	}
+	return row, nil
+}
+
+// portOtherConfigKeys lists the Port other_config keys held by the
+// openvswitch_port attributes. Other keys are left alone.
+var portOtherConfigKeys = (&portModel{}).otherConfig().keys()
+
+// setPortOtherConfig replaces the pairs of portOtherConfigKeys in the
+// other_config column of port, on bridge, with config.
+func (c *Client) setPortOtherConfig(ctx context.Context, bridge, port string, config map[string]string) error {
+	unlock, err := c.bridges.lock(ctx, bridge)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	results, err := c.commit(ctx, "set port "+port, ovsdb.Operation{
+		Op:        "mutate",
+		Table:     "Port",
+		Where:     whereName(port),
+		Mutations: otherConfigMutations(portOtherConfigKeys, config),
+	})
+	if err != nil {
+		return fmt.Errorf("error configuring port %s: %w", port, err)
+	}
+	if results[0].Count == 0 {
+		return fmt.Errorf("port %s: %w", port, errNotFound)
+	}
+	return nil
+}
+
+// owner returns the configured owner.
+func (c *Client) owner() string {
+	return c.config.Owner
+}
+
+// getInterface returns the Interface row named iface, or an error wrapping
+// errNotFound if there is none.
+func (c *Client) getInterface(ctx context.Context, iface string) (ovsdb.Row, error) {
+	snap, err := c.snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading interface %s: %w", iface, err)
+	}
+	row, ok := snap.get("Interface", iface)
+	if !ok {
+		return nil, fmt.Errorf("interface %s: %w", iface, errNotFound)
+	}
+	return row, nil
+}
+
+// isNotFound reports whether err indicates a missing bridge or port.
+func isNotFound(err error) bool {
+	return errors.Is(err, errNotFound)
+}
@@ -0,0 +1,734 @@
+package openvswitch
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/go-openvswitch/ovs"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+)
+
+// ofppLocal is the OpenFlow port number of a bridge's local port.
+const ofppLocal = 65534
+
+// fakeOVS is an in-memory ovsClient modeling the parts of Open vSwitch the
+// resources rely on:
+//
+//   - a bridge owns its ports, which vanish when the bridge is deleted
+//   - port names are unique across bridges, and every bridge has a local
+//     port and internal network device of its own name
+//   - a port is only in the datapath while its network device exists
+//   - OpenFlow requests fail unless the bridge enables the version spoken,
+//     and OpenFlow 1.0 only port config flags need OpenFlow10
+type fakeOVS struct {
+	mu sync.Mutex
+
+	bridges map[string]*fakeBridge
+	ports   map[string]*fakePort
+
+	// devices holds the network devices by name. Tap devices map to their
+	// settings, other devices to nil.
+	devices map[string]*tapDevice
+
+	users  map[string]int
+	groups map[string]int
+
+	nextOFPort int
+
+	// modPortErr, when set, fails every mod-port request, as when
+	// ovs-vswitchd does not answer ovs-ofctl.
+	modPortErr error
+
+	// unusable maps the network devices ovs-vswitchd cannot add to a
+	// bridge to the error it reports for their interfaces.
+	unusable map[string]string
+
+	// addedAt maps the network devices ovs-vswitchd is slow to add to a
+	// bridge to when their interfaces become ready.
+	addedAt map[string]time.Time
+
+	// commandTimeout, when set, bounds the contexts handed out by context,
+	// like command_timeout does.
+	commandTimeout time.Duration
+
+	// ownerName is the owner stamped on the bridges and ports created.
+	ownerName string
+
+	// listening holds the controller targets that accept connections.
+	// Bridges connect to them and retry the others.
+	listening map[string]bool
+}
+
+type fakeBridge struct {
+	protocols   []string
+	settings    bridgeSettings
+	externalIDs ovsdb.Map
+
+	// ports lists the ports of the bridge in creation order, starting with
+	// its local port.
+	ports []string
+
+	// fdb holds the MAC addresses learned on the ports of the bridge, and
+	// mdb the multicast groups they joined.
+	fdb []fdbEntry
+	mdb []mdbEntry
+}
+
+type fakePort struct {
+	bridge      string
+	ofport      int
+	config      map[string]bool
+	otherConfig map[string]string
+	externalIDs ovsdb.Map
+}
+
+var _ ovsClient = (*fakeOVS)(nil)
+
+func newFakeOVS() *fakeOVS {
+	return &fakeOVS{
+		bridges:    make(map[string]*fakeBridge),
+		ports:      make(map[string]*fakePort),
+		devices:    make(map[string]*tapDevice),
+		users:      map[string]int{"root": 0, "qemu": 107},
+		groups:     map[string]int{"root": 0, "kvm": 108},
+		nextOFPort: 1,
+	}
+}
+
+func (f *fakeOVS) context() (context.Context, context.CancelFunc) {
+	if f.commandTimeout > 0 {
+		return context.WithTimeout(context.Background(), f.commandTimeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+func (f *fakeOVS) addBridge(_ context.Context, bridge string, protocols []string, settings bridgeSettings) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.ports[bridge]; ok {
+		return fmt.Errorf("error creating bridge %s: a port named %s already exists", bridge, bridge)
+	}
+	f.bridges[bridge] = &fakeBridge{
+		protocols:   append([]string(nil), protocols...),
+		settings:    copySettings(settings),
+		externalIDs: ownershipIDs(f.ownerName, "openvswitch_bridge", bridge),
+		ports:       []string{bridge},
+	}
+	f.ports[bridge] = &fakePort{bridge: bridge, ofport: ofppLocal, config: make(map[string]bool), otherConfig: make(map[string]string)}
+	f.devices[bridge] = nil
+	return nil
+}
+
+func (f *fakeOVS) setBridgeProtocols(_ context.Context, bridge string, protocols []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	b.protocols = append([]string(nil), protocols...)
+	return nil
+}
+
+func (f *fakeOVS) setBridgeSettings(_ context.Context, bridge string, settings bridgeSettings) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	if settings.DatapathType == "" {
+		settings.DatapathType = b.settings.DatapathType
+	}
+	b.settings = copySettings(settings)
+	return nil
+}
+
+// addresses returns the MAC and datapath ID the bridge uses: those set,
+// or else a MAC derived from its name and the datapath ID derived from the
+// MAC, as ovs-vswitchd derives it.
+func (b *fakeBridge) addresses(name string) (mac, dpid string) {
+	mac, dpid = b.settings.OtherConfig["hwaddr"], b.settings.OtherConfig["datapath-id"]
+	if mac == "" {
+		h := fnv.New32a()
+		h.Write([]byte(name))
+		sum := h.Sum32()
+		mac = fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", byte(sum>>24), byte(sum>>16), byte(sum>>8), byte(sum))
+	}
+	if dpid == "" {
+		dpid = "0000" + strings.ReplaceAll(mac, ":", "")
+	}
+	return mac, dpid
+}
+
+// spanningTree returns the status and rstp_status columns of the bridge
+// and of its port p. With no links between bridges, every bridge is the
+// root of its own tree and its ports are designated ports.
+func (b *fakeBridge) spanningTree(name string, p *fakePort) (status, rstpStatus ovsdb.Map) {
+	mac, _ := b.addresses(name)
+	bridgeID := fmt.Sprintf("%04x.%s", configInt(b.settings.OtherConfig, "stp-priority", 32768), strings.ReplaceAll(mac, ":", ""))
+	rstpID := fmt.Sprintf("%04x.%s", configInt(b.settings.OtherConfig, "rstp-priority", 32768), strings.ReplaceAll(mac, ":", ""))
+
+	status, rstpStatus = ovsdb.Map{}, ovsdb.Map{}
+	switch {
+	case b.settings.STPEnable && p == nil:
+		status = ovsdb.Map{"stp_bridge_id": bridgeID, "stp_designated_root": bridgeID, "stp_root_path_cost": "0"}
+	case b.settings.STPEnable:
+		status = ovsdb.Map{
+			"stp_port_id": fmt.Sprintf("%04x", configInt(p.otherConfig, "stp-port-priority", 128)<<8|p.ofport),
+			"stp_state":   "forwarding",
+			"stp_role":    "designated",
+		}
+	case b.settings.RSTPEnable && p == nil:
+		rstpStatus = ovsdb.Map{"rstp_bridge_id": rstpID, "rstp_root_id": rstpID, "rstp_root_path_cost": "0"}
+	case b.settings.RSTPEnable:
+		rstpStatus = ovsdb.Map{
+			"rstp_port_id":    fmt.Sprintf("%04x", configInt(p.otherConfig, "rstp-port-priority", 128)<<8|p.ofport),
+			"rstp_port_role":  "Designated",
+			"rstp_port_state": "Forwarding",
+		}
+	}
+	return status, rstpStatus
+}
+
+// configInt returns the integer held by key in config, or def if there is
+// none.
+func configInt(config map[string]string, key string, def int) int {
+	if n, err := strconv.Atoi(config[key]); err == nil {
+		return n
+	}
+	return def
+}
+
+// copySettings returns a copy of settings not sharing its slices or maps.
+func copySettings(settings bridgeSettings) bridgeSettings {
+	settings.Controllers = append([]string(nil), settings.Controllers...)
+	settings.OtherConfig = copyConfig(settings.OtherConfig)
+	return settings
+}
+
+// copyConfig returns a copy of an other_config map.
+func copyConfig(config map[string]string) map[string]string {
+	copied := make(map[string]string, len(config))
+	for key, value := range config {
+		copied[key] = value
+	}
+	return copied
+}
+
+func (f *fakeOVS) deleteBridge(_ context.Context, bridge string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	// ovsdb-server garbage collects the ports no longer referenced
+	for _, port := range b.ports {
+		delete(f.ports, port)
+	}
+	delete(f.devices, bridge)
+	delete(f.bridges, bridge)
+	return nil
+}
+
+func (f *fakeOVS) getBridge(_ context.Context, bridge string) (ovsdb.Row, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return nil, fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	protocols := make(ovsdb.Set, 0, len(b.protocols))
+	for _, p := range b.protocols {
+		protocols = append(protocols, p)
+	}
+	row, _ := b.settings.columns()
+	controllers := make(ovsdb.Set, 0, len(b.settings.Controllers))
+	for _, target := range b.settings.Controllers {
+		controllers = append(controllers, ovsdb.UUID("controller-"+target))
+	}
+	_, dpid := b.addresses(bridge)
+	row["_uuid"] = ovsdb.UUID("bridge-" + bridge)
+	row["name"] = bridge
+	row["other_config"] = b.settings.otherConfig()
+	row["datapath_id"] = dpid
+	row["protocols"] = protocols
+	row["controller"] = controllers
+	row["external_ids"] = b.externalIDs
+	row["status"], row["rstp_status"] = b.spanningTree(bridge, nil)
+	return row, nil
+}
+
+func (f *fakeOVS) getControllers(_ context.Context, bridge string) ([]ovsdb.Row, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return nil, fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	targets := append([]string(nil), b.settings.Controllers...)
+	sort.Strings(targets)
+	rows := make([]ovsdb.Row, 0, len(targets))
+	for _, target := range targets {
+		row := ovsdb.Row{
+			"_uuid":        ovsdb.UUID("controller-" + target),
+			"target":       target,
+			"is_connected": false,
+			"role":         ovsdb.Set{},
+			"status":       ovsdb.Map{"state": "BACKOFF"},
+		}
+		if f.listening[target] {
+			row["is_connected"] = true
+			row["role"] = "other"
+			row["status"] = ovsdb.Map{"state": "ACTIVE"}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (f *fakeOVS) listBridges(_ context.Context) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bridges := make([]string, 0, len(f.bridges))
+	for bridge := range f.bridges {
+		bridges = append(bridges, bridge)
+	}
+	sort.Strings(bridges)
+	return bridges, nil
+}
+
+func (f *fakeOVS) listPorts(_ context.Context, bridge string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return nil, fmt.Errorf("bridge %s: %w", bridge, errNotFound)
+	}
+	var ports []string
+	for _, port := range b.ports {
+		if port != bridge {
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+func (f *fakeOVS) addPort(_ context.Context, bridge, port string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return fmt.Errorf("error adding port %s: bridge %s: %w", port, bridge, errNotFound)
+	}
+	if p, ok := f.ports[port]; ok {
+		return fmt.Errorf("error adding port %s to bridge %s: port already exists on bridge %s", port, bridge, p.bridge)
+	}
+	b.ports = append(b.ports, port)
+	f.ports[port] = &fakePort{
+		bridge:      bridge,
+		ofport:      f.nextOFPort,
+		config:      make(map[string]bool),
+		otherConfig: make(map[string]string),
+		externalIDs: ownershipIDs(f.ownerName, "openvswitch_port", bridge+":"+port),
+	}
+	f.nextOFPort++
+	return nil
+}
+
+func (f *fakeOVS) deletePort(_ context.Context, bridge, port string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return fmt.Errorf("error deleting port %s: bridge %s: %w", port, bridge, errNotFound)
+	}
+	p, ok := f.ports[port]
+	if !ok {
+		return fmt.Errorf("error deleting port %s: %w", port, errNotFound)
+	}
+	if p.bridge != bridge {
+		return fmt.Errorf("error deleting port %s: bridge %s does not have a port %s", port, bridge, port)
+	}
+	for i, name := range b.ports {
+		if name == port {
+			b.ports = append(b.ports[:i], b.ports[i+1:]...)
+			break
+		}
+	}
+	delete(f.ports, port)
+
+	// ovs-vswitchd forgets what it learned on the port
+	fdb := b.fdb[:0]
+	for _, e := range b.fdb {
+		if e.Port != strconv.Itoa(p.ofport) {
+			fdb = append(fdb, e)
+		}
+	}
+	b.fdb = fdb
+	mdb := b.mdb[:0]
+	for _, e := range b.mdb {
+		if e.Port != strconv.Itoa(p.ofport) {
+			mdb = append(mdb, e)
+		}
+	}
+	b.mdb = mdb
+	return nil
+}
+
+func (f *fakeOVS) getPort(_ context.Context, port string) (ovsdb.Row, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.ports[port]
+	if !ok {
+		return nil, fmt.Errorf("port %s: %w", port, errNotFound)
+	}
+	row := ovsdb.Row{"name": port, "external_ids": p.externalIDs, "status": ovsdb.Map{}, "rstp_status": ovsdb.Map{}}
+	config := make(ovsdb.Map, len(p.otherConfig))
+	for key, value := range p.otherConfig {
+		config[key] = value
+	}
+	row["other_config"] = config
+	// Only ports in the datapath take part in the spanning tree
+	if _, ok := f.devices[port]; ok && port != p.bridge {
+		row["status"], row["rstp_status"] = f.bridges[p.bridge].spanningTree(p.bridge, p)
+	}
+	return row, nil
+}
+
+func (f *fakeOVS) setPortOtherConfig(ctx context.Context, bridge, port string, config map[string]string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("error configuring port %s: %w", port, err)
+	}
+
+	p, ok := f.ports[port]
+	if !ok || p.bridge != bridge {
+		return fmt.Errorf("port %s: %w", port, errNotFound)
+	}
+	for _, key := range portOtherConfigKeys {
+		delete(p.otherConfig, key)
+	}
+	for key, value := range config {
+		p.otherConfig[key] = value
+	}
+	return nil
+}
+
+func (f *fakeOVS) getInterface(_ context.Context, iface string) (ovsdb.Row, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := f.ports[iface]
+	if !ok {
+		return nil, fmt.Errorf("interface %s: %w", iface, errNotFound)
+	}
+	row := ovsdb.Row{
+		"name":       iface,
+		"type":       "",
+		"ofport":     p.ofport,
+		"link_state": "down",
+		"error":      ovsdb.Set{},
+	}
+	if iface == p.bridge {
+		row["type"] = "internal"
+		row["link_state"] = "up"
+		row["mac_in_use"], _ = f.bridges[p.bridge].addresses(p.bridge)
+	}
+	if _, ok := f.devices[iface]; !ok {
+		row["ofport"] = -1
+		row["link_state"] = ovsdb.Set{}
+		row["error"] = "could not open network device " + iface + " (No such device)"
+	}
+	if msg, ok := f.unusable[iface]; ok {
+		row["ofport"] = -1
+		row["link_state"] = ovsdb.Set{}
+		row["error"] = msg
+	}
+	if at, ok := f.addedAt[iface]; ok && time.Now().Before(at) {
+		row["ofport"] = ovsdb.Set{}
+		row["link_state"] = ovsdb.Set{}
+	}
+	return row, nil
+}
+
+// datapathPort returns port if it is on bridge and ovs-vswitchd could add it
+// to the datapath, after checking that bridge speaks ofversion. Callers must
+// hold f.mu.
+func (f *fakeOVS) datapathPort(bridge, port, ofversion string) (*fakePort, error) {
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return nil, fmt.Errorf("ovs-ofctl: %s is not a bridge or a socket", bridge)
+	}
+	if len(b.protocols) > 0 {
+		enabled := false
+		for _, p := range b.protocols {
+			enabled = enabled || p == ofversion
+		}
+		if !enabled {
+			return nil, fmt.Errorf("ovs-ofctl: %s: failed to connect to socket (Broken pipe): version negotiation failed", bridge)
+		}
+	}
+	p, ok := f.ports[port]
+	if !ok || p.bridge != bridge {
+		return nil, nil
+	}
+	if _, ok := f.devices[port]; !ok {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func (f *fakeOVS) showPort(bridge, port, ofversion string) (*portStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.datapathPort(bridge, port, ofversion)
+	if err != nil {
+		return nil, fmt.Errorf("error reading port %s on bridge %s: %w", port, bridge, err)
+	}
+	if p == nil {
+		return nil, fmt.Errorf("port %s in ovs-ofctl show: %w", port, errNotFound)
+	}
+
+	status := &portStatus{Config: []string{}, State: []string{}}
+	for _, flag := range portConfigFlags {
+		if p.config[flag.name] {
+			status.Config = append(status.Config, flag.name)
+		}
+	}
+	// Nothing holds the tap devices open, so they have no carrier
+	if port != bridge {
+		status.State = append(status.State, "link-down")
+	}
+	return status, nil
+}
+
+func (f *fakeOVS) modPort(bridge, port, ofversion string, action ovs.PortAction) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.modPortErr != nil {
+		return fmt.Errorf("error applying %s to port %s with %s: %w", action, port, ofversion, f.modPortErr)
+	}
+
+	p, err := f.datapathPort(bridge, port, ofversion)
+	if err == nil && p == nil {
+		err = fmt.Errorf("ovs-ofctl: %s: couldn't find port `%s'", bridge, port)
+	}
+	if err != nil {
+		return fmt.Errorf("error applying %s to port %s with %s: %w", action, port, ofversion, err)
+	}
+
+	for _, flag := range portConfigFlags {
+		if action != flag.set && action != flag.clear {
+			continue
+		}
+		if flag.openFlow10 && ofversion != "OpenFlow10" {
+			return fmt.Errorf("error applying %s to port %s with %s: ovs-ofctl: %s is not supported", action, port, ofversion, action)
+		}
+		p.config[flag.name] = action == flag.set
+		return nil
+	}
+	return fmt.Errorf("error applying %s to port %s with %s: unknown action", action, port, ofversion)
+}
+
+func (f *fakeOVS) fdbShow(_ context.Context, bridge string) ([]fdbEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return nil, fmt.Errorf("error reading MAC addresses of bridge %s: exit status 2: no such bridge", bridge)
+	}
+	return append([]fdbEntry{}, b.fdb...), nil
+}
+
+func (f *fakeOVS) mdbShow(_ context.Context, bridge string) ([]mdbEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	b, ok := f.bridges[bridge]
+	if !ok {
+		return nil, fmt.Errorf("error reading multicast groups of bridge %s: exit status 2: no such bridge", bridge)
+	}
+	if !b.settings.MulticastSnooping {
+		return nil, fmt.Errorf("error reading multicast groups of bridge %s: exit status 2: multicast snooping is disabled", bridge)
+	}
+	return append([]mdbEntry{}, b.mdb...), nil
+}
+
+func (f *fakeOVS) taps() tapManager {
+	return fakeTaps{f}
+}
+
+func (f *fakeOVS) username() (string, error) {
+	return "root", nil
+}
+
+func (f *fakeOVS) owner() string {
+	return f.ownerName
+}
+
+// disown clears the external_ids of the bridge or port called name, as if
+// it had been created outside Terraform.
+func (f *fakeOVS) disown(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if b, ok := f.bridges[name]; ok {
+		b.externalIDs = ovsdb.Map{}
+	}
+	if p, ok := f.ports[name]; ok && p.bridge != name {
+		p.externalIDs = ovsdb.Map{}
+	}
+}
+
+// addDevice adds a network device called name that is not a tap device,
+// such as a veth created outside Terraform.
+func (f *fakeOVS) addDevice(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices[name] = nil
+}
+
+// hasDevice reports whether a network device called name exists.
+func (f *fakeOVS) hasDevice(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.devices[name]
+	return ok
+}
+
+// learnMAC records mac as seen on port, on its bridge, in vlan, as when a
+// host behind it sends a frame.
+func (f *fakeOVS) learnMAC(port string, vlan int, mac string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := f.ports[port]
+	b := f.bridges[p.bridge]
+	b.fdb = append(b.fdb, fdbEntry{Port: f.ofportName(p, port), VLAN: vlan, MAC: mac})
+}
+
+// joinGroup records port, on its bridge, as a member of group on vlan, as
+// when a host behind it sends a report. A group of mdbQuerier records it as
+// leading to a multicast router.
+func (f *fakeOVS) joinGroup(port string, vlan int, group string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := f.ports[port]
+	b := f.bridges[p.bridge]
+	b.mdb = append(b.mdb, mdbEntry{Port: f.ofportName(p, port), VLAN: vlan, Group: group})
+}
+
+// ofportName returns the OpenFlow port number of port p called name as
+// ovs-appctl prints it.
+func (f *fakeOVS) ofportName(p *fakePort, name string) string {
+	if name == p.bridge {
+		return "LOCAL"
+	}
+	return strconv.Itoa(p.ofport)
+}
+
+// portConfig returns the port_config flags set on port, sorted.
+func (f *fakeOVS) portConfig(port string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	config := []string{}
+	if p, ok := f.ports[port]; ok {
+		for name, set := range p.config {
+			if set {
+				config = append(config, name)
+			}
+		}
+	}
+	sort.Strings(config)
+	return config
+}
+
+// fakeTaps manages the network devices of a fakeOVS.
+type fakeTaps struct {
+	f *fakeOVS
+}
+
+func (t fakeTaps) addTap(tap tapDevice) error {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	if _, ok := t.f.devices[tap.Name]; ok {
+		return fmt.Errorf("error creating tap device %s: ioctl(TUNSETIFF): Device or resource busy", tap.Name)
+	}
+	t.f.devices[tap.Name] = &tap
+	return nil
+}
+
+func (t fakeTaps) getTap(name string) (*tapDevice, error) {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	tap, ok := t.f.devices[name]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("network device %s: %w", name, errNotFound)
+	case tap == nil:
+		return nil, fmt.Errorf("network device %s: %w", name, errNotTap)
+	}
+	copied := *tap
+	return &copied, nil
+}
+
+func (t fakeTaps) deleteTap(name string) error {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	if _, ok := t.f.devices[name]; !ok {
+		return fmt.Errorf("error deleting tap device %s: Cannot find device %q", name, name)
+	}
+	delete(t.f.devices, name)
+	return nil
+}
+
+func (t fakeTaps) lookupUser(name string) (int, error) {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	id, ok := t.f.users[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown user %q", name)
+	}
+	return id, nil
+}
+
+func (t fakeTaps) lookupGroup(name string) (int, error) {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+
+	id, ok := t.f.groups[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown group %q", name)
+	}
+	return id, nil
+}
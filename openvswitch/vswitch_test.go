@@ -0,0 +1,659 @@
+package openvswitch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb"
+	"github.com/trvon/terraform-provider-openvswitch/internal/ovsdb/ovsdbtest"
+)
+
+// newScriptedClient returns a Client whose ovsdb-server connection is
+// answered by handler, which receives the operations of each transaction.
+// Its snapshot starts out empty.
+func newScriptedClient(t *testing.T, handler func(ops []ovsdb.Operation) []ovsdb.OperationResult) *Client {
+	t.Helper()
+
+	server, conn := net.Pipe()
+	go func() {
+		dec := json.NewDecoder(server)
+		enc := json.NewEncoder(server)
+		for {
+			var req struct {
+				ID     json.RawMessage   `json:"id"`
+				Method string            `json:"method"`
+				Params []json.RawMessage `json:"params"`
+			}
+			if err := dec.Decode(&req); err != nil {
+				return
+			}
+			ops := make([]ovsdb.Operation, 0, len(req.Params))
+			for _, raw := range req.Params[1:] {
+				var op ovsdb.Operation
+				if err := json.Unmarshal(raw, &op); err != nil {
+					t.Errorf("decoding operation: %s", err)
+					return
+				}
+				ops = append(ops, op)
+			}
+			if err := enc.Encode(map[string]interface{}{"id": req.ID, "result": handler(ops), "error": nil}); err != nil {
+				return
+			}
+		}
+	}()
+
+	client, err := (&Config{}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	client.db = ovsdb.NewClient(conn)
+	client.snap = newSnapshot()
+	t.Cleanup(func() { client.db.Close() })
+	return client
+}
+
+// opSummary reduces ops to "op table" strings for comparison.
+func opSummary(ops []ovsdb.Operation) []string {
+	summary := make([]string, 0, len(ops))
+	for _, op := range ops {
+		summary = append(summary, op.Op+" "+op.Table)
+	}
+	return summary
+}
+
+// emptyResults returns one empty result per operation.
+func emptyResults(ops []ovsdb.Operation) []ovsdb.OperationResult {
+	return make([]ovsdb.OperationResult, len(ops))
+}
+
+// reconfigured answers the reconfiguration part of a commit, and reports
+// cur_cfg as already applied.
+func reconfigured(ops []ovsdb.Operation) []ovsdb.OperationResult {
+	results := emptyResults(ops)
+	for i, op := range ops {
+		if op.Op == "select" && op.Table == "Open_vSwitch" {
+			results[i].Rows = []ovsdb.Row{{"next_cfg": 1, "cur_cfg": 1}}
+		}
+	}
+	return results
+}
+
+func TestClientAddBridge(t *testing.T) {
+	var transactions [][]ovsdb.Operation
+	client := newScriptedClient(t, func(ops []ovsdb.Operation) []ovsdb.OperationResult {
+		transactions = append(transactions, ops)
+		return reconfigured(ops)
+	})
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.addBridge(ctx, "pepe0", []string{"OpenFlow13"}, bridgeSettings{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(transactions) != 2 {
+		t.Fatalf("expected the add-br transaction and one cur_cfg poll, got %d transactions", len(transactions))
+	}
+
+	want := []string{
+		"insert Interface",
+		"insert Port",
+		"insert Bridge",
+		"mutate Open_vSwitch",
+		"mutate Open_vSwitch",
+		"select Open_vSwitch",
+		"comment ",
+	}
+	if got := opSummary(transactions[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("add-br operations = %q, want %q", got, want)
+	}
+
+	bridge := transactions[0][2].Row
+	if bridge["name"] != "pepe0" {
+		t.Errorf("bridge name = %v", bridge["name"])
+	}
+	if got := ovsdb.Strings(bridge["protocols"]); !reflect.DeepEqual(got, []string{"OpenFlow13"}) {
+		t.Errorf("bridge protocols = %v", got)
+	}
+	if iface := transactions[0][0].Row; iface["type"] != "internal" {
+		t.Errorf("local interface type = %v, want internal", iface["type"])
+	}
+}
+
+func TestClientListPorts(t *testing.T) {
+	client := newScriptedClient(t, func(ops []ovsdb.Operation) []ovsdb.OperationResult {
+		t.Errorf("expected listing ports to use the snapshot, got transaction %q", opSummary(ops))
+		return emptyResults(ops)
+	})
+	client.snap.update(ovsdb.TableUpdates{
+		"Bridge": {
+			"b": {New: ovsdb.Row{"name": "br0", "ports": ovsdb.Set{ovsdb.UUID("1"), ovsdb.UUID("2")}}},
+		},
+		"Port": {
+			"1": {New: ovsdb.Row{"name": "br0"}},
+			"2": {New: ovsdb.Row{"name": "tap0"}},
+			"3": {New: ovsdb.Row{"name": "other"}},
+		},
+	})
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	ports, err := client.listPorts(ctx, "br0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(ports, []string{"tap0"}) {
+		t.Errorf("ports = %v, want [tap0]", ports)
+	}
+	if _, err := client.listPorts(ctx, "missing"); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestClientAddPortMissingBridge(t *testing.T) {
+	client := newScriptedClient(t, func(ops []ovsdb.Operation) []ovsdb.OperationResult {
+		return []ovsdb.OperationResult{{Error: "timed out", Details: "wait timed out"}}
+	})
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	err := client.addPort(ctx, "missing", "tap0")
+	if !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestClientGetBridgeNotFound(t *testing.T) {
+	client := newScriptedClient(t, emptyResults)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if _, err := client.getBridge(ctx, "missing"); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestClientSetBridgeProtocols(t *testing.T) {
+	var transactions [][]ovsdb.Operation
+	client := newScriptedClient(t, func(ops []ovsdb.Operation) []ovsdb.OperationResult {
+		transactions = append(transactions, ops)
+		results := reconfigured(ops)
+		if ops[0].Op == "update" {
+			results[0].Count = 1
+		}
+		return results
+	})
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.setBridgeProtocols(ctx, "br0", []string{"OpenFlow10"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	update := transactions[0][0]
+	if update.Op != "update" || update.Table != "Bridge" {
+		t.Fatalf("first operation = %s %s, want update Bridge", update.Op, update.Table)
+	}
+	if got := ovsdb.Strings(update.Row["protocols"]); !reflect.DeepEqual(got, []string{"OpenFlow10"}) {
+		t.Errorf("protocols = %v, want [OpenFlow10]", got)
+	}
+	if len(update.Row) != 1 {
+		t.Errorf("update touches %d columns, want only protocols", len(update.Row))
+	}
+}
+
+func TestClientSetBridgeProtocolsNotFound(t *testing.T) {
+	client := newScriptedClient(t, reconfigured)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.setBridgeProtocols(ctx, "missing", []string{"OpenFlow13"}); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+// newServedClient returns a Client connected to an in-process ovsdb-server
+// holding an empty Open_vSwitch database.
+func newServedClient(t *testing.T) *Client {
+	t.Helper()
+
+	server, err := ovsdbtest.NewServer(filepath.Join(t.TempDir(), "db.sock"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	t.Cleanup(func() { server.Close() })
+
+	client, err := (&Config{OVSDBEndpoint: server.Endpoint()}).Client()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return client
+}
+
+func TestClientOVSDBServer(t *testing.T) {
+	client := newServedClient(t)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.addBridge(ctx, "br0", []string{"OpenFlow13"}, bridgeSettings{}); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	if err := client.addBridge(ctx, "br0", nil, bridgeSettings{}); err == nil {
+		t.Error("expected adding a duplicate bridge to fail")
+	}
+	if err := client.setBridgeProtocols(ctx, "br0", []string{"OpenFlow10", "OpenFlow15"}); err != nil {
+		t.Fatalf("set protocols: %s", err)
+	}
+	row, err := client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if got := ovsdb.Strings(row["protocols"]); !reflect.DeepEqual(got, []string{"OpenFlow10", "OpenFlow15"}) {
+		t.Errorf("protocols = %v, want [OpenFlow10 OpenFlow15]", got)
+	}
+
+	if err := client.addPort(ctx, "br0", "tap0"); err != nil {
+		t.Fatalf("add-port: %s", err)
+	}
+	if err := client.addPort(ctx, "missing", "tap1"); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	ports, err := client.listPorts(ctx, "br0")
+	if err != nil {
+		t.Fatalf("list ports: %s", err)
+	}
+	if !reflect.DeepEqual(ports, []string{"tap0"}) {
+		t.Errorf("ports = %v, want [tap0]", ports)
+	}
+	iface, err := client.getInterface(ctx, "tap0")
+	if err != nil {
+		t.Fatalf("get interface: %s", err)
+	}
+	if iface["ofport"] != 1 {
+		t.Errorf("ofport = %v, want 1", iface["ofport"])
+	}
+	if err := waitForInterface(ctx, client, "tap0"); err != nil {
+		t.Errorf("wait for interface: %s", err)
+	}
+
+	if err := client.deletePort(ctx, "br0", "tap0"); err != nil {
+		t.Fatalf("del-port: %s", err)
+	}
+	if _, err := client.getInterface(ctx, "tap0"); !isNotFound(err) {
+		t.Errorf("expected the interface of the deleted port to be collected, got %v", err)
+	}
+
+	// Deleting the bridge leaves its local port and interface unreferenced
+	if err := client.deleteBridge(ctx, "br0"); err != nil {
+		t.Fatalf("del-br: %s", err)
+	}
+	if _, err := client.getBridge(ctx, "br0"); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	if _, err := client.getInterface(ctx, "br0"); !isNotFound(err) {
+		t.Errorf("expected the local interface to be collected, got %v", err)
+	}
+}
+
+func TestClientAddBridgeDatapathType(t *testing.T) {
+	client := newServedClient(t)
+	client.config.DatapathType = "netdev"
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.addBridge(ctx, "br0", nil, bridgeSettings{}); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	row, err := client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if row["datapath_type"] != "netdev" {
+		t.Errorf("datapath_type = %v, want netdev", row["datapath_type"])
+	}
+
+	// The bridge's own datapath_type takes precedence
+	if err := client.addBridge(ctx, "br1", nil, bridgeSettings{DatapathType: "system"}); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	row, err = client.getBridge(ctx, "br1")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if row["datapath_type"] != "system" {
+		t.Errorf("datapath_type = %v, want system", row["datapath_type"])
+	}
+}
+
+func TestClientBridgeAddresses(t *testing.T) {
+	client := newServedClient(t)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	settings := bridgeSettings{OtherConfig: map[string]string{"datapath-id": "00000000000000a1", "hwaddr": "02:00:00:00:00:a1"}}
+	if err := client.addBridge(ctx, "br0", nil, settings); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	row, err := client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if got := optionalString(row["datapath_id"]); got != "00000000000000a1" {
+		t.Errorf("datapath_id = %q, want the configured 00000000000000a1", got)
+	}
+	local, err := client.getInterface(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get interface: %s", err)
+	}
+	if got := optionalString(local["mac_in_use"]); got != "02:00:00:00:00:a1" {
+		t.Errorf("mac_in_use = %q, want the configured 02:00:00:00:00:a1", got)
+	}
+
+	// Keys the settings do not hold are left alone
+	_, err = client.transact(ctx, "test", ovsdb.Operation{
+		Op:        "mutate",
+		Table:     "Bridge",
+		Where:     whereName("br0"),
+		Mutations: []ovsdb.Mutation{{Column: "other_config", Mutator: "insert", Value: ovsdb.Map{"disable-in-band": "true"}}},
+	})
+	if err != nil {
+		t.Fatalf("set other_config: %s", err)
+	}
+	delete(settings.OtherConfig, "datapath-id")
+	if err := client.setBridgeSettings(ctx, "br0", settings); err != nil {
+		t.Fatalf("set bridge: %s", err)
+	}
+	row, err = client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	want := map[string]string{"hwaddr": "02:00:00:00:00:a1", "disable-in-band": "true"}
+	if got := ovsdb.StringMap(row["other_config"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("other_config = %v, want %v", got, want)
+	}
+	if got := optionalString(row["datapath_id"]); got != "00000200000000a1" {
+		t.Errorf("datapath_id = %q, want 00000200000000a1 derived from the MAC", got)
+	}
+}
+
+func TestClientBridgeSettings(t *testing.T) {
+	client := newServedClient(t)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	settings := bridgeSettings{FailMode: "secure", Controllers: []string{"tcp:192.0.2.1:6653", "ptcp:6653"}}
+	if err := client.addBridge(ctx, "br0", nil, settings); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	row, err := client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if got := bridgeFailMode(row); got != "secure" {
+		t.Errorf("fail_mode = %q, want secure", got)
+	}
+	controllers, err := client.getControllers(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get controllers: %s", err)
+	}
+	if got := controllerTargets(controllers); !reflect.DeepEqual(got, []string{"ptcp:6653", "tcp:192.0.2.1:6653"}) {
+		t.Errorf("controllers = %v, want [ptcp:6653 tcp:192.0.2.1:6653]", got)
+	}
+
+	// Unchanged controllers keep their rows, and so their connections
+	settings.FailMode = ""
+	if err := client.setBridgeSettings(ctx, "br0", settings); err != nil {
+		t.Fatalf("set bridge: %s", err)
+	}
+	kept, err := client.getControllers(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get controllers: %s", err)
+	}
+	if len(kept) != 2 || kept[0]["_uuid"] != controllers[0]["_uuid"] {
+		t.Errorf("expected the controller rows to be kept, got %v", kept)
+	}
+	row, _ = client.getBridge(ctx, "br0")
+	if got := bridgeFailMode(row); got != "standalone" {
+		t.Errorf("fail_mode = %q, want standalone once cleared", got)
+	}
+
+	settings.Controllers = []string{"tcp:127.0.0.1:6653"}
+	if err := client.setBridgeSettings(ctx, "br0", settings); err != nil {
+		t.Fatalf("set bridge: %s", err)
+	}
+	controllers, err = client.getControllers(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get controllers: %s", err)
+	}
+	if got := controllerTargets(controllers); !reflect.DeepEqual(got, []string{"tcp:127.0.0.1:6653"}) {
+		t.Errorf("controllers = %v, want [tcp:127.0.0.1:6653]", got)
+	}
+
+	if err := client.setBridgeSettings(ctx, "missing", settings); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestClientSpanningTree(t *testing.T) {
+	client := newServedClient(t)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	settings := bridgeSettings{
+		STPEnable:   true,
+		OtherConfig: map[string]string{"hwaddr": "02:00:00:00:00:a1", "stp-priority": "4096"},
+	}
+	if err := client.addBridge(ctx, "br0", nil, settings); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	if err := client.addPort(ctx, "br0", "tap0"); err != nil {
+		t.Fatalf("add-port: %s", err)
+	}
+	if err := client.setPortOtherConfig(ctx, "br0", "tap0", map[string]string{"stp-port-priority": "32"}); err != nil {
+		t.Fatalf("set port: %s", err)
+	}
+
+	row, err := client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	status := ovsdb.StringMap(row["status"])
+	if status["stp_bridge_id"] != "1000.0200000000a1" || status["stp_designated_root"] != "1000.0200000000a1" {
+		t.Errorf("status = %v, want the bridge 1000.0200000000a1 as the root", status)
+	}
+	port, err := client.getPort(ctx, "tap0")
+	if err != nil {
+		t.Fatalf("get port: %s", err)
+	}
+	if got := ovsdb.StringMap(port["status"])["stp_port_id"]; got != "2001" {
+		t.Errorf("stp_port_id = %q, want 2001", got)
+	}
+
+	// Keys the port settings do not hold are left alone
+	_, err = client.transact(ctx, "test", ovsdb.Operation{
+		Op:        "mutate",
+		Table:     "Port",
+		Where:     whereName("tap0"),
+		Mutations: []ovsdb.Mutation{{Column: "other_config", Mutator: "insert", Value: ovsdb.Map{"priority-tags": "true"}}},
+	})
+	if err != nil {
+		t.Fatalf("set other_config: %s", err)
+	}
+	if err := client.setPortOtherConfig(ctx, "br0", "tap0", map[string]string{"rstp-port-admin-edge": "true"}); err != nil {
+		t.Fatalf("set port: %s", err)
+	}
+	port, err = client.getPort(ctx, "tap0")
+	if err != nil {
+		t.Fatalf("get port: %s", err)
+	}
+	want := map[string]string{"rstp-port-admin-edge": "true", "priority-tags": "true"}
+	if got := ovsdb.StringMap(port["other_config"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("other_config = %v, want %v", got, want)
+	}
+
+	settings.STPEnable, settings.RSTPEnable = false, true
+	if err := client.setBridgeSettings(ctx, "br0", settings); err != nil {
+		t.Fatalf("set bridge: %s", err)
+	}
+	row, err = client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if got := ovsdb.StringMap(row["status"]); len(got) != 0 {
+		t.Errorf("status = %v, want none once STP is disabled", got)
+	}
+	if got := ovsdb.StringMap(row["rstp_status"])["rstp_root_id"]; got != "8000.0200000000a1" {
+		t.Errorf("rstp_root_id = %q, want 8000.0200000000a1", got)
+	}
+
+	if err := client.setPortOtherConfig(ctx, "br0", "missing", nil); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestClientFloodVLANs(t *testing.T) {
+	client := newServedClient(t)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	settings := bridgeSettings{FloodVLANs: []int{20, 10}}
+	if err := client.addBridge(ctx, "br0", nil, settings); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	row, err := client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if got := bridgeFloodVLANs(row); !reflect.DeepEqual(got, []int{10, 20}) {
+		t.Errorf("flood_vlans = %v, want [10 20]", got)
+	}
+
+	// A single VLAN is sent as a bare integer
+	settings.FloodVLANs = []int{30}
+	if err := client.setBridgeSettings(ctx, "br0", settings); err != nil {
+		t.Fatalf("set bridge: %s", err)
+	}
+	if row, err = client.getBridge(ctx, "br0"); err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if got := bridgeFloodVLANs(row); !reflect.DeepEqual(got, []int{30}) {
+		t.Errorf("flood_vlans = %v, want [30]", got)
+	}
+
+	settings.FloodVLANs = nil
+	if err := client.setBridgeSettings(ctx, "br0", settings); err != nil {
+		t.Fatalf("set bridge: %s", err)
+	}
+	if row, err = client.getBridge(ctx, "br0"); err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	if got := bridgeFloodVLANs(row); len(got) != 0 {
+		t.Errorf("flood_vlans = %v, want none", got)
+	}
+}
+
+func TestClientOwnership(t *testing.T) {
+	client := newServedClient(t)
+	client.config.Owner = "prod"
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.addBridge(ctx, "br0", nil, bridgeSettings{}); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+	if err := client.addPort(ctx, "br0", "tap0"); err != nil {
+		t.Fatalf("add-port: %s", err)
+	}
+
+	row, err := client.getBridge(ctx, "br0")
+	if err != nil {
+		t.Fatalf("get bridge: %s", err)
+	}
+	want := map[string]string{
+		"managed-by":         "terraform-provider-openvswitch",
+		"terraform-owner":    "prod",
+		"terraform-resource": "openvswitch_bridge br0",
+	}
+	if got := ovsdb.StringMap(row["external_ids"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("bridge external_ids = %v, want %v", got, want)
+	}
+
+	row, err = client.getPort(ctx, "tap0")
+	if err != nil {
+		t.Fatalf("get port: %s", err)
+	}
+	want["terraform-resource"] = "openvswitch_port br0:tap0"
+	if got := ovsdb.StringMap(row["external_ids"]); !reflect.DeepEqual(got, want) {
+		t.Errorf("port external_ids = %v, want %v", got, want)
+	}
+	if err := checkOwned(row, "prod", "port tap0"); err != nil {
+		t.Errorf("expected the port to be owned: %s", err)
+	}
+	if err := checkOwned(row, "staging", "port tap0"); err == nil {
+		t.Error("expected a port created for another owner not to be owned")
+	}
+
+	if _, err := client.getPort(ctx, "missing"); !isNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestClientConcurrentPorts(t *testing.T) {
+	client := newServedClient(t)
+
+	ctx, cancel := client.context()
+	defer cancel()
+
+	if err := client.addBridge(ctx, "br0", nil, bridgeSettings{}); err != nil {
+		t.Fatalf("add-br: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.addPort(ctx, "br0", fmt.Sprintf("tap%d", i)); err != nil {
+				t.Errorf("add-port: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ports, err := client.listPorts(ctx, "br0")
+	if err != nil {
+		t.Fatalf("list ports: %s", err)
+	}
+	if len(ports) != 10 {
+		t.Errorf("expected 10 ports, got %v", ports)
+	}
+	ofports := make(map[interface{}]string)
+	for _, port := range ports {
+		iface, err := client.getInterface(ctx, port)
+		if err != nil {
+			t.Fatalf("get interface: %s", err)
+		}
+		if other, ok := ofports[iface["ofport"]]; ok {
+			t.Errorf("%s and %s share ofport %v", port, other, iface["ofport"])
+		}
+		ofports[iface["ofport"]] = port
+	}
+}